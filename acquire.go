@@ -0,0 +1,91 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Acquire reserves one of the worker pool's workers for the caller, for code
+// that cannot be submitted as a function, such as code that must run on an OS
+// thread that the caller has locked.  Acquire waits until a worker is
+// available, in turn with submitted tasks, and then returns a function that
+// releases the worker.  The release function must be called when the caller is
+// done, and may be called more than once.
+//
+// A reserved worker is busy, the same as a worker running a task, so Stop and
+// StopWait wait for it to be released.  Returns the context's error if ctx is
+// done before a worker is available, or ErrStopped if the worker pool is
+// stopped.
+func (p *WorkerPool) Acquire(ctx context.Context) (func(), error) {
+	p.checkReentrant("Acquire")
+	return p.acquire(ctx, false)
+}
+
+// Start states of a task that reserves a worker.
+const (
+	startWaiting int32 = iota
+	startStarted
+	startTimedOut
+)
+
+// tryAcquire reserves a worker if one can start the reservation right away,
+// without waiting behind queued tasks.  Returns false if no worker is
+// available or the worker pool is stopped.
+func (p *WorkerPool) tryAcquire() (func(), bool) {
+	release, err := p.acquire(context.Background(), true)
+	return release, err == nil
+}
+
+// acquire reserves a worker for Acquire or tryAcquire.  If noWait is set, the
+// reservation is refused instead of queued when no worker can start it.
+func (p *WorkerPool) acquire(ctx context.Context, noWait bool) (func(), error) {
+	if p.Stopped() {
+		return nil, ErrStopped
+	}
+	done := p.Done()
+	var state int32
+	acquired := make(chan struct{})
+	released := make(chan struct{})
+	t := p.newTask(func() {
+		if !atomic.CompareAndSwapInt32(&state, startWaiting, startStarted) {
+			// The caller stopped waiting.
+			return
+		}
+		close(acquired)
+		<-released
+	})
+	refused := make(chan struct{})
+	if noWait {
+		t.refused = func() { close(refused) }
+	}
+	p.enqueue(t)
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() { close(released) })
+	}
+	var err error
+	select {
+	case <-acquired:
+		return release, nil
+	case <-refused:
+		return nil, errRefused
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-done:
+		err = ErrStopped
+	}
+	if atomic.CompareAndSwapInt32(&state, startWaiting, startTimedOut) {
+		p.discard(t)
+		return nil, err
+	}
+	// A worker started the task while the caller stopped waiting.
+	<-acquired
+	return release, nil
+}
+
+// errRefused is returned by acquire when a reservation that must not wait is
+// refused.
+var errRefused = errors.New("workerpool: no worker available")
@@ -0,0 +1,50 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	defer wp.Stop()
+
+	release, err := wp.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wp.BusyWorkers() != 1 {
+		t.Fatal("expected acquired worker to be busy")
+	}
+
+	// The only worker is reserved, so tasks and other callers wait.
+	ran := make(chan struct{})
+	wp.Submit(func() { close(ran) })
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = wp.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected deadline exceeded, got", err)
+	}
+	select {
+	case <-ran:
+		t.Fatal("task ran while worker was reserved")
+	default:
+	}
+
+	release()
+	release()
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task did not run after release")
+	}
+
+	wp.Stop()
+	if _, err = wp.Acquire(context.Background()); err != ErrStopped {
+		t.Fatal("expected ErrStopped, got", err)
+	}
+}
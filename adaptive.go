@@ -0,0 +1,131 @@
+package workerpool
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultAdaptiveBackoff is the factor that the concurrency limit is
+// multiplied by when a task is slow, if Adaptive does not set Backoff.
+const defaultAdaptiveBackoff = 0.9
+
+// Adaptive configures the adaptive concurrency limit, set using
+// WithAdaptiveConcurrency.
+type Adaptive struct {
+	// MinWorkers is the lowest concurrency limit.  Values less than 1 are 1.
+	MinWorkers int
+	// Latency is the task execution time above which the concurrency limit
+	// is decreased.  This is normally a little above the execution time of
+	// a task when the resource that tasks use is not overloaded.
+	Latency time.Duration
+	// Backoff is the factor, between 0 and 1, that the concurrency limit is
+	// multiplied by when a task takes longer than Latency.  The default is
+	// 0.9.
+	Backoff float64
+}
+
+// WithAdaptiveConcurrency limits the number of tasks that execute at once to
+// a limit that adapts to the observed task latency, between MinWorkers and the
+// maximum number of workers.  The limit is increased by one each time a task
+// completes within Latency while the limit is in use, and is multiplied by
+// Backoff each time a task takes longer.  This additive increase,
+// multiplicative decrease (AIMD) finds the concurrency that a downstream
+// resource can sustain, so the maximum number of workers only needs to be an
+// upper bound.
+//
+// The limit starts at MinWorkers.  While the worker pool is at its limit,
+// workers wait for running tasks to complete before executing their next task.
+func WithAdaptiveConcurrency(adaptive Adaptive) Option {
+	return func(p *WorkerPool) {
+		if adaptive.Latency <= 0 {
+			return
+		}
+		if adaptive.MinWorkers < 1 {
+			adaptive.MinWorkers = 1
+		}
+		if adaptive.Backoff <= 0 || adaptive.Backoff >= 1 {
+			adaptive.Backoff = defaultAdaptiveBackoff
+		}
+		p.adaptive = &adaptiveLimit{Adaptive: adaptive}
+		p.adaptive.cond = sync.NewCond(&p.adaptive.mutex)
+	}
+}
+
+// ConcurrencyLimit returns the number of tasks that may execute at once.  This
+// is the limit set using WithAdaptiveConcurrency or WithSLO, or the maximum
+// number of workers.
+func (p *WorkerPool) ConcurrencyLimit() int {
+	if p.adaptive == nil {
+		return p.maxWorkers
+	}
+	p.adaptive.mutex.Lock()
+	defer p.adaptive.mutex.Unlock()
+	return int(p.adaptive.limit)
+}
+
+// adaptiveLimit is an AIMD concurrency limit.
+type adaptiveLimit struct {
+	Adaptive
+	max     int
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	limit   float64
+	running int
+}
+
+// init sets the bounds of the limit, once the maximum number of workers is
+// known.
+func (a *adaptiveLimit) init(maxWorkers int) {
+	if a.MinWorkers > maxWorkers {
+		a.MinWorkers = maxWorkers
+	}
+	a.max = maxWorkers
+	a.limit = float64(a.MinWorkers)
+	if a.Latency <= 0 {
+		// The limit is set by an SLO, which starts at the maximum.
+		a.limit = float64(maxWorkers)
+	}
+}
+
+// acquire waits until a task may be executed, and returns when it started.
+// Does nothing if the limit is nil.
+func (a *adaptiveLimit) acquire() time.Time {
+	if a == nil {
+		return time.Time{}
+	}
+	a.mutex.Lock()
+	for a.running >= int(a.limit) {
+		a.cond.Wait()
+	}
+	a.running++
+	a.mutex.Unlock()
+	return time.Now()
+}
+
+// release records that a task that started at the given time has finished
+// executing, and adjusts the limit.  Does nothing if the limit is nil.
+func (a *adaptiveLimit) release(started time.Time) {
+	if a == nil {
+		return
+	}
+	latency := time.Since(started)
+	a.mutex.Lock()
+	switch {
+	case a.Latency <= 0:
+		// The limit is set by an SLO.
+	case latency > a.Latency:
+		a.limit *= a.Backoff
+		if a.limit < float64(a.MinWorkers) {
+			a.limit = float64(a.MinWorkers)
+		}
+	case a.running >= int(a.limit):
+		// Only increase the limit when it is what holds tasks back.
+		a.limit++
+		if a.limit > float64(a.max) {
+			a.limit = float64(a.max)
+		}
+	}
+	a.running--
+	a.mutex.Unlock()
+	a.cond.Broadcast()
+}
@@ -0,0 +1,42 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveConcurrency(t *testing.T) {
+	t.Parallel()
+
+	wp := New(8, WithAdaptiveConcurrency(Adaptive{MinWorkers: 2, Latency: 20 * time.Millisecond}))
+	defer wp.Stop()
+
+	if limit := wp.ConcurrencyLimit(); limit != 2 {
+		t.Fatal("expected limit to start at 2, got", limit)
+	}
+
+	// Fast tasks that keep the limit in use increase it to the maximum.
+	for i := 0; i < 100; i++ {
+		wp.Submit(func() { time.Sleep(time.Millisecond) })
+	}
+	wp.WaitContext(context.Background())
+	if limit := wp.ConcurrencyLimit(); limit != 8 {
+		t.Fatal("expected limit to increase to 8, got", limit)
+	}
+
+	// Slow tasks decrease it to the minimum.
+	for i := 0; i < 40; i++ {
+		wp.Submit(func() { time.Sleep(30 * time.Millisecond) })
+	}
+	wp.WaitContext(context.Background())
+	if limit := wp.ConcurrencyLimit(); limit != 2 {
+		t.Fatal("expected limit to decrease to 2, got", limit)
+	}
+
+	wp3 := New(3)
+	defer wp3.Stop()
+	if wp3.ConcurrencyLimit() != 3 {
+		t.Fatal("expected limit of pool without adaptive concurrency to be max workers")
+	}
+}
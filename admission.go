@@ -0,0 +1,226 @@
+package workerpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TaskInfo describes a task being submitted, for an Admission function, or a
+// task waiting in a Queue.
+type TaskInfo struct {
+	// ID identifies the task.
+	ID TaskID
+	// Name is the name of the task, from its metadata or its function.
+	Name string
+	// Labels are the labels of the task, if submitted with metadata.
+	Labels map[string]string
+	// Priority is the priority of a task submitted using SubmitTask.
+	Priority int
+	// Tenant is the tenant of a task submitted using SubmitTenant.
+	Tenant string
+}
+
+// PoolStats describes the current load of a worker pool, for an Admission
+// function.
+type PoolStats struct {
+	// MaxWorkers is the maximum number of workers.
+	MaxWorkers int
+	// Workers is the number of running workers.
+	Workers int
+	// BusyWorkers is the number of workers that are running tasks.
+	BusyWorkers int
+	// Waiting is the number of tasks waiting for a worker.
+	Waiting int
+	// QueueWait is how long the last task given to a worker waited for it.
+	QueueWait time.Duration
+}
+
+// AdmissionAction is what to do with a task being submitted.
+type AdmissionAction int
+
+const (
+	// Admit submits the task.
+	Admit AdmissionAction = iota
+	// Reject does not submit the task.
+	Reject
+	// Delay submits the task after the decision's Delay.
+	Delay
+	// Degrade submits the decision's Fallback function instead of the task.
+	Degrade
+	// RunInCaller runs the task on the goroutine that submits it, before the
+	// submit function returns, instead of queuing it.  This slows down the
+	// submitter while the worker pool is saturated, without dropping work.
+	RunInCaller
+)
+
+// Decision is the result of an Admission function.
+type Decision struct {
+	Action AdmissionAction
+	// Delay is how long to wait before submitting a delayed task.  The task
+	// is pending, for the purposes of Wait and Flush, while it is delayed.
+	// Stopping the worker pool ends the delay: StopWait enqueues the task
+	// to run before stopping, and Stop abandons it.
+	Delay time.Duration
+	// Fallback is the function submitted instead of a degraded task, such as
+	// one that does a cheaper version of the task's work.  A degraded task
+	// without a Fallback is rejected.
+	Fallback func()
+}
+
+// Admission decides what to do with a task being submitted, given the current
+// load of the worker pool.
+type Admission func(TaskInfo, PoolStats) Decision
+
+// WithAdmission sets a function that decides whether each task submitted
+// using Submit, SubmitAll, SubmitWithMetadata, SubmitTask, SubmitTenant,
+// SubmitWaitContext, or SubmitTimeout is admitted.  The function is called
+// from the goroutine that submits the task, and must return quickly.
+//
+// A rejected task is not run, and is complete for the purposes of Wait and
+// Flush.  A task submitted using SubmitWaitContext or SubmitTimeout is
+// rejected instead of degraded, since its caller waits for the task itself.
+// Since tasks submitted using other functions have callers that wait for them
+// to run, these tasks are always admitted.
+func WithAdmission(admission Admission) Option {
+	return func(p *WorkerPool) {
+		p.admission = admission
+		p.recordEnqueue = true
+	}
+}
+
+// CallerRunsPolicy returns an admission function that runs a task on the
+// goroutine that submits it when maxWaiting or more tasks are waiting for a
+// worker, or when tasks are waiting and the last task given to a worker waited
+// longer than maxQueueWait.  Other tasks are admitted.  A zero limit is not
+// checked.
+func CallerRunsPolicy(maxWaiting int, maxQueueWait time.Duration) Admission {
+	return func(_ TaskInfo, stats PoolStats) Decision {
+		if (maxWaiting > 0 && stats.Waiting >= maxWaiting) ||
+			(maxQueueWait > 0 && stats.Waiting != 0 && stats.QueueWait > maxQueueWait) {
+			return Decision{Action: RunInCaller}
+		}
+		return Decision{Action: Admit}
+	}
+}
+
+// enqueueAdmitted enqueues a submitted task if the admission function admits
+// it, or after a delay if the admission function delays it.  Returns
+// ErrQueueFull if the task is rejected, or ErrStopped if the worker pool is
+// stopped.
+func (p *WorkerPool) enqueueAdmitted(t *task) error {
+	switch p.admit(t) {
+	case Admit:
+		return p.enqueue(t)
+	case Reject:
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// admit asks the admission function what to do with a submitted task.
+// Returns Admit if the task, or its fallback, is to be enqueued now, Delay if
+// the task will be enqueued after a delay, RunInCaller if the task was run on
+// the calling goroutine, or Reject if the task is rejected.
+func (p *WorkerPool) admit(t *task) AdmissionAction {
+	if p.admission == nil {
+		return Admit
+	}
+	info := t.Info()
+	stats := PoolStats{
+		MaxWorkers:  p.maxWorkers,
+		Workers:     int(atomic.LoadInt32(&p.workerCount)),
+		BusyWorkers: int(atomic.LoadInt32(&p.busyWorkers)),
+		Waiting:     int(atomic.LoadInt32(&p.waiting)),
+		QueueWait:   time.Duration(atomic.LoadInt64(&p.queueWait)),
+	}
+	d := p.admission(info, stats)
+	switch d.Action {
+	case Delay:
+		p.delayTask(t, d.Delay)
+		return Delay
+	case Degrade:
+		if d.Fallback != nil && !t.waited {
+			t.fn = d.Fallback
+			t.ctxFn = nil
+			// The fallback is not the serializable task.
+			t.serializable = nil
+			return Admit
+		}
+	case Admit:
+		return Admit
+	case RunInCaller:
+		p.runInline(t)
+		return RunInCaller
+	}
+	p.discard(t)
+	p.count(MetricTasksRejected, 1)
+	p.emit(Rejected, t)
+	p.rejected(t.fn, RejectedByAdmission)
+	return Reject
+}
+
+// delayTask enqueues a task after a delay.  The delayed task is tracked until it
+// is enqueued, so that stopping the worker pool can take it.
+func (p *WorkerPool) delayTask(t *task, d time.Duration) {
+	p.delayMutex.Lock()
+	defer p.delayMutex.Unlock()
+	if p.delayed == nil {
+		p.delayed = map[*task]*time.Timer{}
+	}
+	p.delayed[t] = time.AfterFunc(d, func() {
+		// Holding the mutex while enqueuing keeps flushDelayed from
+		// returning before the task is given to the dispatcher.
+		p.delayMutex.Lock()
+		defer p.delayMutex.Unlock()
+		if _, ok := p.delayed[t]; !ok {
+			// Stopping the worker pool took the task.
+			return
+		}
+		delete(p.delayed, t)
+		p.enqueue(t)
+	})
+}
+
+// flushDelayed takes the delayed tasks when the worker pool is told to stop.
+// If the worker pool waits for queued tasks, or is suspended, the tasks are
+// enqueued now.  Otherwise they are abandoned.  Must be called before submits
+// are closed.
+func (p *WorkerPool) flushDelayed(wait bool) {
+	p.delayMutex.Lock()
+	defer p.delayMutex.Unlock()
+	for t, timer := range p.delayed {
+		timer.Stop()
+		delete(p.delayed, t)
+		if wait {
+			p.enqueue(t)
+		} else {
+			if t.quota != nil {
+				t.quota.release()
+				t.quota = nil
+			}
+			p.abandon(t)
+		}
+	}
+}
+
+// admitAll asks the admission function about each task in a batch, and
+// returns the tasks to enqueue.
+func (p *WorkerPool) admitAll(batch []*task) []*task {
+	if p.admission == nil {
+		return batch
+	}
+	admitted := batch[:0]
+	for _, t := range batch {
+		if p.admit(t) == Admit {
+			admitted = append(admitted, t)
+		}
+	}
+	return admitted
+}
+
+// submit enqueues a function that the worker pool, or a helper that waits for
+// the function, depends on running.  Unlike Submit, the function is always
+// admitted.
+func (p *WorkerPool) submit(fn func()) {
+	p.enqueue(p.newTask(fn))
+}
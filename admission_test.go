@@ -0,0 +1,113 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAdmission(t *testing.T) {
+	t.Parallel()
+
+	var ran, degraded int32
+	admission := func(info TaskInfo, stats PoolStats) Decision {
+		if stats.MaxWorkers != 2 {
+			t.Error("wrong MaxWorkers:", stats.MaxWorkers)
+		}
+		switch info.Name {
+		case "reject":
+			return Decision{Action: Reject}
+		case "delay":
+			return Decision{Action: Delay, Delay: 20 * time.Millisecond}
+		case "degrade":
+			return Decision{Action: Degrade, Fallback: func() {
+				atomic.AddInt32(&degraded, 1)
+			}}
+		}
+		if info.Tenant == "blocked" || info.Priority < 0 {
+			return Decision{Action: Reject}
+		}
+		return Decision{Action: Admit}
+	}
+	wp := New(2, WithAdmission(admission))
+	defer wp.Stop()
+
+	run := func() { atomic.AddInt32(&ran, 1) }
+	wp.SubmitWithMetadata(Metadata{Name: "reject"}, run)
+	wp.SubmitWithMetadata(Metadata{Name: "degrade"}, run)
+	wp.SubmitTask(Task{Name: "task", Priority: -1, Fn: func(context.Context) { run() }})
+	if err := wp.SubmitTenant("blocked", run); err != ErrQueueFull {
+		t.Fatal("expected ErrQueueFull, got", err)
+	}
+	wp.SubmitAll(run, run)
+	wp.Wait()
+	if ran != 2 || degraded != 1 {
+		t.Fatalf("expected 2 tasks and 1 fallback to run, got %d and %d", ran, degraded)
+	}
+
+	start := time.Now()
+	wp.SubmitWithMetadata(Metadata{Name: "delay"}, run)
+	wp.Wait()
+	if ran != 3 {
+		t.Fatal("delayed task did not run")
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("task was not delayed")
+	}
+}
+
+func TestAdmissionDelayStop(t *testing.T) {
+	t.Parallel()
+
+	admission := func(TaskInfo, PoolStats) Decision {
+		return Decision{Action: Delay, Delay: time.Hour}
+	}
+
+	var ran int32
+	wp := New(1, WithAdmission(admission))
+	wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	wp.StopWait()
+	if atomic.LoadInt32(&ran) != 1 {
+		t.Fatal("StopWait did not run the delayed task")
+	}
+
+	var rejected int32
+	wp = New(1, WithAdmission(admission), WithOnReject(func(_ func(), reason RejectReason) {
+		if reason == RejectedByStop {
+			atomic.AddInt32(&rejected, 1)
+		}
+	}))
+	wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	wp.Stop()
+	wp.Wait()
+	if atomic.LoadInt32(&rejected) != 1 {
+		t.Fatal("Stop did not drop the delayed task")
+	}
+	if atomic.LoadInt32(&ran) != 1 {
+		t.Fatal("delayed task ran after Stop")
+	}
+}
+
+func TestCallerRunsPolicy(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithAdmission(CallerRunsPolicy(1, 0)))
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	wp.Submit(func() {})
+	for wp.WaitingQueueSize() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The queue is full, so the task runs before Submit returns.
+	var ran bool
+	wp.Submit(func() { ran = true })
+	if !ran {
+		t.Fatal("task did not run on the calling goroutine")
+	}
+	close(release)
+	wp.Wait()
+}
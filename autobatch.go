@@ -0,0 +1,156 @@
+package workerpool
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultBatchWait is how long the first item of a batch waits for more items
+// when a Batcher does not set MaxWait.
+const defaultBatchWait = 10 * time.Millisecond
+
+// ErrUnknownBatch is returned by SubmitBatched when no Batcher is set for the
+// batch key.
+var ErrUnknownBatch = errors.New("workerpool: no batcher for key")
+
+// Batcher merges items submitted using SubmitBatched with the same key into
+// batches, and runs each batch as one task.  This turns many small pieces of
+// work, such as single-row database writes, into fewer larger ones, such as a
+// bulk write.
+//
+// Open batches are given to workers when the worker pool is stopped, so that
+// StopWait runs their items and Stop drops and reports them, the same as
+// other queued tasks.
+type Batcher struct {
+	// MaxSize is the largest number of items in a batch.  A batch is given to
+	// a worker as soon as it is full.  Zero means no limit.
+	MaxSize int
+	// MaxWait is how long the first item in a batch waits for more items
+	// before the batch is given to a worker.  The default is 10ms.
+	MaxWait time.Duration
+	// Run is the task that a worker executes with the items of each batch,
+	// in the order they were submitted.  If Run is nil, each item must be a
+	// func(), and a worker executes the functions of a batch one after
+	// another, so that queued tasks with the same key are run by one worker
+	// invocation.
+	Run func(items []interface{})
+}
+
+// WithBatcher sets the Batcher for items submitted with the key.  Each key may
+// have its own batch size, wait, and task.
+func WithBatcher(key string, batcher Batcher) Option {
+	return func(p *WorkerPool) {
+		if batcher.Run == nil {
+			batcher.Run = runBatchedTasks
+		}
+		if batcher.MaxWait <= 0 {
+			batcher.MaxWait = defaultBatchWait
+		}
+		if p.batchers == nil {
+			p.batchers = map[string]*keyBatch{}
+		}
+		p.batchers[key] = &keyBatch{Batcher: batcher}
+	}
+}
+
+// keyBatch collects the items of a key's next batch.
+type keyBatch struct {
+	Batcher
+	mutex sync.Mutex
+	items []interface{}
+	// task is the pending task that runs the batch, created when the first
+	// item is added so that the items are pending for Wait and Flush.
+	task  *task
+	timer *time.Timer
+}
+
+// SubmitBatched adds an item to the next batch for the key.  The batch is run
+// by the key's Batcher, set using WithBatcher, when it is full or when its
+// first item has waited for MaxWait.  Returns ErrUnknownBatch if the key has
+// no Batcher, or ErrStopped if the worker pool is stopped.
+func (p *WorkerPool) SubmitBatched(key string, item interface{}) error {
+	b := p.batchers[key]
+	if b == nil {
+		return ErrUnknownBatch
+	}
+	if p.submitsClosed() {
+		return ErrStopped
+	}
+	b.mutex.Lock()
+	if b.task == nil {
+		t := p.newTask(nil)
+		b.task = t
+		b.timer = time.AfterFunc(b.MaxWait, func() { p.sendBatch(b, t) })
+	}
+	b.items = append(b.items, item)
+	var t *task
+	if b.MaxSize > 0 && len(b.items) >= b.MaxSize {
+		t = b.take()
+	}
+	b.mutex.Unlock()
+	if t != nil {
+		return p.enqueue(t)
+	}
+	return nil
+}
+
+// sendBatch enqueues the batch run by task t when it has waited for MaxWait,
+// unless it was already sent because it was full.
+func (p *WorkerPool) sendBatch(b *keyBatch, t *task) {
+	b.mutex.Lock()
+	if b.task != t {
+		b.mutex.Unlock()
+		return
+	}
+	b.take()
+	b.mutex.Unlock()
+	p.enqueue(t)
+}
+
+// runBatchedTasks runs the items of a batch that are task functions, for a
+// Batcher without a Run function.
+func runBatchedTasks(items []interface{}) {
+	for _, item := range items {
+		if fn, ok := item.(func()); ok {
+			fn()
+		}
+	}
+}
+
+// flushBatches takes the open batches when the worker pool is told to stop.
+// If the worker pool waits for queued tasks, or is suspended, the batches are
+// given to the dispatcher to run or keep with the other queued tasks.
+// Otherwise they are abandoned.  Must be called before the dispatcher is told
+// to stop.
+func (p *WorkerPool) flushBatches(wait bool) {
+	for _, b := range p.batchers {
+		var t *task
+		b.mutex.Lock()
+		if b.task != nil {
+			t = b.take()
+		}
+		b.mutex.Unlock()
+		if t == nil {
+			continue
+		}
+		if wait {
+			p.enqueue(t)
+		} else {
+			p.abandon(t)
+		}
+	}
+}
+
+// take sets the function of the batch's task to run the collected items, and
+// starts a new batch.  Returns the task.  Must be called with the mutex held.
+func (b *keyBatch) take() *task {
+	t := b.task
+	items := b.items
+	run := b.Run
+	t.fn = func() { run(items) }
+	b.timer.Stop()
+	b.task = nil
+	b.items = nil
+	return t
+}
@@ -0,0 +1,94 @@
+package workerpool
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubmitBatched(t *testing.T) {
+	t.Parallel()
+
+	var mutex sync.Mutex
+	var batches [][]interface{}
+	run := func(items []interface{}) {
+		mutex.Lock()
+		batches = append(batches, items)
+		mutex.Unlock()
+	}
+	wp := New(2,
+		WithBatcher("size", Batcher{MaxSize: 3, MaxWait: time.Hour, Run: run}),
+		WithBatcher("wait", Batcher{MaxWait: 20 * time.Millisecond, Run: run}))
+	defer wp.Stop()
+
+	if err := wp.SubmitBatched("other", 1); err != ErrUnknownBatch {
+		t.Fatal("expected ErrUnknownBatch, got", err)
+	}
+
+	// Full batches are sent without waiting.
+	for i := 0; i < 6; i++ {
+		if err := wp.SubmitBatched("size", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wp.Wait()
+	if len(batches) != 2 || len(batches[0]) != 3 || len(batches[1]) != 3 {
+		t.Fatal("expected 2 batches of 3 items, got", batches)
+	}
+
+	// Items are pending until their batch is sent after MaxWait.
+	batches = nil
+	for i := 0; i < 5; i++ {
+		wp.SubmitBatched("wait", i)
+	}
+	wp.Wait()
+	if len(batches) != 1 || len(batches[0]) != 5 {
+		t.Fatal("expected 1 batch of 5 items, got", batches)
+	}
+	for i, item := range batches[0] {
+		if item != i {
+			t.Fatal("items not in submitted order:", batches[0])
+		}
+	}
+}
+
+func TestSubmitBatchedStop(t *testing.T) {
+	t.Parallel()
+
+	var mutex sync.Mutex
+	var items []interface{}
+	wp := New(1, WithBatcher("wait", Batcher{MaxWait: time.Hour, Run: func(batch []interface{}) {
+		mutex.Lock()
+		items = append(items, batch...)
+		mutex.Unlock()
+	}}))
+	wp.SubmitBatched("wait", 1)
+	wp.SubmitBatched("wait", 2)
+	wp.StopWait()
+	if len(items) != 2 {
+		t.Fatal("expected StopWait to run the open batch, got", items)
+	}
+
+	// Stop drops and reports the open batch.
+	var rejected int
+	wp = New(1, WithOnReject(func(func(), RejectReason) { rejected++ }),
+		WithBatcher("tasks", Batcher{MaxWait: time.Hour}))
+	var ran int
+	wp.SubmitBatched("tasks", func() { ran++ })
+	wp.Stop()
+	wp.Wait()
+	if ran != 0 || rejected != 1 || wp.Stats().DroppedTasks != 1 {
+		t.Fatal("expected open batch to be dropped, ran", ran, "rejected", rejected)
+	}
+
+	// Without a Run function, the batched tasks are run in order.
+	ran = 0
+	wp = New(1, WithBatcher("tasks", Batcher{MaxSize: 2, MaxWait: time.Hour}))
+	defer wp.Stop()
+	wp.SubmitBatched("tasks", func() { ran++ })
+	wp.SubmitBatched("tasks", func() { ran *= 10 })
+	wp.Wait()
+	if ran != 10 {
+		t.Fatal("expected batched tasks to run in order, got", ran)
+	}
+}
@@ -0,0 +1,173 @@
+package workerpool
+
+import (
+	"fmt"
+	"time"
+)
+
+// ErrCircuitOpen is returned by SubmitTagged when the circuit breaker for the
+// task's tag is open.  It wraps ErrRejected.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit open", ErrRejected)
+
+// Breaker configures the circuit breakers of tasks submitted using
+// SubmitTagged.  Each tag has its own circuit breaker.  When the failure rate of
+// a tag's tasks reaches FailureRate, the breaker opens, and further tasks with
+// the tag are rejected, instead of occupying workers, until the Cooldown
+// period has passed.  Then a single task is allowed to run, and the breaker
+// closes if the task succeeds or opens again if it fails.  If the task does not
+// complete within another Cooldown period, because it was dropped, rejected,
+// or is still running, then another task is allowed to run in its place.
+type Breaker struct {
+	// FailureRate is the fraction of failed tasks, from 0 to 1, at which the
+	// breaker opens.  The default is 0.5.
+	FailureRate float64
+	// MinTasks is the number of tasks that must complete within the Window
+	// before the failure rate is considered.  The default is 10.
+	MinTasks int
+	// Window is the period over which the failure rate is measured.  The
+	// default is 1 minute.
+	Window time.Duration
+	// Cooldown is how long the breaker stays open before a task is allowed
+	// to run.  The default is 30 seconds.
+	Cooldown time.Duration
+	// DeadLetter, if not nil, is called with each task that is rejected
+	// because its breaker is open, so that the task can be saved or retried
+	// later.
+	DeadLetter func(tag string, task func() error)
+}
+
+// WithCircuitBreaker enables circuit breakers for tasks submitted using
+// SubmitTagged.
+func WithCircuitBreaker(breaker Breaker) Option {
+	return func(p *WorkerPool) {
+		if breaker.FailureRate <= 0 {
+			breaker.FailureRate = 0.5
+		}
+		if breaker.MinTasks < 1 {
+			breaker.MinTasks = 10
+		}
+		if breaker.Window <= 0 {
+			breaker.Window = time.Minute
+		}
+		if breaker.Cooldown <= 0 {
+			breaker.Cooldown = 30 * time.Second
+		}
+		p.breaker = &breaker
+		p.circuits = map[string]*circuit{}
+	}
+}
+
+// Circuit breaker states.
+const (
+	circuitClosed = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuit is the circuit breaker of one tag.
+type circuit struct {
+	state       int
+	windowStart time.Time
+	tasks       int
+	failures    int
+	// openedAt is when the breaker opened, or when the half-open breaker
+	// allowed a task to run.
+	openedAt time.Time
+}
+
+// SubmitTagged enqueues a function for a worker to execute, the same as
+// Submit, as one of the class of tasks identified by tag.  The error returned
+// by the function records whether the task failed.  If the worker pool was
+// created using WithCircuitBreaker, and the breaker for the tag is open, then
+// the task is not submitted and ErrCircuitOpen is returned.  The rejected task
+// is given to the breaker's DeadLetter function, if it has one.  If the worker
+// pool is stopped, then the task is dropped and ErrStopped is returned.
+func (p *WorkerPool) SubmitTagged(tag string, task func() error) error {
+	if task == nil {
+		return nil
+	}
+	if p.breaker == nil {
+		return p.enqueue(p.newErrTask(task, nil))
+	}
+	if !p.allowTag(tag) {
+		p.rejectTagged(tag, task)
+		return ErrCircuitOpen
+	}
+	return p.enqueue(p.newErrTask(task, func(err error) {
+		p.recordTag(tag, err != nil)
+	}))
+}
+
+// rejectTagged reports a task rejected by its tag's open breaker, and gives
+// the task to the DeadLetter function.
+func (p *WorkerPool) rejectTagged(tag string, fn func() error) {
+	p.count(MetricTasksRejected, 1)
+	p.emit(Rejected, &task{meta: &Metadata{Name: tag}})
+	if p.breaker.DeadLetter != nil {
+		p.breaker.DeadLetter(tag, fn)
+	}
+	if p.onReject != nil {
+		p.rejected(func() { fn() }, RejectedByBreaker)
+	}
+}
+
+// allowTag returns true if a task with the tag may be submitted.
+func (p *WorkerPool) allowTag(tag string) bool {
+	p.circuitMutex.Lock()
+	defer p.circuitMutex.Unlock()
+	c := p.circuits[tag]
+	if c == nil {
+		return true
+	}
+	if c.state == circuitClosed {
+		return true
+	}
+	now := p.clock.Now()
+	if now.Sub(c.openedAt) < p.breaker.Cooldown {
+		return false
+	}
+	// Allow one task to test whether the tag's tasks succeed again, or
+	// another in place of a test task that has not completed.
+	c.state = circuitHalfOpen
+	c.openedAt = now
+	return true
+}
+
+// recordTag records whether a task with the tag failed, and opens or closes
+// the tag's breaker.
+func (p *WorkerPool) recordTag(tag string, failed bool) {
+	now := p.clock.Now()
+	p.circuitMutex.Lock()
+	defer p.circuitMutex.Unlock()
+	c := p.circuits[tag]
+	if c == nil {
+		c = &circuit{windowStart: now}
+		p.circuits[tag] = c
+	}
+	switch c.state {
+	case circuitHalfOpen:
+		if failed {
+			c.state = circuitOpen
+			c.openedAt = now
+		} else {
+			*c = circuit{windowStart: now}
+		}
+		return
+	case circuitOpen:
+		// Tasks submitted before the breaker opened are not counted.
+		return
+	}
+	if now.Sub(c.windowStart) >= p.breaker.Window {
+		c.windowStart = now
+		c.tasks, c.failures = 0, 0
+	}
+	c.tasks++
+	if failed {
+		c.failures++
+	}
+	if c.tasks >= p.breaker.MinTasks &&
+		float64(c.failures) >= p.breaker.FailureRate*float64(c.tasks) {
+		c.state = circuitOpen
+		c.openedAt = now
+	}
+}
@@ -0,0 +1,104 @@
+package workerpool
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	clock := NewManualClock(time.Now())
+	var deadLetters int
+	wp := New(1, WithClock(clock), WithCircuitBreaker(Breaker{
+		FailureRate: 0.5,
+		MinTasks:    4,
+		Cooldown:    time.Minute,
+		DeadLetter:  func(tag string, task func() error) { deadLetters++ },
+	}))
+	defer wp.Stop()
+
+	errFail := errors.New("fail")
+	fail := func() error { return errFail }
+	succeed := func() error { return nil }
+
+	for _, task := range []func() error{succeed, fail, succeed, fail} {
+		if err := wp.SubmitTagged("db", task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wp.Wait()
+	if err := wp.SubmitTagged("db", succeed); err != ErrCircuitOpen {
+		t.Fatal("expected ErrCircuitOpen, got", err)
+	}
+	if deadLetters != 1 {
+		t.Fatal("rejected task not given to dead letter function")
+	}
+	// Other tags are not affected.
+	if err := wp.SubmitTagged("cache", fail); err != nil {
+		t.Fatal(err)
+	}
+
+	// After the cooldown, one task is allowed, and it failing opens the
+	// breaker again.
+	clock.Advance(time.Minute)
+	if err := wp.SubmitTagged("db", fail); err != nil {
+		t.Fatal(err)
+	}
+	if err := wp.SubmitTagged("db", succeed); err != ErrCircuitOpen {
+		t.Fatal("expected ErrCircuitOpen while testing, got", err)
+	}
+	wp.Wait()
+	if err := wp.SubmitTagged("db", succeed); err != ErrCircuitOpen {
+		t.Fatal("expected ErrCircuitOpen, got", err)
+	}
+
+	// A successful test task closes the breaker.
+	clock.Advance(time.Minute)
+	if err := wp.SubmitTagged("db", succeed); err != nil {
+		t.Fatal(err)
+	}
+	wp.Wait()
+	if err := wp.SubmitTagged("db", succeed); err != nil {
+		t.Fatal("expected closed breaker, got", err)
+	}
+	wp.Wait()
+}
+
+func TestCircuitBreakerProbeTimeout(t *testing.T) {
+	t.Parallel()
+
+	clock := NewManualClock(time.Now())
+	wp := New(2, WithClock(clock), WithCircuitBreaker(Breaker{
+		MinTasks: 1,
+		Cooldown: time.Minute,
+	}))
+	defer wp.Stop()
+
+	if err := wp.SubmitTagged("db", func() error { return errors.New("fail") }); err != nil {
+		t.Fatal(err)
+	}
+	wp.Wait()
+
+	// A test task that does not complete within the cooldown does not keep
+	// the breaker from allowing another.
+	clock.Advance(time.Minute)
+	release := make(chan struct{})
+	if err := wp.SubmitTagged("db", func() error { <-release; return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := wp.SubmitTagged("db", func() error { return nil }); err != ErrCircuitOpen {
+		t.Fatal("expected ErrCircuitOpen while testing, got", err)
+	}
+	clock.Advance(time.Minute)
+	if err := wp.SubmitTagged("db", func() error { return nil }); err != nil {
+		t.Fatal("expected another test task to be allowed, got", err)
+	}
+	close(release)
+	wp.Wait()
+	if err := wp.SubmitTagged("db", func() error { return nil }); err != nil {
+		t.Fatal("expected closed breaker, got", err)
+	}
+	wp.Wait()
+}
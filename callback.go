@@ -0,0 +1,35 @@
+package workerpool
+
+// WithCallbackPool makes SubmitWithCallback run completion callbacks on the
+// given worker pool, instead of on the worker that ran the task.  This keeps
+// slow callbacks from occupying this worker pool's workers.
+func WithCallbackPool(callbacks *WorkerPool) Option {
+	return func(p *WorkerPool) {
+		p.callbacks = callbacks
+	}
+}
+
+// SubmitWithCallback enqueues a function for a worker to execute, and calls
+// done with the error returned by the function after it returns.  Unlike
+// SubmitWait, no goroutine is blocked waiting for the task.  The callback runs
+// on the worker that ran the task, or on the worker pool set using
+// WithCallbackPool.  The callback is not called if the task does not run.  If
+// the task panics, and the panic is recovered because the worker pool was
+// created using WithErrors or WithPanicBackoff, the callback is given a
+// *PanicError.
+func (p *WorkerPool) SubmitWithCallback(task func() error, done func(error)) {
+	if task == nil {
+		return
+	}
+	var callback func(error)
+	switch {
+	case done == nil:
+	case p.callbacks != nil:
+		callback = func(err error) {
+			p.callbacks.Submit(func() { done(err) })
+		}
+	default:
+		callback = done
+	}
+	p.enqueueAdmitted(p.newErrTask(task, callback))
+}
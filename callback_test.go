@@ -0,0 +1,33 @@
+package workerpool
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSubmitWithCallback(t *testing.T) {
+	t.Parallel()
+
+	callbacks := New(1)
+	defer callbacks.Stop()
+	wp := New(2, WithCallbackPool(callbacks))
+	defer wp.Stop()
+
+	errFail := errors.New("fail")
+	results := make(chan error, 2)
+	wp.SubmitWithCallback(func() error { return nil }, func(err error) { results <- err })
+	wp.SubmitWithCallback(func() error { return errFail }, func(err error) { results <- err })
+	wp.SubmitWithCallback(func() error { return nil }, nil)
+
+	var failed int
+	for i := 0; i < 2; i++ {
+		if err := <-results; err == errFail {
+			failed++
+		} else if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+	}
+	if failed != 1 {
+		t.Fatal("expected one failure, got", failed)
+	}
+}
@@ -0,0 +1,83 @@
+package workerpool
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrChaos is the value of the panics injected by WithChaos.
+var ErrChaos = errors.New("workerpool: injected panic")
+
+// Chaos describes the faults that WithChaos injects into a worker pool.  A
+// zero value injects no faults.
+type Chaos struct {
+	// Latency is the maximum delay added before each task runs.  Each task
+	// is delayed by a random duration up to Latency.
+	Latency time.Duration
+	// DropRate is the probability, from 0 to 1, that a task is not run, as
+	// if it failed without doing its work.  A dropped task is still complete
+	// for the purposes of Wait and Flush.
+	DropRate float64
+	// PanicRate is the probability, from 0 to 1, that the worker panics with
+	// ErrChaos instead of running a task.  Since the panic is not recovered,
+	// this crashes the program, and is for testing how the program is
+	// restarted.
+	PanicRate float64
+	// WorkerStartDelay is the delay before each new worker starts running
+	// tasks.
+	WorkerStartDelay time.Duration
+	// Seed seeds the random choices, so that a test can repeat the same
+	// faults.  If zero, a random seed is used.
+	Seed int64
+}
+
+// WithChaos injects faults into the worker pool, so that applications can
+// test how they handle a slow or misbehaving worker pool.  This option is only
+// meant for tests.
+func WithChaos(chaos Chaos) Option {
+	return func(p *WorkerPool) {
+		seed := chaos.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		p.chaos = &chaosInjector{
+			Chaos: chaos,
+			rand:  rand.New(rand.NewSource(seed)),
+		}
+	}
+}
+
+type chaosInjector struct {
+	Chaos
+	mutex sync.Mutex
+	rand  *rand.Rand
+}
+
+// beforeTask delays a task and may panic.  Returns true if the task is to be
+// dropped.
+func (c *chaosInjector) beforeTask() bool {
+	c.mutex.Lock()
+	var delay time.Duration
+	if c.Latency > 0 {
+		delay = time.Duration(c.rand.Int63n(int64(c.Latency) + 1))
+	}
+	fault := c.rand.Float64()
+	c.mutex.Unlock()
+
+	if delay != 0 {
+		time.Sleep(delay)
+	}
+	if fault < c.PanicRate {
+		panic(ErrChaos)
+	}
+	return fault < c.PanicRate+c.DropRate
+}
+
+// workerStarted delays a new worker.
+func (c *chaosInjector) workerStarted() {
+	if c.WorkerStartDelay > 0 {
+		time.Sleep(c.WorkerStartDelay)
+	}
+}
@@ -0,0 +1,40 @@
+package workerpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChaos(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4, WithChaos(Chaos{
+		Latency:          2 * time.Millisecond,
+		DropRate:         0.5,
+		WorkerStartDelay: 5 * time.Millisecond,
+		Seed:             1,
+	}))
+	var ran int32
+	start := time.Now()
+	for i := 0; i < 100; i++ {
+		wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	wp.StopWait()
+
+	if ran == 0 || ran == 100 {
+		t.Fatal("expected some tasks to be dropped, ran", ran)
+	}
+	if time.Since(start) < 5*time.Millisecond {
+		t.Fatal("workers were not delayed")
+	}
+
+	defer func() {
+		if r := recover(); r != ErrChaos {
+			t.Fatal("expected ErrChaos panic, got", r)
+		}
+	}()
+	p := &WorkerPool{}
+	WithChaos(Chaos{PanicRate: 1})(p)
+	p.chaos.beforeTask()
+}
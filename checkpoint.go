@@ -0,0 +1,148 @@
+package workerpool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// checkpoint collects the serializable tasks abandoned when the worker pool
+// is stopped by Checkpoint.
+type checkpoint struct {
+	mutex sync.Mutex
+	tasks []SerializableTask
+}
+
+// Checkpoint stops the worker pool, the same as Stop, and writes the
+// serializable tasks that were waiting to w, so that they can be submitted
+// again using Restore when the program restarts.  Only tasks submitted using
+// SubmitSerializable are saved, and other waiting tasks are abandoned.  The
+// tasks' tenants are not saved.
+//
+// Checkpoint waits for running tasks to complete, and does nothing if the
+// worker pool is already stopped.  Returns ErrReentrantWait if called from a
+// task, which cannot wait for the worker pool to stop.
+func (p *WorkerPool) Checkpoint(w io.Writer) error {
+	p.stopMutex.Lock()
+	if p.stopped {
+		p.stopMutex.Unlock()
+		return nil
+	}
+	if p.isWorker() {
+		p.stopMutex.Unlock()
+		return fmt.Errorf("%w: Checkpoint called from task", ErrReentrantWait)
+	}
+	// The checkpoint is set in the same critical section that starts the
+	// stop, so that a concurrent Stop cannot drop the tasks instead.
+	cp := &checkpoint{}
+	p.checkpoint = cp
+	p.beginStop(false, StoppedByCheckpoint)
+	stopped := p.runState.Load().stopped
+	p.stopMutex.Unlock()
+	<-stopped
+
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+	bw := bufio.NewWriter(w)
+	var rec []byte
+	for _, st := range cp.tasks {
+		payload, err := st.Payload()
+		if err != nil {
+			return fmt.Errorf("encoding task %q: %w", st.TaskName(), err)
+		}
+		rec = appendBytes(rec[:0], []byte(st.TaskName()))
+		rec = appendBytes(rec, payload)
+		if _, err = bw.Write(rec); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Restore reads tasks written by Checkpoint from r, decodes them using the
+// registry, and submits them to the worker pool.  Returns the number of tasks
+// submitted.  If a task cannot be decoded, Restore returns the error after
+// submitting the tasks before it.
+func (p *WorkerPool) Restore(r io.Reader, registry *TaskRegistry) (int, error) {
+	br := bufio.NewReader(r)
+	var n int
+	for {
+		name, err := readRecordBytes(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return n, nil
+			}
+			return n, err
+		}
+		payload, err := readRecordBytes(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return n, err
+		}
+		st, err := registry.Decode(string(name), payload)
+		if err != nil {
+			return n, err
+		}
+		p.SubmitSerializable(st)
+		n++
+	}
+}
+
+// readRecordBytes reads a length-prefixed byte string written by appendBytes.
+func readRecordBytes(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, size)
+	if _, err = io.ReadFull(r, b); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
+
+// abandon records that a task was abandoned without running, because the
+// worker pool was stopped.  If the worker pool is being stopped by Checkpoint,
+// a serializable task is saved.  Otherwise the task is dropped.
+func (p *WorkerPool) abandon(t *task) {
+	if !t.claim() {
+		// The task was canceled.
+		return
+	}
+	cp := p.checkpoint
+	if cp == nil || t.serializable == nil {
+		p.drop(t)
+		return
+	}
+	p.taskIndex.finish(t, StateDropped)
+	cp.mutex.Lock()
+	cp.tasks = append(cp.tasks, t.serializable)
+	cp.mutex.Unlock()
+	p.taskDone(t)
+}
+
+// drop records that a task was dropped without running, and reports it to the
+// task's dropped function and to the OnDropped and OnReject functions.  All
+// dropped tasks are reported here, so that none are dropped silently.
+func (p *WorkerPool) drop(t *task) {
+	p.taskIndex.finish(t, StateDropped)
+	atomic.AddInt64(&p.droppedTasks, 1)
+	if t.dropped != nil {
+		t.dropped()
+	}
+	fn := t.runFunc()
+	if p.onDropped != nil && fn != nil {
+		p.onDropped(fn)
+	}
+	p.rejected(fn, RejectedByStop)
+	p.taskDone(t)
+}
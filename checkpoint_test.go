@@ -0,0 +1,64 @@
+package workerpool
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckpoint(t *testing.T) {
+	t.Parallel()
+
+	var mutex sync.Mutex
+	var got []int
+	record := func(ctx context.Context, n int) error {
+		mutex.Lock()
+		got = append(got, n)
+		mutex.Unlock()
+		return nil
+	}
+	var reg TaskRegistry
+	RegisterJSON(&reg, "record", record)
+
+	for _, spill := range []bool{false, true} {
+		got = nil
+		var options []Option
+		if spill {
+			options = append(options, WithSpill(2, t.TempDir(), &reg))
+		}
+		wp := New(1, options...)
+		started := make(chan struct{})
+		wp.Submit(func() {
+			close(started)
+			time.Sleep(20 * time.Millisecond)
+		})
+		<-started
+		for i := 0; i < 5; i++ {
+			wp.SubmitSerializable(NewJSONTask("record", i, record))
+		}
+		wp.Submit(func() { t.Error("abandoned task ran") })
+
+		var buf bytes.Buffer
+		if err := wp.Checkpoint(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if !wp.Stopped() || len(got) != 0 {
+			t.Fatal("worker pool not stopped by Checkpoint")
+		}
+
+		wp = New(2)
+		n, err := wp.Restore(&buf, &reg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 5 {
+			t.Fatal("expected 5 restored tasks, got", n)
+		}
+		wp.StopWait()
+		if len(got) != 5 {
+			t.Fatal("expected 5 tasks to run, got", got)
+		}
+	}
+}
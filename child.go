@@ -0,0 +1,82 @@
+package workerpool
+
+import "runtime/debug"
+
+// Child creates a worker pool whose tasks are executed by the workers of this
+// worker pool.  The child pool has its own queue and runs at most maxWorkers
+// of its tasks at once, and each of its running tasks counts against this
+// pool's maximum number of workers.  This allows a service to give each
+// subsystem its own pool, limited to a slice of the parent's capacity.
+//
+// Stopping the parent pool first stops all of its child pools, in the same
+// way: with Stop, the children abandon their queued tasks, and with StopWait,
+// the children complete them.  A child created after the parent is stopped is
+// already stopped.  A child may also be stopped by itself.
+func (p *WorkerPool) Child(maxWorkers int, options ...Option) *WorkerPool {
+	child := New(maxWorkers, options...)
+	child.parent = p
+	p.childMutex.Lock()
+	if p.childrenStopped {
+		p.childMutex.Unlock()
+		child.stop(false, StoppedByParent)
+		return child
+	}
+	if p.children == nil {
+		p.children = map[*WorkerPool]struct{}{}
+	}
+	p.children[child] = struct{}{}
+	p.childMutex.Unlock()
+	return child
+}
+
+// exec executes a task function.  A child pool executes the function on one
+// of its parent's workers, and waits for it to complete.  If the child pool
+// recovers panics, a panic on the parent's worker is recovered there and
+// raised again on the child's worker, so that the child reports it.
+func (p *WorkerPool) exec(fn func()) {
+	if p.parent == nil {
+		fn()
+		return
+	}
+	if p.errors == nil && p.panicBackoff == nil {
+		p.parent.SubmitWait(fn)
+		return
+	}
+	var forwarded *PanicError
+	p.parent.SubmitWait(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				forwarded = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+		}()
+		fn()
+	})
+	if forwarded != nil {
+		panic(forwardedPanic{forwarded})
+	}
+}
+
+// forwardedPanic is raised by a child pool's worker for a panic recovered on
+// its parent's worker.
+type forwardedPanic struct {
+	err *PanicError
+}
+
+// stopChildren stops all of the child pools.
+func (p *WorkerPool) stopChildren(wait bool) {
+	p.childMutex.Lock()
+	p.childrenStopped = true
+	children := p.children
+	p.children = nil
+	p.childMutex.Unlock()
+	for child := range children {
+		child.stop(wait, StoppedByParent)
+	}
+}
+
+// removeChild forgets a child pool that has stopped.
+func (p *WorkerPool) removeChild(child *WorkerPool) {
+	p.childMutex.Lock()
+	delete(p.children, child)
+	p.childMutex.Unlock()
+}
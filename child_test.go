@@ -0,0 +1,78 @@
+package workerpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestChild(t *testing.T) {
+	t.Parallel()
+
+	wp := New(3)
+	children := []*WorkerPool{wp.Child(2), wp.Child(2)}
+
+	var running, maxRunning, done int32
+	var mutex sync.Mutex
+	for i := 0; i < 12; i++ {
+		children[i%2].Submit(func() {
+			n := atomic.AddInt32(&running, 1)
+			mutex.Lock()
+			if n > maxRunning {
+				maxRunning = n
+			}
+			mutex.Unlock()
+			time.Sleep(2 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			atomic.AddInt32(&done, 1)
+		})
+	}
+	wp.StopWait()
+
+	if done != 12 {
+		t.Fatal("expected 12 tasks to complete, got", done)
+	}
+	if maxRunning != 3 {
+		t.Fatal("expected at most 3 tasks running, had", maxRunning)
+	}
+	for _, child := range children {
+		if !child.Stopped() {
+			t.Fatal("child not stopped with parent")
+		}
+	}
+	if child := wp.Child(1); !child.Stopped() {
+		t.Fatal("child of stopped pool not stopped")
+	}
+}
+
+func TestChildStopFromTask(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	child := wp.Child(1)
+	var ran int32
+	child.Submit(func() {
+		child.Stop()
+		atomic.AddInt32(&ran, 1)
+	})
+	select {
+	case <-child.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("child pool did not stop after its task called stop")
+	}
+	if atomic.LoadInt32(&ran) != 1 {
+		t.Fatal("task did not complete after stopping child pool")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wp.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("parent pool did not stop")
+	}
+}
@@ -0,0 +1,161 @@
+package workerpool
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the time to a worker pool.  It is used for the idle timeout
+// and for choosing which idle workers to stop, for the cooldown of circuit
+// breakers, for smoothing and the worker rate limit, and for measuring
+// utilization.  Tests can use a ManualClock to control when these expire.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// NewTimer creates a timer that sends the current time on its channel
+	// after at least duration d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer created by a Clock.  It behaves like a time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is sent when the timer fires.
+	C() <-chan time.Time
+	// Reset changes the timer to fire after duration d.  Returns true if the
+	// timer had been active.
+	Reset(d time.Duration) bool
+	// Stop prevents the timer from firing.  Returns true if the timer had
+	// been active.
+	Stop() bool
+}
+
+// WithClock sets the clock used for the worker pool's timing, described by
+// Clock.  The default is the system clock.
+func WithClock(clock Clock) Option {
+	return func(p *WorkerPool) {
+		p.clock = clock
+	}
+}
+
+// systemClock is a Clock that uses the time package.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.Timer.C }
+
+// ManualClock is a Clock whose time only changes when it is advanced.  Use it
+// to test how a worker pool stops idle workers, or how a circuit breaker
+// recovers, without waiting for time to pass.
+type ManualClock struct {
+	mutex sync.Mutex
+	now   time.Time
+	// timers holds the active timers.  Timers are removed when they fire
+	// or are stopped, and added again when reset.
+	timers []*manualTimer
+}
+
+// NewManualClock creates a ManualClock set to the given time.
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+// Now returns the clock's current time.
+func (c *ManualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer that fires when the clock is advanced by at least
+// duration d.
+func (c *ManualClock) NewTimer(d time.Duration) Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &manualTimer{
+		clock:  c,
+		c:      make(chan time.Time, 1),
+		when:   c.now.Add(d),
+		active: true,
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the clock forward by duration d, and fires the timers that
+// expire by the new time.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	active := c.timers[:0]
+	for _, t := range c.timers {
+		if t.when.After(c.now) {
+			active = append(active, t)
+			continue
+		}
+		t.active = false
+		select {
+		case t.c <- c.now:
+		default:
+		}
+	}
+	for i := len(active); i < len(c.timers); i++ {
+		c.timers[i] = nil
+	}
+	c.timers = active
+}
+
+// remove removes a timer that is no longer active.  Must be called with the
+// mutex held.
+func (c *ManualClock) remove(t *manualTimer) {
+	for i, timer := range c.timers {
+		if timer == t {
+			last := len(c.timers) - 1
+			c.timers[i] = c.timers[last]
+			c.timers[last] = nil
+			c.timers = c.timers[:last]
+			return
+		}
+	}
+}
+
+type manualTimer struct {
+	clock  *ManualClock
+	c      chan time.Time
+	when   time.Time
+	active bool
+}
+
+func (t *manualTimer) C() <-chan time.Time { return t.c }
+
+func (t *manualTimer) Reset(d time.Duration) bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	wasActive := t.active
+	t.active = true
+	t.when = t.clock.now.Add(d)
+	if !wasActive {
+		t.clock.timers = append(t.clock.timers, t)
+	}
+	return wasActive
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	wasActive := t.active
+	if wasActive {
+		t.active = false
+		t.clock.remove(t)
+	}
+	return wasActive
+}
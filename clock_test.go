@@ -0,0 +1,74 @@
+package workerpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestManualClock(t *testing.T) {
+	t.Parallel()
+
+	clock := NewManualClock(time.Now())
+	wp := New(3, WithClock(clock), WithIdleTimeout(time.Millisecond))
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		wp.Submit(func() { <-release })
+	}
+	close(release)
+	wp.Wait()
+
+	// The idle timeout does not pass until the clock is advanced.
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&wp.workerCount); n != 3 {
+		t.Fatal("expected 3 workers, got", n)
+	}
+
+	for i := 0; i < 1000 && atomic.LoadInt32(&wp.workerCount) != 0; i++ {
+		clock.Advance(time.Millisecond)
+		time.Sleep(time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&wp.workerCount); n != 0 {
+		t.Fatal("expected idle workers to stop, have", n)
+	}
+}
+
+func TestManualClockTimers(t *testing.T) {
+	t.Parallel()
+
+	clock := NewManualClock(time.Now())
+	fired := clock.NewTimer(time.Second)
+	stopped := clock.NewTimer(time.Second)
+	if !stopped.Stop() {
+		t.Fatal("expected timer to be active")
+	}
+	clock.Advance(time.Second)
+	select {
+	case <-fired.C():
+	default:
+		t.Fatal("expected timer to fire")
+	}
+	select {
+	case <-stopped.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+	if n := len(clock.timers); n != 0 {
+		t.Fatal("expected fired and stopped timers to be removed, have", n)
+	}
+
+	if fired.Reset(time.Second) {
+		t.Fatal("expected fired timer to be inactive")
+	}
+	clock.Advance(time.Second)
+	select {
+	case <-fired.C():
+	default:
+		t.Fatal("expected reset timer to fire")
+	}
+	if n := len(clock.timers); n != 0 {
+		t.Fatal("expected fired timer to be removed, have", n)
+	}
+}
@@ -0,0 +1,26 @@
+package workerpool
+
+// Clone creates and starts a new worker pool with the same maximum number of
+// workers, and the same options, as this worker pool.  The given options are
+// applied after the copied options, so they override them.  For example, a
+// clone with a different maximum number of workers is created using
+// WithMaxWorkers.  This makes it easy to create a pool for each resource with
+// the same hooks and settings.
+//
+// The options are applied again, so a clone shares the values given to the
+// options, such as a MetricsSink or a Limiter, but not the state the options
+// create, such as an Events channel.  The clone is not registered under this
+// pool's name unless it is given one using WithName.  A clone of a child pool
+// is a child of the same parent.
+func (p *WorkerPool) Clone(options ...Option) *WorkerPool {
+	opts := make([]Option, 0, len(p.options)+1+len(options))
+	opts = append(opts, p.options...)
+	opts = append(opts, func(c *WorkerPool) {
+		c.name = ""
+	})
+	opts = append(opts, options...)
+	if p.parent != nil {
+		return p.parent.Child(p.maxWorkers, opts...)
+	}
+	return New(p.maxWorkers, opts...)
+}
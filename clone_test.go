@@ -0,0 +1,34 @@
+package workerpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClone(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2, WithName("clone-test"), WithMinWorkers(1), WithOnIdle(func() {}))
+	defer wp.Stop()
+
+	clone := wp.Clone(WithMaxWorkers(4), WithIdleTimeout(time.Minute))
+	defer clone.Stop()
+	if clone.maxWorkers != 4 || clone.timeout != time.Minute {
+		t.Fatal("options given to Clone not applied")
+	}
+	if clone.minWorkers != 1 || clone.onIdle == nil {
+		t.Fatal("options of original pool not copied")
+	}
+	if clone.Name() != "" || Get("clone-test") != wp {
+		t.Fatal("clone registered under original pool's name")
+	}
+	if wp.maxWorkers != 2 {
+		t.Fatal("original pool changed by Clone")
+	}
+
+	child := wp.Child(1)
+	childClone := child.Clone()
+	if childClone.parent != wp {
+		t.Fatal("clone of child pool is not a child of the same parent")
+	}
+}
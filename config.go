@@ -0,0 +1,110 @@
+package workerpool
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Config holds worker pool settings that can be loaded at run time, from
+// environment variables or JSON, so that a deployment can be tuned without
+// recompiling.  Zero values use the defaults.
+type Config struct {
+	// Name registers the pool by name, as with WithName.
+	Name string `json:"name,omitempty"`
+	// MaxWorkers is the maximum number of workers.  Must be set.
+	MaxWorkers int `json:"max_workers"`
+	// MinWorkers is the number of workers kept running when idle, as with
+	// WithMinWorkers.
+	MinWorkers int `json:"min_workers,omitempty"`
+	// IdleTimeout is the time without new tasks after which an idle worker
+	// is stopped, as with WithIdleTimeout.
+	IdleTimeout time.Duration `json:"idle_timeout,omitempty"`
+	// LIFOWorkers selects the most recently ready worker, as with
+	// WithLIFOWorkers.
+	LIFOWorkers bool `json:"lifo_workers,omitempty"`
+}
+
+// NewFromConfig creates and starts a worker pool using the settings in the
+// configuration.  Additional options are applied after the configuration.
+func NewFromConfig(config Config, options ...Option) *WorkerPool {
+	var opts []Option
+	if config.Name != "" {
+		opts = append(opts, WithName(config.Name))
+	}
+	if config.MinWorkers > 0 {
+		opts = append(opts, WithMinWorkers(config.MinWorkers))
+	}
+	if config.IdleTimeout > 0 {
+		opts = append(opts, WithIdleTimeout(config.IdleTimeout))
+	}
+	if config.LIFOWorkers {
+		opts = append(opts, WithLIFOWorkers())
+	}
+	return New(config.MaxWorkers, append(opts, options...)...)
+}
+
+// ConfigFromEnv reads a configuration from environment variables whose names
+// are the prefix followed by NAME, MAX_WORKERS, MIN_WORKERS, IDLE_TIMEOUT, and
+// LIFO_WORKERS.  For example, with the prefix "WORKERPOOL_", the maximum
+// number of workers is read from WORKERPOOL_MAX_WORKERS.  The idle timeout is
+// a duration, such as "30s", and LIFO_WORKERS is a boolean.  Settings that are
+// not in the environment are not changed from the given configuration, which
+// supplies the defaults.
+func ConfigFromEnv(prefix string, config Config) (Config, error) {
+	if v, ok := os.LookupEnv(prefix + "NAME"); ok {
+		config.Name = v
+	}
+	var err error
+	if v, ok := os.LookupEnv(prefix + "MAX_WORKERS"); ok {
+		if config.MaxWorkers, err = strconv.Atoi(v); err != nil {
+			return config, fmt.Errorf("%sMAX_WORKERS: %w", prefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(prefix + "MIN_WORKERS"); ok {
+		if config.MinWorkers, err = strconv.Atoi(v); err != nil {
+			return config, fmt.Errorf("%sMIN_WORKERS: %w", prefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(prefix + "IDLE_TIMEOUT"); ok {
+		if config.IdleTimeout, err = time.ParseDuration(v); err != nil {
+			return config, fmt.Errorf("%sIDLE_TIMEOUT: %w", prefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(prefix + "LIFO_WORKERS"); ok {
+		if config.LIFOWorkers, err = strconv.ParseBool(v); err != nil {
+			return config, fmt.Errorf("%sLIFO_WORKERS: %w", prefix, err)
+		}
+	}
+	return config, nil
+}
+
+// UnmarshalJSON decodes a configuration, accepting the idle timeout as either
+// a duration string, such as "30s", or a number of nanoseconds.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plain Config
+	var v struct {
+		plain
+		IdleTimeout json.RawMessage `json:"idle_timeout,omitempty"`
+	}
+	v.plain = plain(*c)
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v.IdleTimeout) != 0 {
+		var s string
+		if err := json.Unmarshal(v.IdleTimeout, &s); err == nil {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("idle_timeout: %w", err)
+			}
+			v.plain.IdleTimeout = d
+		} else if err = json.Unmarshal(v.IdleTimeout, &v.plain.IdleTimeout); err != nil {
+			return fmt.Errorf("idle_timeout: %w", err)
+		}
+	}
+	*c = Config(v.plain)
+	return nil
+}
@@ -0,0 +1,71 @@
+package workerpool
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("WPTEST_MAX_WORKERS", "8")
+	t.Setenv("WPTEST_IDLE_TIMEOUT", "30s")
+	t.Setenv("WPTEST_LIFO_WORKERS", "true")
+
+	config, err := ConfigFromEnv("WPTEST_", Config{MaxWorkers: 2, MinWorkers: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{MaxWorkers: 8, MinWorkers: 1, IdleTimeout: 30 * time.Second, LIFOWorkers: true}
+	if config != want {
+		t.Fatalf("expected %+v, got %+v", want, config)
+	}
+
+	t.Setenv("WPTEST_MIN_WORKERS", "many")
+	if _, err = ConfigFromEnv("WPTEST_", Config{}); err == nil {
+		t.Fatal("expected error for invalid number")
+	}
+}
+
+func TestConfigJSON(t *testing.T) {
+	t.Parallel()
+
+	var config Config
+	err := json.Unmarshal([]byte(`{"max_workers": 4, "min_workers": 2, "idle_timeout": "1m"}`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Config{MaxWorkers: 4, MinWorkers: 2, IdleTimeout: time.Minute}
+	if config != want {
+		t.Fatalf("expected %+v, got %+v", want, config)
+	}
+	if err = json.Unmarshal([]byte(`{"idle_timeout": 1000}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.IdleTimeout != 1000 || config.MaxWorkers != 4 {
+		t.Fatalf("wrong config: %+v", config)
+	}
+	if err = json.Unmarshal([]byte(`{"idle_timeout": "soon"}`), &config); err == nil {
+		t.Fatal("expected error for invalid duration")
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	t.Parallel()
+
+	wp := NewFromConfig(Config{MaxWorkers: 4, MinWorkers: 2, IdleTimeout: 10 * time.Millisecond})
+	defer wp.Stop()
+
+	if wp.WorkerCount() != 2 {
+		t.Fatal("expected 2 workers started, got", wp.WorkerCount())
+	}
+	release := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wp.Submit(func() { <-release })
+	}
+	close(release)
+	wp.Wait()
+	time.Sleep(100 * time.Millisecond)
+	if wp.WorkerCount() != 2 {
+		t.Fatal("expected idle workers stopped down to 2, have", wp.WorkerCount())
+	}
+}
@@ -0,0 +1,60 @@
+package workerpool
+
+import (
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// MetricTaskCPUTime times the CPU time used by each task, when enabled using
+// WithCPUTime.
+const MetricTaskCPUTime = "workerpool.task.cpu"
+
+// WithCPUTime records the approximate CPU time used by each task.  The CPU
+// time is given in the TaskDone event, reported to the metrics sink, and
+// totaled in the TaskCPUTime field of the worker pool's Stats.
+//
+// The CPU time is measured by locking the worker's goroutine to its OS thread
+// while the task runs, and reading the thread's CPU clock.  It includes time
+// spent by the Go runtime on the thread, such as garbage collection assists,
+// and excludes work done by other goroutines that the task starts.  CPU time
+// is only available on Linux, and is otherwise recorded as zero.
+func WithCPUTime() Option {
+	return func(p *WorkerPool) {
+		p.cpuTime = true
+	}
+}
+
+// cpuTimer measures the CPU time used by a task.
+type cpuTimer struct {
+	start time.Duration
+	ok    bool
+}
+
+// begin locks the goroutine to its thread, and reads the thread's CPU time.
+func (c *cpuTimer) begin() {
+	runtime.LockOSThread()
+	c.start, c.ok = threadCPUTime()
+}
+
+// end returns the CPU time used since begin, and unlocks the goroutine from
+// its thread.
+func (c *cpuTimer) end() time.Duration {
+	var used time.Duration
+	if c.ok {
+		if now, ok := threadCPUTime(); ok {
+			used = now - c.start
+		}
+	}
+	runtime.UnlockOSThread()
+	return used
+}
+
+// recordCPUTime records the CPU time used by a task.
+func (p *WorkerPool) recordCPUTime(t *task, used time.Duration) {
+	t.cpuTime = used
+	atomic.AddInt64(&p.taskCPUTime, int64(used))
+	if p.metrics != nil {
+		p.metrics.Timing(MetricTaskCPUTime, used)
+	}
+}
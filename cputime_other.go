@@ -0,0 +1,10 @@
+//go:build !linux
+
+package workerpool
+
+import "time"
+
+// threadCPUTime is not available on this platform.
+func threadCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,49 @@
+package workerpool
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCPUTime(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2, WithCPUTime(), WithEvents(10))
+	wp.Submit(func() {
+		// Spin for a while to use CPU time.
+		start := time.Now()
+		for time.Since(start) < 20*time.Millisecond {
+		}
+	})
+	wp.Submit(func() { time.Sleep(20 * time.Millisecond) })
+	wp.StopWait()
+
+	var spun, slept time.Duration
+	for len(wp.Events()) != 0 {
+		ev := <-wp.Events()
+		if ev.Type != TaskDone {
+			continue
+		}
+		if spun == 0 && ev.CPUTime > 10*time.Millisecond {
+			spun = ev.CPUTime
+		} else {
+			slept = ev.CPUTime
+		}
+	}
+	if runtime.GOOS != "linux" {
+		if wp.Stats().TaskCPUTime != 0 {
+			t.Fatal("expected no CPU time")
+		}
+		return
+	}
+	if spun == 0 {
+		t.Fatal("spinning task did not use CPU time")
+	}
+	if slept > 10*time.Millisecond {
+		t.Fatal("sleeping task used too much CPU time:", slept)
+	}
+	if total := wp.Stats().TaskCPUTime; total != spun+slept {
+		t.Fatal("wrong total CPU time:", total)
+	}
+}
@@ -29,11 +29,16 @@ message queue, etc.
 Dispatcher
 
 This worker pool uses a single dispatcher goroutine to read tasks from the
-input task queue and dispatch them to a worker goroutine.  This allows for a
-small input channel, and lets the dispatcher queue as many tasks as are
-submitted when there are no available workers.  Additionally, the dispatcher
-can adjust the number of workers as appropriate for the work load, without
-having to utilize locked counters and checks incurred on task submission.
+input task queue and dispatch them to a worker goroutine.  This lets the
+dispatcher queue as many tasks as are submitted when there are no available
+workers.  Additionally, the dispatcher can adjust the number of workers as
+appropriate for the work load, without having to utilize locked counters and
+checks incurred on task submission.
+
+The input task queue is a lock-free queue that any number of goroutines can
+submit tasks to without waiting for the dispatcher.  The dispatcher is woken
+when tasks are submitted, and receives all tasks submitted since it last
+looked at once.
 
 When no tasks have been submitted for a period of time, a worker is removed by
 the dispatcher.  This is done until there are no more workers to remove.  The
@@ -0,0 +1,144 @@
+package workerpool
+
+import (
+	"reflect"
+	"runtime"
+	"sync/atomic"
+	"time"
+)
+
+// Dump is a snapshot of a worker pool's workers and waiting tasks, for
+// debugging a worker pool that is backed up.
+type Dump struct {
+	// Time is when the snapshot was taken.
+	Time time.Time `json:"time"`
+	// Workers is the state of each worker.  Workers are only listed when the
+	// worker pool is created using WithDebugDump.
+	Workers []WorkerDump `json:"workers,omitempty"`
+	// Waiting is the tasks waiting for a worker, in the order that they are
+	// given to workers.
+	Waiting []TaskDump `json:"waiting,omitempty"`
+	// Spilled is the number of waiting tasks written to disk, which are not
+	// listed in Waiting.
+	Spilled int `json:"spilled,omitempty"`
+}
+
+// WorkerDump is the state of one worker.
+type WorkerDump struct {
+	// Busy is true if the worker is running a task.
+	Busy bool `json:"busy"`
+	// Task is the name of the task that the worker is running.
+	Task string `json:"task,omitempty"`
+	// Labels are the labels of the task that the worker is running.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Running is how long the worker has been running the task.
+	Running time.Duration `json:"running,omitempty"`
+}
+
+// TaskDump describes one waiting task.
+type TaskDump struct {
+	// Task is the name of the task.
+	Task string `json:"task"`
+	// Labels are the labels of the task.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Tenant is the tenant that submitted the task, if not the default.
+	Tenant string `json:"tenant,omitempty"`
+	// Enqueued is the time the task was submitted.  This is only recorded
+	// when the worker pool is created using WithDebugDump.
+	Enqueued time.Time `json:"enqueued,omitempty"`
+}
+
+// WithDebugDump records the state of each worker and the time that each task
+// is submitted, for Dump to report.
+func WithDebugDump() Option {
+	return func(p *WorkerPool) {
+		p.debugDump = true
+		p.recordEnqueue = true
+		p.trackTasks = true
+	}
+}
+
+// Dump returns a snapshot of the worker pool's workers and waiting tasks.
+// The waiting tasks are listed by the dispatcher, so Dump waits for the
+// dispatcher to receive previously submitted tasks.  If the worker pool is
+// stopped, no tasks are waiting.
+func (p *WorkerPool) Dump() Dump {
+	d := Dump{Time: time.Now()}
+
+	if p.debugDump {
+		now := d.Time.UnixNano()
+		p.workerMutex.Lock()
+		for _, ws := range p.workerStates {
+			var wd WorkerDump
+			if started := atomic.LoadInt64(&ws.started); started != 0 {
+				wd.Busy = true
+				wd.Running = time.Duration(now - started)
+				if t := ws.task.Load(); t != nil {
+					wd.Task = t.name()
+					wd.Labels = t.labels()
+				}
+			}
+			d.Workers = append(d.Workers, wd)
+		}
+		p.workerMutex.Unlock()
+	}
+
+	stoppedChan := p.Done()
+	dumpChan := make(chan Dump, 1)
+	p.enqueue(&task{dump: dumpChan})
+	select {
+	case waiting := <-dumpChan:
+		d.Waiting = waiting.Waiting
+		d.Spilled = waiting.Spilled
+	case <-stoppedChan:
+	}
+	return d
+}
+
+// dumpWaiting sends the waiting tasks to a caller of Dump.  This is only
+// called by the dispatcher.
+func (p *WorkerPool) dumpWaiting(dumpChan chan<- Dump) {
+	var d Dump
+	p.waitingQueue.each(func(t *task) {
+		d.Waiting = append(d.Waiting, TaskDump{
+			Task:     t.name(),
+			Labels:   t.labels(),
+			Tenant:   t.tenant,
+			Enqueued: t.enqueued,
+		})
+	})
+	if p.spill != nil {
+		d.Spilled = p.spill.count
+	}
+	dumpChan <- d
+}
+
+// name returns the name of a task, which is the name given in its metadata,
+// or otherwise the name of its function.
+func (t *task) name() string {
+	if t.meta != nil && t.meta.Name != "" {
+		return t.meta.Name
+	}
+	if t.serializable != nil {
+		return t.serializable.TaskName()
+	}
+	if t.ctxFn != nil {
+		return funcName(t.ctxFn)
+	}
+	if t.errFn != nil {
+		return funcName(t.errFn)
+	}
+	return funcName(t.fn)
+}
+
+// funcName returns the name of a function, such as "main.run.func1".
+func funcName(fn any) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	if f := runtime.FuncForPC(v.Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
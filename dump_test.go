@@ -0,0 +1,55 @@
+package workerpool
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDump(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2, WithDebugDump())
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	blocking := func() { <-release }
+	for i := 0; i < 2; i++ {
+		wp.Submit(blocking)
+	}
+	wp.SubmitTenant("other", func() {})
+	for wp.WaitingQueueSize() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	d := wp.Dump()
+	close(release)
+
+	if len(d.Workers) != 2 {
+		t.Fatal("expected 2 workers, got", len(d.Workers))
+	}
+	for _, w := range d.Workers {
+		if !w.Busy || w.Running < 5*time.Millisecond {
+			t.Fatal("expected busy worker, got", w)
+		}
+		if !strings.HasSuffix(w.Task, "TestDump.func1") {
+			t.Fatal("wrong task name:", w.Task)
+		}
+	}
+	if len(d.Waiting) != 1 {
+		t.Fatal("expected 1 waiting task, got", len(d.Waiting))
+	}
+	w := d.Waiting[0]
+	if w.Tenant != "other" || !strings.HasPrefix(w.Task, "github.com/gammazero/workerpool.TestDump") {
+		t.Fatal("wrong waiting task:", w)
+	}
+	if w.Enqueued.IsZero() || w.Enqueued.After(d.Time) {
+		t.Fatal("wrong enqueue time:", w.Enqueued)
+	}
+
+	wp.StopWait()
+	if d = wp.Dump(); len(d.Waiting) != 0 {
+		t.Fatal("expected no waiting tasks after stop")
+	}
+}
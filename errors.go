@@ -0,0 +1,60 @@
+package workerpool
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors that callers can test for using errors.Is.  Errors for the same kind
+// of failure wrap a common error, so that callers can test for the kind of
+// failure without knowing which function returned it.
+var (
+	// ErrStopped is returned when a task is submitted after the worker pool
+	// is stopped, or is dropped because the worker pool stopped.  It is
+	// wrapped by ErrPoolStopped, reported by Healthy.
+	ErrStopped = errors.New("workerpool: stopped")
+	// ErrRejected is wrapped by the errors returned when a task is not
+	// accepted: ErrQueueFull, ErrQuotaExceeded, and ErrCircuitOpen.
+	ErrRejected = errors.New("workerpool: task rejected")
+	// ErrQueueFull is returned when the admission function rejects a task,
+	// because the worker pool has too much work.
+	ErrQueueFull = fmt.Errorf("%w: worker pool is full", ErrRejected)
+	// ErrTaskCanceled is the cause of the context of a task that the worker
+	// pool cancels, such as a task that is preempted.
+	ErrTaskCanceled = errors.New("workerpool: task canceled")
+)
+
+// PanicError is the error of a task that panicked, reported in the Err field of
+// TaskError.  Use errors.As to get the recovered value and stack trace.
+type PanicError struct {
+	// Value is the value that the task panicked with.
+	Value interface{}
+	// Stack is the stack trace of the task when it panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value that the task panicked with, if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// wrapError is an error with its own message that wraps a more general error,
+// so that errors.Is matches both without the general error's message being
+// repeated.
+type wrapError struct {
+	msg string
+	err error
+}
+
+func (e *wrapError) Error() string {
+	return e.msg
+}
+
+func (e *wrapError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,84 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestPanicError(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithErrors(1))
+	defer wp.Stop()
+
+	wp.Submit(func() { panic(io.EOF) })
+	var te TaskError
+	select {
+	case te = <-wp.Errors():
+	case <-time.After(5 * time.Second):
+		t.Fatal("panic not reported")
+	}
+
+	var pe *PanicError
+	if !errors.As(te, &pe) {
+		t.Fatal("expected PanicError, got", te.Err)
+	}
+	if pe.Value != io.EOF || len(pe.Stack) == 0 {
+		t.Fatal("wrong panic value or missing stack:", pe.Value)
+	}
+	if !errors.Is(te, io.EOF) {
+		t.Fatal("expected panic error to wrap the panic value")
+	}
+	if pe.Error() != "panic: EOF" {
+		t.Fatal("wrong error message:", pe.Error())
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithBatcher("b", Batcher{}))
+	wp.Stop()
+	if err := wp.Healthy(); !errors.Is(err, ErrStopped) {
+		t.Fatal("expected ErrStopped, got", err)
+	}
+	if err := wp.Healthy(); err.Error() != "workerpool: pool is stopped" {
+		t.Fatal("wrong error message:", err)
+	}
+	tasks := make(chan func(), 1)
+	tasks <- func() {}
+	submits := []func() error{
+		func() error { return wp.SubmitTenant("t", func() {}) },
+		func() error { return wp.SubmitTimeout(time.Second, func() {}) },
+		func() error { return wp.SubmitTagged("t", func() error { return nil }) },
+		func() error { return wp.SubmitBatched("b", 1) },
+		func() error {
+			return wp.SubmitWaitContext(context.Background(), func() {})
+		},
+		func() error {
+			return wp.Consume(context.Background(), ChanSource(tasks))
+		},
+	}
+	for i, submit := range submits {
+		if err := submit(); err != ErrStopped {
+			t.Fatal("expected ErrStopped from submit", i, "got", err)
+		}
+	}
+
+	wp = New(1, WithAdmission(func(TaskInfo, PoolStats) Decision { return Decision{Action: Reject} }))
+	defer wp.Stop()
+	if err := wp.SubmitTimeout(time.Second, func() {}); !errors.Is(err, ErrQueueFull) {
+		t.Fatal("expected ErrQueueFull, got", err)
+	}
+	for _, err := range []error{ErrQueueFull, ErrQuotaExceeded, ErrCircuitOpen} {
+		if !errors.Is(err, ErrRejected) {
+			t.Fatal("expected error to wrap ErrRejected:", err)
+		}
+	}
+	if errors.Is(ErrQuotaExceeded, ErrQueueFull) || errors.Is(ErrStopped, ErrPoolStopped) {
+		t.Fatal("expected distinct errors")
+	}
+}
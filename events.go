@@ -0,0 +1,126 @@
+package workerpool
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType int
+
+const (
+	// TaskQueued is sent when a task is submitted.
+	TaskQueued EventType = iota
+	// TaskStarted is sent when a worker starts a task.
+	TaskStarted
+	// TaskDone is sent when a task returns, or when its worker is abandoned
+	// because the task did not return after its timeout.
+	TaskDone
+	// WorkerStarted is sent when a worker is started.
+	WorkerStarted
+	// WorkerStopped is sent when a worker is stopped or abandoned.
+	WorkerStopped
+	// Rejected is sent when a task is not accepted, such as when a tenant's
+	// quota is exceeded.
+	Rejected
+	// WorkerBackoff is sent when a worker waits before starting another task
+	// because its tasks panicked.
+	WorkerBackoff
+)
+
+var eventNames = [...]string{
+	TaskQueued:    "TaskQueued",
+	TaskStarted:   "TaskStarted",
+	TaskDone:      "TaskDone",
+	WorkerStarted: "WorkerStarted",
+	WorkerStopped: "WorkerStopped",
+	Rejected:      "Rejected",
+	WorkerBackoff: "WorkerBackoff",
+}
+
+func (e EventType) String() string {
+	if e < 0 || int(e) >= len(eventNames) {
+		return "EventType(" + strconv.Itoa(int(e)) + ")"
+	}
+	return eventNames[e]
+}
+
+// Event describes something that happened in a worker pool.
+type Event struct {
+	Type EventType
+	// Time is when the event happened.
+	Time time.Time
+	// Task is the name of the task, for task events.
+	Task string
+	// Labels are the labels of the task, if submitted with metadata.
+	Labels map[string]string
+	// CPUTime is the CPU time used by the task, for TaskDone events, if
+	// recorded using WithCPUTime.
+	CPUTime time.Duration
+}
+
+// WithEvents makes the worker pool send events on the channel returned by
+// Events.  Up to buffer events are held until they are received.  Events are
+// dropped, instead of slowing the worker pool, when the buffer is full, and are
+// counted in the DroppedEvents field of the worker pool's Stats.
+func WithEvents(buffer int) Option {
+	return func(p *WorkerPool) {
+		if buffer < 1 {
+			buffer = 1
+		}
+		p.events = make(chan Event, buffer)
+	}
+}
+
+// Events returns the channel that the worker pool sends events on, or nil if
+// the worker pool was not created using WithEvents.  The channel is not closed
+// when the worker pool stops, since a worker pool may be rebooted.
+func (p *WorkerPool) Events() <-chan Event {
+	return p.events
+}
+
+// emit sends an event about a task, or about a worker if t is nil, without
+// blocking.
+func (p *WorkerPool) emit(typ EventType, t *task) {
+	if p.events == nil {
+		return
+	}
+	ev := Event{Type: typ, Time: time.Now()}
+	if t != nil {
+		ev.Task = t.name()
+		ev.Labels = t.labels()
+		ev.CPUTime = t.cpuTime
+	}
+	select {
+	case p.events <- ev:
+	default:
+		atomic.AddInt64(&p.droppedEvents, 1)
+	}
+}
+
+// emitRejected sends a Rejected event for a function that was not accepted,
+// and counts the rejection.
+func (p *WorkerPool) emitRejected(fn func()) {
+	p.count(MetricTasksRejected, 1)
+	if p.events != nil {
+		p.emit(Rejected, &task{fn: fn})
+	}
+}
+
+// emitQueued sends a TaskQueued event for a submitted task, or for each task in
+// a submitted batch, and counts the submitted tasks.
+func (p *WorkerPool) emitQueued(t *task) {
+	if (p.events == nil && p.metrics == nil) || t.finished != nil || t.dump != nil {
+		return
+	}
+	if t.batch == nil {
+		p.count(MetricTasksSubmitted, 1)
+		p.emit(TaskQueued, t)
+		return
+	}
+	p.count(MetricTasksSubmitted, int64(len(t.batch)))
+	for _, bt := range t.batch {
+		p.emit(TaskQueued, bt)
+	}
+}
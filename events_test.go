@@ -0,0 +1,51 @@
+package workerpool
+
+import (
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithEvents(100), WithTenantQuota("limited", Quota{MaxQueued: 1}))
+	release := make(chan struct{})
+	wp.SubmitWithMetadata(Metadata{Name: "first"}, func() { <-release })
+	wp.SubmitTenant("limited", func() {})
+	if err := wp.SubmitTenant("limited", func() {}); err != ErrQuotaExceeded {
+		t.Fatal("expected ErrQuotaExceeded, got", err)
+	}
+	close(release)
+	wp.StopWait()
+
+	counts := map[EventType]int{}
+	var first []EventType
+	for len(wp.Events()) != 0 {
+		ev := <-wp.Events()
+		if ev.Time.IsZero() {
+			t.Fatal("event has no time")
+		}
+		counts[ev.Type]++
+		if ev.Task == "first" {
+			first = append(first, ev.Type)
+		}
+	}
+	want := map[EventType]int{
+		TaskQueued:    2,
+		TaskStarted:   2,
+		TaskDone:      2,
+		WorkerStarted: 1,
+		WorkerStopped: 1,
+		Rejected:      1,
+	}
+	for typ, n := range want {
+		if counts[typ] != n {
+			t.Errorf("expected %d %s events, got %d", n, typ, counts[typ])
+		}
+	}
+	if len(first) != 3 || first[0] != TaskQueued || first[1] != TaskStarted || first[2] != TaskDone {
+		t.Fatal("wrong events for task:", first)
+	}
+	if wp.Stats().DroppedEvents != 0 {
+		t.Fatal("events were dropped")
+	}
+}
@@ -0,0 +1,195 @@
+package workerpool
+
+import "github.com/gammazero/deque"
+
+// SubmitTenant enqueues a function for a worker to execute on behalf of the
+// named tenant.  When workers are not available and tasks are queued, the
+// dispatcher takes queued tasks from each tenant with waiting tasks in turn,
+// so that a tenant that submits a large number of tasks does not delay the
+// tasks of other tenants until all of its own tasks have run.  A tenant whose
+// quota has a Weight is given that many tasks in each turn.  Tasks from the
+// same tenant are executed in the order they were submitted.
+//
+// Tasks submitted using Submit, and the other submit functions, belong to the
+// tenant with the empty name.
+//
+// If the tenant has a quota, set using WithTenantQuota, and already has the
+// maximum number of tasks queued, then SubmitTenant either blocks until one of
+// the tenant's queued tasks starts or returns ErrQuotaExceeded, as set by the
+// quota's Block policy.
+func (p *WorkerPool) SubmitTenant(tenant string, task func()) error {
+	if task == nil {
+		return nil
+	}
+	quota := p.quotas[tenant]
+	if quota != nil && !quota.acquire() {
+		p.emitRejected(task)
+		p.rejected(task, RejectedByQuota)
+		return ErrQuotaExceeded
+	}
+	t := p.newTask(task)
+	t.tenant = tenant
+	t.quota = quota
+	err := p.enqueueAdmitted(t)
+	if err != nil && t.quota != nil {
+		// The task was rejected before it was queued.
+		quota.release()
+	}
+	return err
+}
+
+// waitQueue holds the tasks that are waiting for a worker.  Tasks are queued
+// separately for each tenant, and are removed from each tenant that has
+// waiting tasks in weighted round-robin order.  Tenants that have their
+// maximum number of tasks running are held, and do not take a turn until one
+// of their running tasks completes.
+//
+// The waitQueue is only accessed by the dispatcher.
+type waitQueue struct {
+	// newQueue creates the queue of a tenant's waiting tasks.
+	newQueue func() Queue
+	tenants  map[string]*tenantQueue
+	// ready holds the tenants that have waiting tasks and are not held, in
+	// the order that they are next given a worker.
+	ready deque.Deque
+	count int
+	// held is the number of waiting tasks of held tenants.
+	held int
+}
+
+// tenantQueue holds one tenant's waiting tasks.
+type tenantQueue struct {
+	name  string
+	tasks Queue
+	quota *tenantQuota
+	held  bool
+	// taken is the number of tasks taken from the tenant in its current
+	// turn.
+	taken int
+}
+
+// len returns the number of waiting tasks for all tenants.
+func (q *waitQueue) len() int {
+	return q.count
+}
+
+// runnable returns the number of waiting tasks that can be given to a worker.
+func (q *waitQueue) runnable() int {
+	return q.count - q.held
+}
+
+// mustWait returns true if the task cannot be given to a worker before the
+// tasks already waiting, or because its tenant has its maximum number of
+// tasks running.
+func (q *waitQueue) mustWait(t *task) bool {
+	if q.runnable() != 0 {
+		return true
+	}
+	if t.quota == nil {
+		return false
+	}
+	return q.tenants[t.tenant] != nil || t.quota.full()
+}
+
+// push adds a task to the back of its tenant's queue.
+func (q *waitQueue) push(t *task) {
+	tq := q.tenants[t.tenant]
+	if tq == nil {
+		if q.tenants == nil {
+			q.tenants = map[string]*tenantQueue{}
+		}
+		tq = &tenantQueue{name: t.tenant, tasks: q.newQueue(), quota: t.quota}
+		q.tenants[t.tenant] = tq
+		if tq.quota.full() {
+			tq.held = true
+		} else {
+			q.ready.PushBack(tq)
+		}
+	}
+	tq.tasks.Push(t)
+	q.count++
+	if tq.held {
+		q.held++
+	}
+}
+
+// pop removes the next task from the tenant whose turn it is, and records
+// that the task is started.  There must be a runnable task.
+func (q *waitQueue) pop() *task {
+	tq := q.ready.PopFront().(*tenantQueue)
+	t := tq.tasks.Pop().(*task)
+	q.count--
+	q.started(t)
+	switch {
+	case tq.tasks.Len() == 0:
+		// Forget tenants without waiting tasks, so that tenants that come
+		// and go do not accumulate.
+		delete(q.tenants, tq.name)
+	case tq.quota.full():
+		tq.held = true
+		tq.taken = 0
+		q.held += tq.tasks.Len()
+	default:
+		// The tenant keeps its turn until its weight of tasks are taken.
+		if tq.taken++; tq.taken < tq.quota.weight() {
+			q.ready.PushFront(tq)
+		} else {
+			tq.taken = 0
+			q.ready.PushBack(tq)
+		}
+	}
+	return t
+}
+
+// started records that a task is given to a worker.
+func (q *waitQueue) started(t *task) {
+	if t.quota != nil {
+		t.quota.start()
+	}
+}
+
+// finished records that a task of a tenant with a quota has completed, and
+// gives the tenant its turn again if it was held.
+func (q *waitQueue) finished(quota *tenantQuota) {
+	quota.running--
+	tq := q.tenants[quota.name]
+	if tq != nil && tq.held && !quota.full() {
+		tq.held = false
+		q.held -= tq.tasks.Len()
+		q.ready.PushBack(tq)
+	}
+}
+
+// each calls fn for each waiting task, without removing the tasks.  The
+// tasks of each tenant are given in order, starting with the tenants that are
+// not held.
+func (q *waitQueue) each(fn func(*task)) {
+	for i := 0; i < q.ready.Len(); i++ {
+		q.ready.At(i).(*tenantQueue).each(fn)
+	}
+	for _, tq := range q.tenants {
+		if tq.held {
+			tq.each(fn)
+		}
+	}
+}
+
+// each calls fn for each of the tenant's waiting tasks, in the order that
+// they are popped.  Since a Queue can only be read by popping its tasks, the
+// tasks are pushed back onto the queue after fn is called.
+func (tq *tenantQueue) each(fn func(*task)) {
+	if fq, ok := tq.tasks.(*fifoQueue); ok {
+		for i := 0; i < fq.tasks.Len(); i++ {
+			fn(fq.tasks.At(i).(*task))
+		}
+		return
+	}
+	tasks := make([]QueuedTask, tq.tasks.Len())
+	for i := range tasks {
+		tasks[i] = tq.tasks.Pop()
+	}
+	for _, t := range tasks {
+		fn(t.(*task))
+		tq.tasks.Push(t)
+	}
+}
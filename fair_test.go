@@ -0,0 +1,99 @@
+package workerpool
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSubmitTenant(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	defer wp.Stop()
+
+	// Occupy the only worker so that the tenants' tasks are queued.
+	started := make(chan struct{})
+	release := make(chan struct{})
+	wp.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var mutex sync.Mutex
+	var order []string
+	record := func(name string) func() {
+		return func() {
+			mutex.Lock()
+			order = append(order, name)
+			mutex.Unlock()
+		}
+	}
+	for i := 0; i < 10; i++ {
+		wp.SubmitTenant("a", record("a"))
+	}
+	wp.SubmitTenant("b", record("b"))
+	wp.SubmitTenant("b", record("b"))
+	// Give the dispatcher time to queue all the submitted tasks.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wp.Wait()
+
+	if len(order) != 12 {
+		t.Fatal("expected 12 tasks to run, got", len(order))
+	}
+	// The tasks of tenant b must not wait behind all of tenant a's tasks.
+	want := []string{"a", "b", "a", "b", "a"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatal("tasks not taken from tenants in turn:", order)
+		}
+	}
+}
+
+func TestTenantWeight(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithTenantQuota("a", Quota{Weight: 3}))
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	wp.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var mutex sync.Mutex
+	var order []string
+	record := func(name string) func() {
+		return func() {
+			mutex.Lock()
+			order = append(order, name)
+			mutex.Unlock()
+		}
+	}
+	for i := 0; i < 6; i++ {
+		wp.SubmitTenant("a", record("a"))
+	}
+	for i := 0; i < 4; i++ {
+		wp.SubmitTenant("b", record("b"))
+	}
+	for wp.WaitingQueueSize() != 10 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wp.Wait()
+
+	want := []string{"a", "a", "a", "b", "a", "a", "a", "b", "b", "b"}
+	if len(order) != len(want) {
+		t.Fatal("expected 10 tasks to run, got", len(order))
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatal("tasks not taken from tenants by weight:", order)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package workerpool
+
+import "sync/atomic"
+
+// SubmitWithFallback enqueues primary for a worker to execute, unless the
+// worker pool is saturated, in which case fallback is run by the calling
+// goroutine instead.  This degrades gracefully under load, for example by
+// returning a placeholder image instead of queueing an expensive thumbnail.
+// The fallback should be cheap, since it is run before SubmitWithFallback
+// returns.
+//
+// The worker pool is saturated when it is Saturated, if watermarks are set
+// using WithWatermarks.  Otherwise, it is saturated when all workers are busy
+// or tasks are waiting, so that primary would have to wait for a worker.
+//
+// Returns true if primary was submitted, or false if fallback was run.
+func (p *WorkerPool) SubmitWithFallback(primary, fallback func()) bool {
+	if fallback != nil && p.saturated() {
+		fallback()
+		return false
+	}
+	p.Submit(primary)
+	return true
+}
+
+// saturated returns true if a submitted task would not be run promptly.
+func (p *WorkerPool) saturated() bool {
+	if p.watermarks != nil {
+		select {
+		case <-p.Saturated():
+			return true
+		default:
+			return false
+		}
+	}
+	return atomic.LoadInt32(&p.waiting) != 0 ||
+		int(atomic.LoadInt32(&p.busyWorkers)) >= p.maxWorkers
+}
@@ -0,0 +1,38 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmitWithFallback(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	defer wp.Stop()
+
+	var primary, fallback int32
+	if !wp.SubmitWithFallback(func() { atomic.AddInt32(&primary, 1) }, func() { atomic.AddInt32(&fallback, 1) }) {
+		t.Fatal("expected primary to be submitted to idle pool")
+	}
+	wp.WaitContext(context.Background())
+
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	for wp.BusyWorkers() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if wp.SubmitWithFallback(func() { atomic.AddInt32(&primary, 1) }, func() { atomic.AddInt32(&fallback, 1) }) {
+		t.Fatal("expected fallback to run when all workers are busy")
+	}
+	if atomic.LoadInt32(&fallback) != 1 {
+		t.Fatal("fallback did not run in caller")
+	}
+	close(release)
+	wp.StopWait()
+	if atomic.LoadInt32(&primary) != 1 {
+		t.Fatal("expected 1 primary task to run, ran", primary)
+	}
+}
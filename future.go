@@ -0,0 +1,92 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+)
+
+// WaitAll waits for all of the futures to complete, and returns their values
+// in the same order as the futures.  The returned error joins the errors of
+// all of the futures that failed.  If ctx is done first, the values of the
+// futures that have completed are returned, along with the context's error.
+func WaitAll(ctx context.Context, futures ...*Future) ([]interface{}, error) {
+	checkFutures("WaitAll", futures)
+	values := make([]interface{}, len(futures))
+	var errs []error
+	for i, f := range futures {
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return values, ctx.Err()
+		}
+		values[i] = f.value
+		if f.err != nil {
+			errs = append(errs, f.err)
+		}
+	}
+	return values, errors.Join(errs...)
+}
+
+// WaitAny waits for the first of the futures to complete successfully, and
+// returns its value.  If all of the futures fail, the returned error joins
+// their errors.  If ctx is done first, the context's error is returned.
+func WaitAny(ctx context.Context, futures ...*Future) (interface{}, error) {
+	checkFutures("WaitAny", futures)
+	completed := completions(futures)
+	errs := make([]error, 0, len(futures))
+	for range futures {
+		var f *Future
+		select {
+		case f = <-completed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if f.err == nil {
+			return f.value, nil
+		}
+		errs = append(errs, f.err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Race waits for the first of the futures to complete, successfully or not,
+// and returns its result.  If ctx is done first, the context's error is
+// returned.  If there are no futures, Race returns nil values.
+func Race(ctx context.Context, futures ...*Future) (interface{}, error) {
+	checkFutures("Race", futures)
+	if len(futures) == 0 {
+		return nil, nil
+	}
+	select {
+	case f := <-completions(futures):
+		return f.value, f.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// completions returns a channel that receives each future as it completes.
+// The channel is buffered so that the goroutines waiting for the futures exit
+// when the futures complete, even if no longer received from.
+func completions(futures []*Future) <-chan *Future {
+	completed := make(chan *Future, len(futures))
+	for _, f := range futures {
+		go func(f *Future) {
+			<-f.done
+			completed <- f
+		}(f)
+	}
+	return completed
+}
+
+// checkFutures performs the reentrant wait check of the futures' worker
+// pools.
+func checkFutures(op string, futures []*Future) {
+	var checked *WorkerPool
+	for _, f := range futures {
+		if f.pool != checked {
+			f.pool.checkReentrant(op)
+			checked = f.pool
+		}
+	}
+}
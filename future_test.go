@@ -0,0 +1,54 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFutureCombinators(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.StopWait()
+
+	errFail := errors.New("fail")
+	release := make(chan struct{})
+	submit := func(key string, value interface{}, err error, wait bool) *Future {
+		return wp.SubmitKeyedFunc(key, func() (interface{}, error) {
+			if wait {
+				<-release
+			}
+			return value, err
+		})
+	}
+
+	ctx := context.Background()
+	values, err := WaitAll(ctx, submit("a", 1, nil, false), submit("b", 2, errFail, false))
+	if len(values) != 2 || values[0] != 1 || values[1] != 2 || !errors.Is(err, errFail) {
+		t.Fatal("wrong WaitAll result:", values, err)
+	}
+
+	slow := submit("slow", "slow", nil, true)
+	value, err := WaitAny(ctx, slow, submit("c", nil, errFail, false), submit("d", "d", nil, false))
+	if value != "d" || err != nil {
+		t.Fatal("wrong WaitAny result:", value, err)
+	}
+	value, err = WaitAny(ctx, submit("e", nil, errFail, false), submit("f", nil, errFail, false))
+	if value != nil || !errors.Is(err, errFail) {
+		t.Fatal("expected WaitAny error, got", value, err)
+	}
+
+	value, err = Race(ctx, slow, submit("g", nil, errFail, false))
+	if value != nil || err != errFail {
+		t.Fatal("wrong Race result:", value, err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err = Race(timeoutCtx, slow); err != context.DeadlineExceeded {
+		t.Fatal("expected deadline exceeded, got", err)
+	}
+	close(release)
+}
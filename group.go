@@ -0,0 +1,130 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+)
+
+// Group is a collection of tasks, submitted to a worker pool, that are
+// working on subtasks of a common task.  It is similar to errgroup.Group from
+// golang.org/x/sync, except that the concurrency of the tasks is limited by
+// the worker pool instead of each task running in its own goroutine.
+//
+// The first task to return an error cancels the group's context.
+type Group struct {
+	pool    *WorkerPool
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+	// failed is closed when a task returns an error, in fail-fast mode.
+	failed chan struct{}
+}
+
+// Group creates a new Group whose tasks are executed by the worker pool.  The
+// group's context is derived from ctx, and is canceled when a task returns an
+// error or when Wait returns.
+func (p *WorkerPool) Group(ctx context.Context) *Group {
+	gctx, cancel := context.WithCancel(ctx)
+	return &Group{
+		pool:   p,
+		ctx:    gctx,
+		cancel: cancel,
+	}
+}
+
+// FailFast makes Wait return as soon as a task returns an error, instead of
+// waiting for the group's running tasks to return after the group's context is
+// canceled.  It must be called before any tasks are submitted with Go, and
+// returns the group.
+func (g *Group) FailFast() *Group {
+	g.failed = make(chan struct{})
+	return g
+}
+
+// Context returns the group's context.
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// Go submits the task to the worker pool, passing it the group's context.
+// If the group's context is canceled before the task starts, then the task is
+// skipped.
+func (g *Group) Go(task func(context.Context) error) {
+	g.wg.Add(1)
+	g.pool.submit(func() {
+		defer g.wg.Done()
+		if g.ctx.Err() != nil {
+			return
+		}
+		if err := task(g.ctx); err != nil {
+			g.errOnce.Do(func() {
+				g.err = err
+				g.cancel()
+				if g.failed != nil {
+					close(g.failed)
+				}
+			})
+		}
+	})
+}
+
+// Wait blocks until all tasks submitted with Go have completed or been
+// skipped, then returns the first error returned by any task.  In fail-fast
+// mode, Wait returns as soon as a task returns an error.
+func (g *Group) Wait() error {
+	g.pool.checkReentrant("Group.Wait")
+	if g.failed == nil {
+		g.wg.Wait()
+	} else {
+		done := make(chan struct{})
+		go func() {
+			g.wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-g.failed:
+		}
+	}
+	g.cancel()
+	return g.err
+}
+
+// TaskGroup tracks a subset of the tasks submitted to a worker pool, so that
+// a caller can wait for only its own tasks to complete.  This allows many
+// independent callers, such as request handlers, to share one worker pool.
+//
+// A TaskGroup is lightweight and is not stopped or closed.  It may be reused
+// after Wait returns.
+type TaskGroup struct {
+	pool *WorkerPool
+	wg   sync.WaitGroup
+}
+
+// NewGroup creates a new TaskGroup whose tasks are executed by the worker
+// pool.
+func (p *WorkerPool) NewGroup() *TaskGroup {
+	return &TaskGroup{pool: p}
+}
+
+// Submit enqueues a function for a worker to execute, and tracks it as part
+// of the group.
+func (g *TaskGroup) Submit(task func()) {
+	if task == nil {
+		return
+	}
+	g.wg.Add(1)
+	g.pool.submit(func() {
+		defer g.wg.Done()
+		task()
+	})
+}
+
+// Wait blocks until all tasks submitted to the group have completed.  Tasks
+// submitted to the worker pool by other callers are not waited for.
+func (g *TaskGroup) Wait() {
+	g.pool.checkReentrant("TaskGroup.Wait")
+	g.wg.Wait()
+}
@@ -0,0 +1,125 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGroup(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	g := wp.Group(context.Background())
+	var count int32
+	for i := 0; i < 10; i++ {
+		g.Go(func(context.Context) error {
+			atomic.AddInt32(&count, 1)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if count != 10 {
+		t.Fatal("expected 10 tasks to run, ran", count)
+	}
+	if g.Context().Err() == nil {
+		t.Fatal("context should be canceled after Wait")
+	}
+}
+
+func TestGroupFirstError(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	defer wp.Stop()
+
+	g := wp.Group(context.Background())
+	testErr := errors.New("first")
+	var ran int32
+	g.Go(func(context.Context) error {
+		return testErr
+	})
+	for i := 0; i < 10; i++ {
+		g.Go(func(ctx context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return errors.New("later")
+		})
+	}
+	if err := g.Wait(); err != testErr {
+		t.Fatal("expected first error, got", err)
+	}
+	if ran != 0 {
+		t.Fatal("queued tasks should be skipped after error, ran", ran)
+	}
+}
+
+func TestGroupFailFast(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	g := wp.Group(context.Background()).FailFast()
+	testErr := errors.New("failed")
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	g.Go(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(canceled)
+		// Keep running after the context is canceled.
+		<-release
+		return nil
+	})
+	g.Go(func(context.Context) error {
+		<-started
+		return testErr
+	})
+	var ran int32
+	for i := 0; i < 10; i++ {
+		g.Go(func(context.Context) error {
+			atomic.AddInt32(&ran, 1)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != testErr {
+		t.Fatal("expected task error, got", err)
+	}
+	<-canceled
+	if atomic.LoadInt32(&ran) != 0 {
+		t.Fatal("queued tasks should be skipped after error, ran", ran)
+	}
+}
+
+func TestTaskGroup(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.Stop()
+
+	// Task outside the group stays blocked while the group is waited on.
+	release := make(chan struct{})
+	defer close(release)
+	wp.Submit(func() { <-release })
+
+	g1 := wp.NewGroup()
+	g2 := wp.NewGroup()
+	var count1, count2 int32
+	for i := 0; i < 10; i++ {
+		g1.Submit(func() { atomic.AddInt32(&count1, 1) })
+	}
+	g2.Submit(func() { <-release })
+	g2.Submit(func() { atomic.AddInt32(&count2, 1) })
+
+	g1.Wait()
+	if count1 != 10 {
+		t.Fatal("expected 10 group tasks completed, got", count1)
+	}
+}
@@ -0,0 +1,47 @@
+package workerpool
+
+import "sync/atomic"
+
+// Run states of a task.
+const (
+	taskWaiting int32 = iota
+	taskStarted
+	taskCanceled
+)
+
+// Handle refers to a task submitted using SubmitTask.
+type Handle struct {
+	pool *WorkerPool
+	task *task
+}
+
+// Cancel cancels the task, if a worker has not started it.  A canceled task
+// does not run, and is complete for the purposes of Wait and Flush.  Returns
+// true if the task was canceled, or false if the task has already started or
+// was not accepted by the worker pool.
+//
+// A canceled task is discarded when the dispatcher reaches it in the waiting
+// queue, so it is counted by WaitingQueueSize until then.
+func (h *Handle) Cancel() bool {
+	if h == nil {
+		return false
+	}
+	return h.pool.discard(h.task)
+}
+
+// discard records that a task that has not started will not run.  Returns
+// false if the task has already started or been discarded.
+func (p *WorkerPool) discard(t *task) bool {
+	if !atomic.CompareAndSwapInt32(&t.state, taskWaiting, taskCanceled) {
+		return false
+	}
+	p.taskIndex.finish(t, StateCanceled)
+	p.taskDone(t)
+	return true
+}
+
+// claim records that a task is being started or abandoned, so that it can no
+// longer be canceled.  Returns false if the task was discarded.
+func (t *task) claim() bool {
+	return atomic.CompareAndSwapInt32(&t.state, taskWaiting, taskStarted)
+}
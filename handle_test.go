@@ -0,0 +1,44 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHandleCancel(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	running := wp.SubmitTask(Task{Fn: func(ctx context.Context) {
+		close(started)
+		<-release
+	}})
+	<-started
+	var ran bool
+	queued := wp.SubmitTask(Task{Fn: func(ctx context.Context) { ran = true }})
+
+	if running.Cancel() {
+		t.Fatal("canceled a running task")
+	}
+	if !queued.Cancel() {
+		t.Fatal("could not cancel a queued task")
+	}
+	if queued.Cancel() {
+		t.Fatal("canceled a task twice")
+	}
+
+	// Wait does not wait for the canceled task.
+	close(release)
+	wp.Wait()
+	wp.StopWait()
+	if ran {
+		t.Fatal("canceled task ran")
+	}
+	if (*Handle)(nil).Cancel() {
+		t.Fatal("canceled nil handle")
+	}
+}
@@ -0,0 +1,115 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Errors reported by Healthy.  ErrPoolStopped wraps ErrStopped.
+var (
+	ErrPoolStopped       = &wrapError{"workerpool: pool is stopped", ErrStopped}
+	ErrDispatcherStalled = errors.New("workerpool: dispatcher stalled")
+	ErrBacklog           = errors.New("workerpool: waiting queue above threshold")
+	ErrNoProgress        = errors.New("workerpool: no task completed")
+)
+
+// HealthConfig sets the thresholds used by Healthy.  A zero value disables
+// the corresponding check.
+type HealthConfig struct {
+	// MaxWaiting is the number of waiting tasks above which the pool is
+	// unhealthy.
+	MaxWaiting int
+	// MaxNoProgress is how long tasks may be pending without any task
+	// completing before the pool is unhealthy.
+	MaxNoProgress time.Duration
+	// StallTimeout is how long submitted tasks may go unreceived by the
+	// dispatcher before it is considered stalled.
+	StallTimeout time.Duration
+}
+
+// WithHealthConfig sets the thresholds used by Healthy.
+func WithHealthConfig(config HealthConfig) Option {
+	return func(p *WorkerPool) {
+		p.health = &health{config: config}
+	}
+}
+
+// health holds the state that Healthy compares between calls.
+type health struct {
+	config HealthConfig
+
+	mutex      sync.Mutex
+	heartbeat  uint64
+	stallSince time.Time
+	completed  uint64
+	idleSince  time.Time
+}
+
+// Healthy returns nil if the worker pool is healthy, or an error describing
+// each problem found.  The pool is unhealthy if it is stopped, or if it
+// exceeds the thresholds set using WithHealthConfig.  The dispatcher stall and
+// progress checks compare the pool's state with the previous call, so Healthy
+// is meant to be called periodically, such as by a liveness probe.  Each error
+// wraps one of ErrPoolStopped, ErrDispatcherStalled, ErrBacklog,
+// ErrNoProgress, or ErrPanicking.
+func (p *WorkerPool) Healthy() error {
+	if p.Stopped() {
+		return ErrPoolStopped
+	}
+	var errs []error
+	if n := atomic.LoadInt32(&p.backingOff); n != 0 {
+		errs = append(errs, fmt.Errorf("%w: %d workers", ErrPanicking, n))
+	}
+	h := p.health
+	if h == nil {
+		return errors.Join(errs...)
+	}
+	now := time.Now()
+
+	if h.config.MaxWaiting > 0 {
+		if n := p.WaitingQueueSize(); n > h.config.MaxWaiting {
+			errs = append(errs, fmt.Errorf("%w: %d tasks waiting", ErrBacklog, n))
+		}
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.config.StallTimeout > 0 {
+		heartbeat := atomic.LoadUint64(&p.heartbeat)
+		if atomic.LoadInt32(&p.submitSignaled) == 0 || heartbeat != h.heartbeat {
+			h.stallSince = time.Time{}
+		} else if h.stallSince.IsZero() {
+			h.stallSince = now
+		} else if d := now.Sub(h.stallSince); d > h.config.StallTimeout {
+			errs = append(errs, fmt.Errorf("%w: submitted tasks not received for %s", ErrDispatcherStalled, d))
+		}
+		h.heartbeat = heartbeat
+	}
+
+	if h.config.MaxNoProgress > 0 {
+		p.pendingMutex.Lock()
+		pending, completed := p.pending, p.completed
+		p.pendingMutex.Unlock()
+		if pending == 0 || completed != h.completed {
+			h.idleSince = time.Time{}
+		} else if h.idleSince.IsZero() {
+			h.idleSince = now
+		} else if d := now.Sub(h.idleSince); d > h.config.MaxNoProgress {
+			errs = append(errs, fmt.Errorf("%w in %s with %d tasks pending", ErrNoProgress, d, pending))
+		}
+		h.completed = completed
+	}
+
+	return errors.Join(errs...)
+}
+
+// HealthCheck is the same as Healthy, and has the signature used by health
+// check libraries.
+func (p *WorkerPool) HealthCheck(ctx context.Context) error {
+	return p.Healthy()
+}
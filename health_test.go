@@ -0,0 +1,47 @@
+package workerpool
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthy(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithHealthConfig(HealthConfig{
+		MaxWaiting:    2,
+		MaxNoProgress: 20 * time.Millisecond,
+	}))
+	if err := wp.Healthy(); err != nil {
+		t.Fatal("expected healthy pool, got:", err)
+	}
+
+	release := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wp.Submit(func() { <-release })
+	}
+	for wp.WaitingQueueSize() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	err := wp.Healthy()
+	if !errors.Is(err, ErrBacklog) {
+		t.Fatal("expected ErrBacklog, got:", err)
+	}
+	time.Sleep(30 * time.Millisecond)
+	err = wp.Healthy()
+	if !errors.Is(err, ErrNoProgress) {
+		t.Fatal("expected ErrNoProgress, got:", err)
+	}
+
+	close(release)
+	wp.Flush()
+	if err = wp.Healthy(); err != nil {
+		t.Fatal("expected healthy pool, got:", err)
+	}
+
+	wp.Stop()
+	if err = wp.Healthy(); err != ErrPoolStopped {
+		t.Fatal("expected ErrPoolStopped, got:", err)
+	}
+}
@@ -0,0 +1,57 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SubmitHedged enqueues a function for a worker to execute, and if the
+// function has not returned within hedgeAfter of starting, enqueues a second
+// execution of the same function.  The result of whichever execution returns
+// first is given by the returned Future, and the context given to the
+// executions is then canceled so that the other execution can stop early.
+//
+// Hedging reduces tail latency when a task is occasionally slow for reasons
+// that a second attempt is unlikely to share, such as a slow replica.  The
+// function must be safe to execute more than once, and concurrently.
+func (p *WorkerPool) SubmitHedged(hedgeAfter time.Duration, task func(context.Context) (interface{}, error)) *Future {
+	f := newFuture(p)
+	if task == nil {
+		close(f.done)
+		return f
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var (
+		once    sync.Once
+		started int32
+		hedge   atomic.Pointer[time.Timer]
+	)
+	var run func()
+	run = func() {
+		if ctx.Err() != nil {
+			// The other execution already returned.
+			return
+		}
+		if atomic.CompareAndSwapInt32(&started, 0, 1) {
+			hedge.Store(time.AfterFunc(hedgeAfter, func() {
+				if ctx.Err() == nil {
+					p.submit(run)
+				}
+			}))
+		}
+		value, err := task(ctx)
+		once.Do(func() {
+			if t := hedge.Load(); t != nil {
+				t.Stop()
+			}
+			f.value, f.err = value, err
+			cancel()
+			close(f.done)
+		})
+	}
+	p.submit(run)
+	return f
+}
@@ -0,0 +1,51 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmitHedged(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.StopWait()
+
+	// The first execution is slow, so the hedged execution's result is used,
+	// and the first execution is canceled.
+	var calls int32
+	canceled := make(chan struct{})
+	f := wp.SubmitHedged(10*time.Millisecond, func(ctx context.Context) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			close(canceled)
+			return "slow", ctx.Err()
+		}
+		return "fast", nil
+	})
+	value, err := f.Wait()
+	if err != nil || value != "fast" {
+		t.Fatal("expected hedged result, got", value, err)
+	}
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow execution was not canceled")
+	}
+
+	// A fast task is not hedged.
+	atomic.StoreInt32(&calls, 0)
+	f = wp.SubmitHedged(50*time.Millisecond, func(ctx context.Context) (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		return 1, nil
+	})
+	if value, _ = f.Wait(); value != 1 {
+		t.Fatal("wrong result:", value)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatal("expected 1 execution, got", n)
+	}
+}
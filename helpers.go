@@ -0,0 +1,154 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Map calls fn for each of the inputs, using the worker pool to limit the
+// number of concurrent calls, and waits for all calls to complete.  The
+// results are returned in the same order as the inputs.
+//
+// If any calls return an error, then the returned error joins all errors in
+// the order of the inputs that caused them.  The result for an input that
+// caused an error is whatever value fn returned with the error.
+func Map[T, R any](p *WorkerPool, inputs []T, fn func(T) (R, error)) ([]R, error) {
+	results := make([]R, len(inputs))
+	errs := make([]error, len(inputs))
+	inline := p.nested("Map")
+	var wg sync.WaitGroup
+	wg.Add(len(inputs))
+	for i := range inputs {
+		i := i
+		p.submitNested(inline, func() {
+			defer wg.Done()
+			results[i], errs[i] = fn(inputs[i])
+		})
+	}
+	wg.Wait()
+	return results, errors.Join(errs...)
+}
+
+// ForEach calls fn for each of the items, using the worker pool to limit the
+// number of concurrent calls, and waits for all calls to complete.  The
+// returned error joins all errors returned by fn, in the order of the items
+// that caused them, or is nil if there were no errors.
+func ForEach[T any](p *WorkerPool, items []T, fn func(T) error) error {
+	errs := make([]error, len(items))
+	inline := p.nested("ForEach")
+	var wg sync.WaitGroup
+	wg.Add(len(items))
+	for i := range items {
+		i := i
+		p.submitNested(inline, func() {
+			defer wg.Done()
+			errs[i] = fn(items[i])
+		})
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// Reduce aggregates items in parallel using the worker pool.  Up to the pool's
+// maximum number of workers each get their own accumulator, created by
+// newAcc, and fold items into it using fn.  Since each accumulator is only
+// used by one worker, no locking is needed in fn.  When all items have been
+// processed, the accumulators are combined using merge and the result is
+// returned.
+//
+// Items are not assigned to accumulators in any particular order, so fn and
+// merge must not depend on the order of items.
+func Reduce[T, A any](p *WorkerPool, items []T, newAcc func() A, fn func(A, T) A, merge func(A, A) A) A {
+	n := p.maxWorkers
+	if len(items) < n {
+		n = len(items)
+	}
+	if n == 0 {
+		return newAcc()
+	}
+
+	accs := make([]A, n)
+	inline := p.nested("Reduce")
+	var next int64 = -1
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		i := i
+		p.submitNested(inline, func() {
+			defer wg.Done()
+			acc := newAcc()
+			for {
+				j := atomic.AddInt64(&next, 1)
+				if j >= int64(len(items)) {
+					break
+				}
+				acc = fn(acc, items[j])
+			}
+			accs[i] = acc
+		})
+	}
+	wg.Wait()
+
+	result := accs[0]
+	for _, acc := range accs[1:] {
+		result = merge(result, acc)
+	}
+	return result
+}
+
+// FanOut submits each of the tasks to the worker pool, and collects their
+// results as they complete.  It returns when all tasks have completed, or
+// when ctx is canceled, whichever happens first.  Results are returned in the
+// same order as the tasks.
+//
+// The context passed to each task is ctx.  Tasks that have not started when
+// ctx is canceled are skipped.  If ctx is canceled before all tasks complete,
+// then the results of the completed tasks are returned along with the
+// context's error.  Otherwise the returned error joins all errors returned by
+// the tasks, in task order.
+func FanOut[R any](ctx context.Context, p *WorkerPool, tasks ...func(context.Context) (R, error)) ([]R, error) {
+	type indexedResult struct {
+		index int
+		value R
+		err   error
+	}
+	resultChan := make(chan indexedResult, len(tasks))
+	inline := p.nested("FanOut")
+	for i := range tasks {
+		i := i
+		run := func() error {
+			if err := ctx.Err(); err != nil {
+				resultChan <- indexedResult{index: i, err: err}
+				return nil
+			}
+			r, err := tasks[i](ctx)
+			resultChan <- indexedResult{i, r, err}
+			return nil
+		}
+		if inline {
+			run()
+			continue
+		}
+		// A task whose panic is recovered sends its *PanicError as its
+		// result, so that FanOut does not wait for it.
+		p.enqueue(p.newErrTask(run, func(err error) {
+			if err != nil {
+				resultChan <- indexedResult{index: i, err: err}
+			}
+		}))
+	}
+
+	results := make([]R, len(tasks))
+	errs := make([]error, len(tasks))
+	for range tasks {
+		select {
+		case r := <-resultChan:
+			results[r.index], errs[r.index] = r.value, r.err
+		case <-ctx.Done():
+			return results, ctx.Err()
+		}
+	}
+	return results, errors.Join(errs...)
+}
@@ -0,0 +1,165 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMap(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.Stop()
+
+	inputs := make([]int, 50)
+	for i := range inputs {
+		inputs[i] = i
+	}
+	results, err := Map(wp, inputs, func(n int) (string, error) {
+		// Finish out of order.
+		time.Sleep(time.Duration(len(inputs)-n) * 100 * time.Microsecond)
+		return fmt.Sprint(n * 2), nil
+	})
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	for i, r := range results {
+		if r != fmt.Sprint(i*2) {
+			t.Fatalf("result %d out of order: %s", i, r)
+		}
+	}
+
+	err1 := errors.New("one")
+	err3 := errors.New("three")
+	_, err = Map(wp, []int{0, 1, 2, 3}, func(n int) (int, error) {
+		switch n {
+		case 1:
+			return 0, err1
+		case 3:
+			return 0, err3
+		}
+		return n, nil
+	})
+	if !errors.Is(err, err1) || !errors.Is(err, err3) {
+		t.Fatal("expected both errors to be joined, got", err)
+	}
+
+	results2, err := Map(wp, nil, func(n int) (int, error) { return n, nil })
+	if err != nil || len(results2) != 0 {
+		t.Fatal("expected empty result for empty input")
+	}
+}
+
+func TestForEach(t *testing.T) {
+	t.Parallel()
+
+	wp := New(3)
+	defer wp.Stop()
+
+	items := []int{1, 2, 3, 4, 5, 6}
+	var sum int64
+	err := ForEach(wp, items, func(n int) error {
+		atomic.AddInt64(&sum, int64(n))
+		if n%2 == 0 {
+			return fmt.Errorf("even %d", n)
+		}
+		return nil
+	})
+	if sum != 21 {
+		t.Fatal("not all items processed, sum:", sum)
+	}
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if err.Error() != "even 2\neven 4\neven 6" {
+		t.Fatal("wrong joined error:", err)
+	}
+
+	if err = ForEach(wp, items, func(int) error { return nil }); err != nil {
+		t.Fatal("expected nil error, got", err)
+	}
+}
+
+func TestReduce(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.Stop()
+
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i + 1
+	}
+	var accs int32
+	newAcc := func() map[int]int {
+		atomic.AddInt32(&accs, 1)
+		return map[int]int{}
+	}
+	counts := Reduce(wp, items, newAcc,
+		func(acc map[int]int, n int) map[int]int {
+			acc[n%3]++
+			return acc
+		},
+		func(a, b map[int]int) map[int]int {
+			for k, v := range b {
+				a[k] += v
+			}
+			return a
+		})
+	if counts[0] != 333 || counts[1] != 334 || counts[2] != 333 {
+		t.Fatal("wrong counts:", counts)
+	}
+	if accs > 4 {
+		t.Fatal("expected at most one accumulator per worker, got", accs)
+	}
+
+	sum := Reduce(wp, nil, func() int { return 0 },
+		func(acc, n int) int { return acc + n },
+		func(a, b int) int { return a + b })
+	if sum != 0 {
+		t.Fatal("expected zero value for empty input")
+	}
+}
+
+func TestFanOut(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	ctx := context.Background()
+	testErr := errors.New("failed")
+	results, err := FanOut(ctx, wp,
+		func(context.Context) (int, error) { return 1, nil },
+		func(context.Context) (int, error) { return 2, testErr },
+		func(context.Context) (int, error) { return 3, nil },
+	)
+	if !errors.Is(err, testErr) {
+		t.Fatal("expected task error, got", err)
+	}
+	if results[0] != 1 || results[1] != 2 || results[2] != 3 {
+		t.Fatal("wrong results:", results)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err = FanOut(ctx, wp,
+		func(context.Context) (int, error) { return 1, nil },
+		func(context.Context) (int, error) {
+			<-release
+			return 2, nil
+		},
+	)
+	if err != context.Canceled {
+		t.Fatal("expected context canceled, got", err)
+	}
+}
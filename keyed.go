@@ -0,0 +1,158 @@
+package workerpool
+
+import "time"
+
+// minCacheSweep is the number of cached results below which expired entries
+// are only removed when their key is submitted again.
+const minCacheSweep = 64
+
+// Future holds the result of a keyed task submitted to the worker pool.  All
+// submitters whose tasks were coalesced into the same execution share the
+// same Future.
+type Future struct {
+	pool  *WorkerPool
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+func newFuture(p *WorkerPool) *Future {
+	return &Future{pool: p, done: make(chan struct{})}
+}
+
+// Done returns a channel that is closed when the task has completed.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the task has completed and returns its result.
+func (f *Future) Wait() (interface{}, error) {
+	f.pool.checkReentrant("Future.Wait")
+	<-f.done
+	return f.value, f.err
+}
+
+// cachedResult is a completed keyed execution that is reused until expires.
+type cachedResult struct {
+	future  *Future
+	expires time.Time
+}
+
+// WithResultCache enables caching of keyed task results.  When a task
+// submitted with SubmitKeyed or SubmitKeyedFunc completes without error, its
+// Future is returned for any submission of the same key within the ttl period,
+// without executing the task again.  A key is executed at most once per ttl
+// period.  Failed executions are not cached.
+func WithResultCache(ttl time.Duration) Option {
+	return func(p *WorkerPool) {
+		if ttl > 0 {
+			p.cacheTTL = ttl
+			p.cache = map[string]cachedResult{}
+		}
+	}
+}
+
+// SubmitKeyed enqueues a function for a worker to execute, unless a task with
+// the same key is already queued or running.  In that case the task is not
+// submitted, and the Future of the existing execution is returned instead.
+//
+// This prevents duplicate execution of expensive work, such as refilling the
+// same cache entry, when it is requested many times before completing.  If
+// the task is dropped because the worker pool is stopped, the Future completes
+// with ErrStopped, and the key may be submitted again.
+func (p *WorkerPool) SubmitKeyed(key string, task func()) *Future {
+	if task == nil {
+		return p.SubmitKeyedFunc(key, nil)
+	}
+	return p.SubmitKeyedFunc(key, func() (interface{}, error) {
+		task()
+		return nil, nil
+	})
+}
+
+// SubmitKeyedFunc is the same as SubmitKeyed, except that the task returns a
+// value and an error that are available to all waiters from the returned
+// Future.
+//
+// If the worker pool was created with WithResultCache, and the key was
+// successfully executed within the cache ttl, the cached Future is returned.
+func (p *WorkerPool) SubmitKeyedFunc(key string, task func() (interface{}, error)) *Future {
+	if task == nil {
+		f := newFuture(p)
+		close(f.done)
+		return f
+	}
+
+	p.keyedMutex.Lock()
+	if f, ok := p.keyed[key]; ok {
+		p.keyedMutex.Unlock()
+		return f
+	}
+	if p.cache != nil {
+		if cached, ok := p.cache[key]; ok {
+			if time.Now().Before(cached.expires) {
+				p.keyedMutex.Unlock()
+				return cached.future
+			}
+			delete(p.cache, key)
+		}
+	}
+	f := newFuture(p)
+	p.keyed[key] = f
+	p.keyedMutex.Unlock()
+
+	// The Future is completed by the task's done function, which is also
+	// called with the panic of a task whose panic is recovered.  The task's
+	// own error is not reported as a failure of the worker pool.
+	t := p.newErrTask(func() error {
+		f.value, f.err = task()
+		return nil
+	}, func(err error) {
+		if err != nil {
+			f.value, f.err = nil, err
+		}
+		p.completeKeyed(key, f)
+	})
+	t.dropped = func() {
+		f.err = ErrStopped
+		p.completeKeyed(key, f)
+	}
+	p.enqueue(t)
+	return f
+}
+
+// completeKeyed removes the key of a completed keyed task, caches a
+// successful result, and releases the Future's waiters.  The key is removed
+// before signaling completion, so that any task submitted after waiters are
+// released starts a new execution.
+func (p *WorkerPool) completeKeyed(key string, f *Future) {
+	p.keyedMutex.Lock()
+	delete(p.keyed, key)
+	if p.cache != nil && f.err == nil {
+		p.cacheResult(key, f)
+	}
+	p.keyedMutex.Unlock()
+	close(f.done)
+}
+
+// cacheResult stores a successful result in the result cache.  Expired entries
+// are swept each time the cache doubles in size since the last sweep, so that
+// keys that are never submitted again do not accumulate.
+//
+// Must be called with keyedMutex held.
+func (p *WorkerPool) cacheResult(key string, f *Future) {
+	now := time.Now()
+	p.cache[key] = cachedResult{
+		future:  f,
+		expires: now.Add(p.cacheTTL),
+	}
+	if len(p.cache) < minCacheSweep || len(p.cache) < 2*p.cacheSweep {
+		return
+	}
+	for k, cached := range p.cache {
+		if !now.Before(cached.expires) {
+			delete(p.cache, k)
+		}
+	}
+	p.cacheSweep = len(p.cache)
+}
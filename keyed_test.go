@@ -0,0 +1,144 @@
+package workerpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmitKeyed(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	var runs int32
+	task := func() (interface{}, error) {
+		<-release
+		atomic.AddInt32(&runs, 1)
+		return "value", nil
+	}
+
+	futures := make([]*Future, 10)
+	for i := range futures {
+		futures[i] = wp.SubmitKeyedFunc("key", task)
+	}
+	for i := range futures {
+		if futures[i] != futures[0] {
+			t.Fatal("expected all submissions to share one future")
+		}
+	}
+	close(release)
+
+	for _, f := range futures {
+		v, err := f.Wait()
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if v != "value" {
+			t.Fatal("wrong value:", v)
+		}
+	}
+	if runs != 1 {
+		t.Fatal("expected task to run once, ran", runs)
+	}
+
+	// After completion, the same key executes again.
+	testErr := errors.New("failed")
+	f := wp.SubmitKeyedFunc("key", func() (interface{}, error) {
+		return nil, testErr
+	})
+	if f == futures[0] {
+		t.Fatal("completed future should not be reused")
+	}
+	if _, err := f.Wait(); err != testErr {
+		t.Fatal("expected error from task, got", err)
+	}
+
+	// Different keys do not coalesce.
+	done := make(chan struct{})
+	f1 := wp.SubmitKeyed("a", func() { <-done })
+	f2 := wp.SubmitKeyed("b", func() { <-done })
+	if f1 == f2 {
+		t.Fatal("different keys should not share a future")
+	}
+	close(done)
+	<-f1.Done()
+	<-f2.Done()
+
+	// Nil task returns a completed future.
+	<-wp.SubmitKeyed("nil", nil).Done()
+}
+
+func TestResultCache(t *testing.T) {
+	t.Parallel()
+
+	ttl := 200 * time.Millisecond
+	wp := New(2, WithResultCache(ttl))
+	defer wp.Stop()
+
+	var runs int32
+	task := func() (interface{}, error) {
+		return atomic.AddInt32(&runs, 1), nil
+	}
+
+	v, _ := wp.SubmitKeyedFunc("key", task).Wait()
+	if v.(int32) != 1 {
+		t.Fatal("expected first execution, got", v)
+	}
+	v, _ = wp.SubmitKeyedFunc("key", task).Wait()
+	if v.(int32) != 1 {
+		t.Fatal("expected cached result, got", v)
+	}
+
+	time.Sleep(ttl + 50*time.Millisecond)
+	v, _ = wp.SubmitKeyedFunc("key", task).Wait()
+	if v.(int32) != 2 {
+		t.Fatal("expected new execution after ttl, got", v)
+	}
+
+	// Errors are not cached.
+	testErr := errors.New("failed")
+	var failRuns int32
+	fail := func() (interface{}, error) {
+		atomic.AddInt32(&failRuns, 1)
+		return nil, testErr
+	}
+	wp.SubmitKeyedFunc("fail", fail).Wait()
+	wp.SubmitKeyedFunc("fail", fail).Wait()
+	if failRuns != 2 {
+		t.Fatal("failed result should not be cached")
+	}
+}
+
+func TestSubmitKeyedDropped(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	release := make(chan struct{})
+	wp.Submit(func() {
+		<-release
+		wp.Stop()
+	})
+	f := wp.SubmitKeyed("key", func() {})
+	for wp.WaitingQueueSize() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	<-wp.Done()
+	if _, err := f.Wait(); !errors.Is(err, ErrStopped) {
+		t.Fatal("expected dropped keyed task to fail with ErrStopped, got", err)
+	}
+
+	wp.Reboot()
+	defer wp.Stop()
+	f2 := wp.SubmitKeyed("key", func() {})
+	if f2 == f {
+		t.Fatal("expected a new future after the dropped task")
+	}
+	if _, err := f2.Wait(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
@@ -0,0 +1,59 @@
+package workerpool
+
+// Limiter caps the number of tasks that execute concurrently across all of the
+// worker pools that share it.  Each worker pool keeps its own queue and
+// maximum number of workers, and a worker waits for the limiter before
+// executing each task.  This allows work to be partitioned into separate pools
+// by task type, while limiting the total use of a shared resource.
+//
+// A Limiter is safe to share between any number of worker pools.
+type Limiter struct {
+	sem chan struct{}
+}
+
+// NewLimiter creates a Limiter that allows at most n tasks to execute at
+// once.  There must be at least one.
+func NewLimiter(n int) *Limiter {
+	if n < 1 {
+		n = 1
+	}
+	return &Limiter{
+		sem: make(chan struct{}, n),
+	}
+}
+
+// WithLimiter makes the worker pool's tasks count against the given
+// limiter.  While the limiter is at its limit, the pool's workers wait for
+// tasks in other pools sharing the limiter to complete.
+func WithLimiter(l *Limiter) Option {
+	return func(p *WorkerPool) {
+		p.limiter = l
+	}
+}
+
+// Limit returns the maximum number of tasks that execute at once.
+func (l *Limiter) Limit() int {
+	return cap(l.sem)
+}
+
+// Running returns the number of tasks currently executing in all of the
+// worker pools sharing the limiter.
+func (l *Limiter) Running() int {
+	return len(l.sem)
+}
+
+// acquire waits until a task may be executed.  Does nothing if the limiter is
+// nil.
+func (l *Limiter) acquire() {
+	if l != nil {
+		l.sem <- struct{}{}
+	}
+}
+
+// release records that a task has finished executing.  Does nothing if the
+// limiter is nil.
+func (l *Limiter) release() {
+	if l != nil {
+		<-l.sem
+	}
+}
@@ -0,0 +1,46 @@
+package workerpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	t.Parallel()
+
+	limiter := NewLimiter(3)
+	if limiter.Limit() != 3 {
+		t.Fatal("wrong limit:", limiter.Limit())
+	}
+	pools := []*WorkerPool{
+		New(4, WithLimiter(limiter)),
+		New(4, WithLimiter(limiter)),
+	}
+
+	var running, maxRunning int32
+	var mutex sync.Mutex
+	for i := 0; i < 20; i++ {
+		pools[i%2].Submit(func() {
+			n := atomic.AddInt32(&running, 1)
+			mutex.Lock()
+			if n > maxRunning {
+				maxRunning = n
+			}
+			mutex.Unlock()
+			time.Sleep(2 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+	}
+	for _, wp := range pools {
+		wp.StopWait()
+	}
+
+	if maxRunning != 3 {
+		t.Fatal("expected at most 3 tasks running, had", maxRunning)
+	}
+	if limiter.Running() != 0 {
+		t.Fatal("limiter not released:", limiter.Running())
+	}
+}
@@ -0,0 +1,33 @@
+package workerpool
+
+// Metadata identifies a task in the reports of a worker pool, such as the
+// StuckTask given to a watchdog and the tasks listed by Dump.  Without
+// metadata, a task is identified by the name of its function, which for a
+// closure only identifies the function that created it.
+type Metadata struct {
+	// Name is the name of the task.
+	Name string
+	// Labels are key/value pairs that describe the task, such as the ID of
+	// the request that the task is part of.
+	Labels map[string]string
+}
+
+// SubmitWithMetadata enqueues a function for a worker to execute, the same as
+// Submit, and attaches the metadata to the task.  The labels map must not be
+// modified after the task is submitted.
+func (p *WorkerPool) SubmitWithMetadata(md Metadata, task func()) {
+	if task == nil {
+		return
+	}
+	t := p.newTask(task)
+	t.meta = &md
+	p.enqueueAdmitted(t)
+}
+
+// labels returns the labels of a task, or nil if it has no metadata.
+func (t *task) labels() map[string]string {
+	if t.meta == nil {
+		return nil
+	}
+	return t.meta.Labels
+}
@@ -0,0 +1,40 @@
+package workerpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubmitWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	reports := make(chan StuckTask, 1)
+	wp := New(1, WithDebugDump(), WithWatchdog(20*time.Millisecond, func(st StuckTask) {
+		reports <- st
+	}))
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	md := Metadata{Name: "blocker", Labels: map[string]string{"request": "r1"}}
+	wp.SubmitWithMetadata(md, func() { <-release })
+	wp.SubmitWithMetadata(Metadata{Name: "waiter"}, func() {})
+
+	var st StuckTask
+	select {
+	case st = <-reports:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stuck task not reported")
+	}
+	if st.Name != "blocker" || st.Labels["request"] != "r1" {
+		t.Fatal("wrong metadata in report:", st.Name, st.Labels)
+	}
+
+	d := wp.Dump()
+	close(release)
+	if len(d.Workers) != 1 || d.Workers[0].Task != "blocker" || d.Workers[0].Labels["request"] != "r1" {
+		t.Fatal("wrong running task in dump:", d.Workers)
+	}
+	if len(d.Waiting) != 1 || d.Waiting[0].Task != "waiter" {
+		t.Fatal("wrong waiting task in dump:", d.Waiting)
+	}
+}
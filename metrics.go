@@ -0,0 +1,69 @@
+package workerpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Names of the metrics reported to a MetricsSink.
+const (
+	// MetricTasksSubmitted counts submitted tasks.
+	MetricTasksSubmitted = "workerpool.tasks.submitted"
+	// MetricTasksCompleted counts tasks that returned.
+	MetricTasksCompleted = "workerpool.tasks.completed"
+	// MetricTasksRejected counts tasks that were not accepted.
+	MetricTasksRejected = "workerpool.tasks.rejected"
+	// MetricTasksAbandoned counts tasks whose workers were abandoned after
+	// the tasks did not return after their timeout.
+	MetricTasksAbandoned = "workerpool.tasks.abandoned"
+	// MetricTaskDuration times how long each task runs.
+	MetricTaskDuration = "workerpool.task.duration"
+	// MetricWorkers is the number of running workers.
+	MetricWorkers = "workerpool.workers"
+	// MetricBusyWorkers is the number of workers running tasks.
+	MetricBusyWorkers = "workerpool.workers.busy"
+	// MetricWaitingTasks is the number of tasks waiting for a worker.
+	MetricWaitingTasks = "workerpool.tasks.waiting"
+)
+
+// MetricsSink receives a worker pool's metrics, so that they can be reported
+// to any metrics backend.  The methods are called from the worker pool's
+// workers and dispatcher, so they must be safe for concurrent use and should
+// return quickly.  The metric names are the Metric constants.
+type MetricsSink interface {
+	// Count adds delta to a counter.
+	Count(name string, delta int64)
+	// Gauge sets the value of a gauge.
+	Gauge(name string, value float64)
+	// Timing records a duration in a timer.
+	Timing(name string, d time.Duration)
+}
+
+// WithMetrics makes the worker pool report its metrics to the sink.  Without
+// this option, metrics are not recorded.
+func WithMetrics(sink MetricsSink) Option {
+	return func(p *WorkerPool) {
+		p.metrics = sink
+		if sink != nil {
+			p.recordEnqueue = true
+		}
+	}
+}
+
+// count adds to a counter, if metrics are recorded.
+func (p *WorkerPool) count(name string, delta int64) {
+	if p.metrics != nil {
+		p.metrics.Count(name, delta)
+	}
+}
+
+// gaugeWorkers reports the number of workers and busy workers.
+func (p *WorkerPool) gaugeWorkers() {
+	if p.metrics != nil {
+		p.metrics.Gauge(MetricWorkers, float64(atomic.LoadInt32(&p.workerCount)))
+		p.metrics.Gauge(MetricBusyWorkers, float64(atomic.LoadInt32(&p.busyWorkers)))
+		if p.utilization != nil {
+			p.metrics.Gauge(MetricUtilization, p.Utilization())
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package workerpool
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type testSink struct {
+	mutex    sync.Mutex
+	counters map[string]int64
+	gauges   map[string]float64
+	timings  map[string]int
+}
+
+func (s *testSink) Count(name string, delta int64) {
+	s.mutex.Lock()
+	s.counters[name] += delta
+	s.mutex.Unlock()
+}
+
+func (s *testSink) Gauge(name string, value float64) {
+	s.mutex.Lock()
+	s.gauges[name] = value
+	s.mutex.Unlock()
+}
+
+func (s *testSink) Timing(name string, d time.Duration) {
+	s.mutex.Lock()
+	s.timings[name]++
+	s.mutex.Unlock()
+}
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	sink := &testSink{
+		counters: map[string]int64{},
+		gauges:   map[string]float64{},
+		timings:  map[string]int{},
+	}
+	wp := New(2, WithMetrics(sink))
+	wp.SubmitAll(func() {}, func() {}, func() {})
+	for i := 0; i < 7; i++ {
+		wp.Submit(func() { time.Sleep(time.Millisecond) })
+	}
+	wp.StopWait()
+
+	if n := sink.counters[MetricTasksSubmitted]; n != 10 {
+		t.Fatal("wrong number of submitted tasks:", n)
+	}
+	if n := sink.counters[MetricTasksCompleted]; n != 10 {
+		t.Fatal("wrong number of completed tasks:", n)
+	}
+	if n := sink.timings[MetricTaskDuration]; n != 10 {
+		t.Fatal("wrong number of task timings:", n)
+	}
+	if _, ok := sink.gauges[MetricBusyWorkers]; !ok {
+		t.Fatal("busy workers not reported")
+	}
+	if _, ok := sink.gauges[MetricWaitingTasks]; !ok {
+		t.Fatal("waiting tasks not reported")
+	}
+}
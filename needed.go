@@ -0,0 +1,31 @@
+package workerpool
+
+import "sync/atomic"
+
+// SubmitIfNeeded enqueues a function for a worker to execute, if it is still
+// needed when a worker is about to start it.  The needed function is called
+// just before the task would start, and the task is skipped if it returns
+// false.  This avoids running queued work that became stale while it waited,
+// such as refreshing a cache entry that has since been evicted.
+//
+// Skipped tasks are complete for the purposes of Wait and Flush, are not
+// rejected, and are counted in the SkippedTasks field of the worker pool's
+// Stats.
+func (p *WorkerPool) SubmitIfNeeded(needed func() bool, task func()) {
+	if task == nil {
+		return
+	}
+	t := p.newTask(task)
+	t.needed = needed
+	p.enqueueAdmitted(t)
+}
+
+// skip records that a task that is no longer needed will not run.
+func (p *WorkerPool) skip(t *task) {
+	if !atomic.CompareAndSwapInt32(&t.state, taskWaiting, taskCanceled) {
+		return
+	}
+	p.taskIndex.finish(t, StateSkipped)
+	atomic.AddInt64(&p.skippedTasks, 1)
+	p.taskDone(t)
+}
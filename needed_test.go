@@ -0,0 +1,39 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSubmitIfNeeded(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithTaskIndex(10))
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+
+	var stale int32
+	var ran int32
+	needed := func() bool { return atomic.LoadInt32(&stale) == 0 }
+	wp.SubmitIfNeeded(needed, func() { atomic.AddInt32(&ran, 1) })
+	h := wp.SubmitTask(Task{Fn: func(ctx context.Context) { atomic.AddInt32(&ran, 1) }, Needed: needed})
+	wp.SubmitIfNeeded(func() bool { return true }, func() { atomic.AddInt32(&ran, 1) })
+
+	// The tasks become stale while they wait.
+	atomic.StoreInt32(&stale, 1)
+	close(release)
+	wp.Wait()
+
+	if n := atomic.LoadInt32(&ran); n != 1 {
+		t.Fatal("expected 1 task to run, ran", n)
+	}
+	if n := wp.Stats().SkippedTasks; n != 2 {
+		t.Fatal("expected 2 skipped tasks, got", n)
+	}
+	if status, _ := wp.Status(h.ID()); status.State != StateSkipped {
+		t.Fatal("expected skipped task, got", status.State)
+	}
+}
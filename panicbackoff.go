@@ -0,0 +1,59 @@
+package workerpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPanicking is reported by Healthy while workers are backing off after
+// their tasks panicked, set using WithPanicBackoff.
+var ErrPanicking = errors.New("workerpool: workers backing off after panics")
+
+type panicBackoff struct {
+	min time.Duration
+	max time.Duration
+}
+
+// WithPanicBackoff recovers tasks that panic, and makes a worker whose tasks
+// panic repeatedly wait before starting another task.  The worker waits for
+// min after its task panics, and the wait doubles for each consecutive panic,
+// up to max.  A task that returns normally resets the wait.  This keeps a
+// poisoned source of tasks from making workers panic and recover at full
+// speed.
+//
+// A worker that is backing off is busy, and is not given tasks.  While any
+// worker is backing off, Healthy reports ErrPanicking, and a WorkerBackoff
+// event is sent each time a worker starts to back off.  Panics are also
+// reported on the channel returned by Errors, if set using WithErrors.
+func WithPanicBackoff(min, max time.Duration) Option {
+	return func(p *WorkerPool) {
+		if min <= 0 {
+			return
+		}
+		if max < min {
+			max = min
+		}
+		p.panicBackoff = &panicBackoff{min, max}
+	}
+}
+
+// backOffPanics waits, if the worker's task panicked, for the backoff of the
+// worker's consecutive panics.  Otherwise, the worker's backoff is reset.
+func (p *WorkerPool) backOffPanics(ws *workerState, t *task) {
+	if !t.panicked {
+		ws.panics = 0
+		return
+	}
+	wait := p.panicBackoff.max
+	if ws.panics < 32 {
+		if d := p.panicBackoff.min << ws.panics; d > 0 && d < wait {
+			wait = d
+		}
+	}
+	ws.panics++
+	atomic.AddInt32(&p.backingOff, 1)
+	p.emit(WorkerBackoff, nil)
+	p.delay(wait)
+	atomic.AddInt32(&p.backingOff, -1)
+}
@@ -0,0 +1,53 @@
+package workerpool
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPanicBackoff(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithPanicBackoff(20*time.Millisecond, 40*time.Millisecond), WithEvents(100))
+	defer wp.Stop()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		wp.Submit(func() { panic("poisoned") })
+	}
+	var ran int32
+	wp.Submit(func() { atomic.StoreInt32(&ran, 1) })
+
+	// The worker backs off while its tasks panic.
+	deadline := time.Now().Add(5 * time.Second)
+	for !errors.Is(wp.Healthy(), ErrPanicking) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected Healthy to report ErrPanicking")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	wp.StopWait()
+
+	// Backoffs of 20ms, 40ms, and 40ms, capped at the maximum.
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatal("worker did not back off after panics:", elapsed)
+	}
+	if atomic.LoadInt32(&ran) != 1 {
+		t.Fatal("task after panics did not run")
+	}
+	if err := wp.Healthy(); !errors.Is(err, ErrStopped) {
+		t.Fatal("expected ErrStopped, got", err)
+	}
+
+	var backoffs int
+	for len(wp.Events()) != 0 {
+		if e := <-wp.Events(); e.Type == WorkerBackoff {
+			backoffs++
+		}
+	}
+	if backoffs != 3 {
+		t.Fatal("expected 3 WorkerBackoff events, got", backoffs)
+	}
+}
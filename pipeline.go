@@ -0,0 +1,128 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+)
+
+// Pipeline connects a series of stages, where each stage processes items
+// using its own worker pool, and passes its results to the next stage over a
+// channel.  This allows each stage to have a concurrency limit suited to the
+// resources that it uses.
+//
+// A pipeline is built by calling From with the input channel, and then
+// calling Then once for each stage.  The output of the last stage must be read
+// until it is closed, after which Wait returns the first error from any stage.
+//
+//	pl := workerpool.NewPipeline(ctx)
+//	urls := workerpool.From(pl, urlChan)
+//	pages := workerpool.Then(urls, fetchPool, fetch)
+//	docs := workerpool.Then(pages, parsePool, parse)
+//	for doc := range docs.Out() {
+//	    ...
+//	}
+//	if err := pl.Wait(); err != nil {
+//	    ...
+//	}
+//
+// Each stage closes its output channel after its input channel is closed and
+// all of its items have been processed, so stages shut down in order from
+// first to last.  If any stage returns an error, or the pipeline's context is
+// canceled, all stages stop processing new items.  The producer writing to
+// the pipeline's input channel should also stop when the context is done.
+type Pipeline struct {
+	parent  context.Context
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	err     error
+}
+
+// Stage is a step in a Pipeline that outputs items of type T.
+type Stage[T any] struct {
+	pl  *Pipeline
+	out <-chan T
+}
+
+type stageResult[R any] struct {
+	value R
+	err   error
+}
+
+// NewPipeline creates a new Pipeline that stops processing when ctx is
+// canceled.
+func NewPipeline(ctx context.Context) *Pipeline {
+	pctx, cancel := context.WithCancel(ctx)
+	return &Pipeline{
+		parent: ctx,
+		ctx:    pctx,
+		cancel: cancel,
+	}
+}
+
+// Context returns the pipeline's context, which is canceled when any stage
+// fails or the parent context is canceled.
+func (pl *Pipeline) Context() context.Context {
+	return pl.ctx
+}
+
+// Wait waits for all stages to finish, and returns the first error returned
+// by any stage.  If no stage failed, but the parent context was canceled,
+// then the context's error is returned.
+func (pl *Pipeline) Wait() error {
+	pl.wg.Wait()
+	pl.cancel()
+	if pl.err != nil {
+		return pl.err
+	}
+	return pl.parent.Err()
+}
+
+func (pl *Pipeline) fail(err error) {
+	pl.errOnce.Do(func() {
+		pl.err = err
+		pl.cancel()
+	})
+}
+
+// From creates the first stage of a pipeline, which outputs the items read
+// from the in channel.
+func From[T any](pl *Pipeline, in <-chan T) *Stage[T] {
+	return &Stage[T]{pl: pl, out: in}
+}
+
+// Then adds a stage to the pipeline that calls fn for each item output by the
+// previous stage, using the given worker pool.  The values returned by fn are
+// output by the new stage in the order that calls complete.  If fn returns an
+// error, then the pipeline is stopped.
+func Then[T, R any](s *Stage[T], p *WorkerPool, fn func(context.Context, T) (R, error)) *Stage[R] {
+	pl := s.pl
+	results := Process(pl.ctx, p, s.out, func(v T) stageResult[R] {
+		r, err := fn(pl.ctx, v)
+		return stageResult[R]{r, err}
+	})
+	out := make(chan R)
+	pl.wg.Add(1)
+	go func() {
+		defer pl.wg.Done()
+		defer close(out)
+		for res := range results {
+			if res.err != nil {
+				pl.fail(res.err)
+				continue
+			}
+			select {
+			case out <- res.value:
+			case <-pl.ctx.Done():
+			}
+		}
+	}()
+	return &Stage[R]{pl: pl, out: out}
+}
+
+// Out returns the channel that the stage outputs items on.  The channel is
+// closed when the stage has finished.
+func (s *Stage[T]) Out() <-chan T {
+	return s.out
+}
@@ -0,0 +1,78 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Parallel()
+
+	wp1 := New(2)
+	defer wp1.Stop()
+	wp2 := New(5)
+	defer wp2.Stop()
+
+	pl := NewPipeline(context.Background())
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 1; i <= 20; i++ {
+			in <- i
+		}
+	}()
+
+	doubled := Then(From(pl, in), wp1, func(_ context.Context, n int) (int, error) {
+		return n * 2, nil
+	})
+	strs := Then(doubled, wp2, func(_ context.Context, n int) (string, error) {
+		return strconv.Itoa(n), nil
+	})
+
+	var sum int
+	for s := range strs.Out() {
+		n, _ := strconv.Atoi(s)
+		sum += n
+	}
+	if err := pl.Wait(); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if sum != 420 {
+		t.Fatal("wrong sum:", sum)
+	}
+}
+
+func TestPipelineError(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	testErr := errors.New("bad item")
+	pl := NewPipeline(context.Background())
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := 0; ; i++ {
+			select {
+			case in <- i:
+			case <-pl.Context().Done():
+				return
+			}
+		}
+	}()
+
+	out := Then(From(pl, in), wp, func(_ context.Context, n int) (int, error) {
+		if n == 10 {
+			return 0, testErr
+		}
+		return n, nil
+	})
+	for range out.Out() {
+	}
+	if err := pl.Wait(); err != testErr {
+		t.Fatal("expected stage error, got", err)
+	}
+}
@@ -0,0 +1,38 @@
+package workerpool
+
+import "context"
+
+// Context returns a context that is canceled, with ErrStopped as its cause,
+// when the worker pool is stopped without waiting for its queued tasks, such as
+// by Stop, or when StopWait stops waiting, such as when the grace period of
+// StopOnSignal ends.  Otherwise, it is canceled once the worker pool has
+// stopped.  After Reboot, Context returns a new context.
+func (p *WorkerPool) Context() context.Context {
+	return p.runState.Load().ctx
+}
+
+// SubmitContext enqueues a function for a worker to execute with a context
+// that is canceled when ctx is done, or when the worker pool's Context is
+// canceled.  This lets long tasks return early when the worker pool is
+// stopped.  The task is skipped, instead of being run, if ctx is done before a
+// worker starts it, the same as a task submitted using SubmitTask.  If ctx is
+// nil, only the worker pool's Context is used.  Returns a handle that can
+// cancel the task before it starts.
+func (p *WorkerPool) SubmitContext(ctx context.Context, task func(context.Context)) *Handle {
+	if task == nil {
+		return nil
+	}
+	return p.SubmitTask(Task{
+		Fn: func(ctx context.Context) {
+			poolCtx := p.Context()
+			ctx, cancel := context.WithCancelCause(ctx)
+			defer cancel(nil)
+			stop := context.AfterFunc(poolCtx, func() {
+				cancel(context.Cause(poolCtx))
+			})
+			defer stop()
+			task(ctx)
+		},
+		Ctx: ctx,
+	})
+}
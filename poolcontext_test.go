@@ -0,0 +1,61 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubmitContext(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	poolCtx := wp.Context()
+
+	started := make(chan struct{})
+	var cause error
+	wp.SubmitContext(nil, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		cause = context.Cause(ctx)
+	})
+	<-started
+
+	// A task whose context is done before it starts is skipped.
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan struct{}, 1)
+	wp.SubmitContext(ctx, func(context.Context) { ran <- struct{}{} })
+	cancel()
+
+	select {
+	case <-poolCtx.Done():
+		t.Fatal("pool context canceled before stopping")
+	case <-time.After(10 * time.Millisecond):
+	}
+	wp.Stop()
+	if cause != ErrStopped {
+		t.Fatal("expected running task to be canceled by Stop, got", cause)
+	}
+	if len(ran) != 0 {
+		t.Fatal("task ran after its context was canceled")
+	}
+
+	wp.Reboot()
+	defer wp.Stop()
+	if wp.Context().Err() != nil {
+		t.Fatal("expected new context after Reboot")
+	}
+	wp.SubmitContext(context.Background(), func(ctx context.Context) {
+		if ctx.Err() != nil {
+			t.Error("task context canceled before StopWait")
+		}
+		ran <- struct{}{}
+	})
+	wp.StopWait()
+	if len(ran) != 1 {
+		t.Fatal("task not run by StopWait")
+	}
+	if context.Cause(wp.Context()) != ErrStopped {
+		t.Fatal("expected pool context to be canceled after stopping")
+	}
+}
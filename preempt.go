@@ -0,0 +1,82 @@
+package workerpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Preemption configures preemption of running tasks, set using
+// WithPreemption.
+type Preemption struct {
+	// MaxPriority is the highest priority of the running tasks that may be
+	// preempted.
+	MaxPriority int
+	// MinGap is how much higher a submitted task's priority must be than a
+	// running task's priority to preempt it.  Values less than 1 are 1.
+	MinGap int
+	// MinRunning is how long a task must have been running before it may be
+	// preempted, so that tasks that have just started are not preempted.
+	MinRunning time.Duration
+	// Preempted, if not nil, is called with the information of each
+	// preempted task, from the goroutine that submitted the higher priority
+	// task.  This can be used to log or count preemptions.  A task that must
+	// be run again after being preempted should resubmit itself when its
+	// context is canceled with ErrTaskCanceled as the cause.
+	Preempted func(TaskInfo)
+}
+
+// WithPreemption lets a task submitted using SubmitTask preempt a running task
+// of lower priority when all workers are busy.  The context of the running
+// task that has been running the longest, of those that the preemption policy
+// allows, is canceled, with ErrTaskCanceled as its cause.  The preempted task
+// must return when its context is canceled to free its worker.
+//
+// Only tasks submitted using SubmitTask are preempted, since they are the only
+// tasks that are given a context.  Since waiting tasks are otherwise started in
+// the order they were submitted, preemption is normally used with a Queue, set
+// using WithQueue, that starts higher priority tasks first.
+func WithPreemption(preemption Preemption) Option {
+	return func(p *WorkerPool) {
+		if preemption.MinGap < 1 {
+			preemption.MinGap = 1
+		}
+		p.preemption = &preemption
+		p.trackTasks = true
+	}
+}
+
+// preempt cancels the context of the running task that a submitted task
+// preempts, if all workers are busy and a running task may be preempted.
+func (p *WorkerPool) preempt(t *task) {
+	if int(atomic.LoadInt32(&p.busyWorkers)) < p.maxWorkers {
+		return
+	}
+	policy := p.preemption
+	now := time.Now().UnixNano()
+	var victim *task
+	var oldest int64
+	p.workerMutex.Lock()
+	for _, ws := range p.workerStates {
+		rt := ws.task.Load()
+		started := atomic.LoadInt64(&ws.started)
+		if rt == nil || started == 0 || rt.cancel.Load() == nil ||
+			atomic.LoadInt32(&rt.preempted) != 0 ||
+			rt.priority > policy.MaxPriority ||
+			t.priority-rt.priority < policy.MinGap ||
+			time.Duration(now-started) < policy.MinRunning {
+			continue
+		}
+		if victim == nil || started < oldest {
+			victim = rt
+			oldest = started
+		}
+	}
+	p.workerMutex.Unlock()
+	if victim == nil || !atomic.CompareAndSwapInt32(&victim.preempted, 0, 1) {
+		return
+	}
+	(*victim.cancel.Load())(ErrTaskCanceled)
+	if policy.Preempted != nil {
+		policy.Preempted(victim.Info())
+	}
+}
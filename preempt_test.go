@@ -0,0 +1,66 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPreemption(t *testing.T) {
+	t.Parallel()
+
+	preempted := make(chan TaskInfo, 1)
+	wp := New(1, WithPreemption(Preemption{
+		MaxPriority: 1,
+		Preempted:   func(info TaskInfo) { preempted <- info },
+	}))
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	var cause error
+	wp.SubmitTask(Task{
+		Fn: func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			cause = context.Cause(ctx)
+			close(canceled)
+		},
+		Name: "low",
+	})
+	<-started
+
+	// A task that is not of higher priority does not preempt.
+	ran := make(chan int, 2)
+	wp.SubmitTask(Task{
+		Fn: func(context.Context) { ran <- 0 },
+	})
+	select {
+	case <-canceled:
+		t.Fatal("task preempted by task of the same priority")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	wp.SubmitTask(Task{
+		Fn:       func(context.Context) { ran <- 2 },
+		Priority: 2,
+	})
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("running task not preempted")
+	}
+	if cause != ErrTaskCanceled {
+		t.Fatal("expected preempted task's context cause to be ErrTaskCanceled, got", cause)
+	}
+	if info := <-preempted; info.Name != "low" {
+		t.Fatal("expected low priority task to be preempted, got", info.Name)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ran:
+		case <-time.After(5 * time.Second):
+			t.Fatal("waiting tasks did not run after preemption")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package workerpool
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ProfileLabel is the pprof label that identifies the worker goroutine, and
+// the goroutines that its tasks start, in the profiles of slow tasks.
+const ProfileLabel = "workerpool.worker"
+
+// SlowTaskProfile is a CPU profile captured while a slow task was running.
+type SlowTaskProfile struct {
+	// Running is how long the task had been running when profiling started.
+	Running time.Duration
+	// Name is the name of the task.
+	Name string
+	// Labels are the labels of the task, if submitted with metadata.
+	Labels map[string]string
+	// Worker is the value of the ProfileLabel label of the samples taken from
+	// the worker running the task.
+	Worker string
+	// Profile is the CPU profile, in the pprof format.
+	Profile []byte
+}
+
+type profiler struct {
+	threshold time.Duration
+	duration  time.Duration
+	report    func(SlowTaskProfile)
+}
+
+// WithSlowTaskProfile captures a CPU profile, lasting for the given duration,
+// when a task is still running after the threshold duration, and calls report
+// with it.  This helps to diagnose tasks that are sporadically slow in
+// production.
+//
+// The Go runtime profiles the whole process, and only one CPU profile can be
+// captured at a time, so a task is not profiled while any other CPU profile is
+// being captured.  Each worker goroutine is labeled with ProfileLabel, so the
+// samples from the slow task's worker can be selected, for example using
+// "go tool pprof -tagfocus workerpool.worker=<Worker>".
+//
+// Running tasks are checked at intervals of half the threshold, and each task
+// is profiled at most once.  The report function is called from the profiling
+// goroutine.
+func WithSlowTaskProfile(threshold, duration time.Duration, report func(SlowTaskProfile)) Option {
+	return func(p *WorkerPool) {
+		if threshold > 0 && duration > 0 && report != nil {
+			p.profiler = &profiler{threshold, duration, report}
+			p.trackTasks = true
+		}
+	}
+}
+
+// labelWorker labels the calling worker goroutine for profiles.
+func labelWorker(ws *workerState) {
+	pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(),
+		pprof.Labels(ProfileLabel, strconv.FormatUint(ws.goroutineID, 10))))
+}
+
+// runProfiler periodically checks for tasks that have been running longer
+// than the profiling threshold, and profiles them, until the worker pool
+// stops.
+func (p *WorkerPool) runProfiler(stoppedChan <-chan struct{}) {
+	ticker := time.NewTicker(p.profiler.threshold / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stoppedChan:
+			return
+		}
+
+		now := time.Now().UnixNano()
+		var slow *workerState
+		var started int64
+		p.workerMutex.Lock()
+		for _, ws := range p.workerStates {
+			started = atomic.LoadInt64(&ws.started)
+			if started != 0 && time.Duration(now-started) >= p.profiler.threshold &&
+				atomic.LoadInt64(&ws.profiled) != started {
+				slow = ws
+				break
+			}
+		}
+		p.workerMutex.Unlock()
+		if slow == nil {
+			continue
+		}
+
+		var buf bytes.Buffer
+		if pprof.StartCPUProfile(&buf) != nil {
+			// Another CPU profile is being captured.
+			continue
+		}
+		atomic.StoreInt64(&slow.profiled, started)
+		st := SlowTaskProfile{
+			Running: time.Duration(now - started),
+			Worker:  strconv.FormatUint(slow.goroutineID, 10),
+		}
+		if t := slow.task.Load(); t != nil {
+			st.Name = t.name()
+			st.Labels = t.labels()
+		}
+		timer := time.NewTimer(p.profiler.duration)
+		select {
+		case <-timer.C:
+		case <-stoppedChan:
+			timer.Stop()
+		}
+		pprof.StopCPUProfile()
+		st.Profile = buf.Bytes()
+		p.profiler.report(st)
+	}
+}
@@ -0,0 +1,43 @@
+package workerpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlowTaskProfile(t *testing.T) {
+	t.Parallel()
+
+	profiles := make(chan SlowTaskProfile, 1)
+	wp := New(1, WithSlowTaskProfile(20*time.Millisecond, 50*time.Millisecond, func(p SlowTaskProfile) {
+		profiles <- p
+	}))
+	defer wp.Stop()
+
+	wp.SubmitWithMetadata(Metadata{Name: "slow"}, func() {
+		for end := time.Now().Add(200 * time.Millisecond); time.Now().Before(end); {
+		}
+	})
+
+	select {
+	case p := <-profiles:
+		if p.Name != "slow" {
+			t.Fatal("wrong task name:", p.Name)
+		}
+		if p.Running < 20*time.Millisecond {
+			t.Fatal("task profiled before threshold:", p.Running)
+		}
+		if p.Worker == "" || len(p.Profile) == 0 {
+			t.Fatal("missing worker or profile")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow task was not profiled")
+	}
+
+	wp.StopWait()
+	select {
+	case <-profiles:
+		t.Fatal("task profiled more than once")
+	default:
+	}
+}
@@ -0,0 +1,75 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+)
+
+// Progress is reported by a long-running task, and read by observers, such as
+// a progress endpoint.  A Progress is given to a task by setting it in the
+// Progress field of the Task submitted using SubmitTask, and the task gets it
+// from its context using ProgressFrom.  A Progress is safe for concurrent use,
+// and the zero value is ready to use.
+type Progress struct {
+	mutex    sync.Mutex
+	fraction float64
+	message  string
+	// updated is created when an observer first calls Updated after an
+	// update.
+	updated chan struct{}
+}
+
+// NewProgress creates a Progress with no progress.
+func NewProgress() *Progress {
+	return &Progress{}
+}
+
+// Set records the fraction of the task that is done, from 0 to 1, and a
+// message describing what the task is doing.  Does nothing if the Progress is
+// nil, so that a task does not have to check whether it was given one.
+func (p *Progress) Set(fraction float64, message string) {
+	if p == nil {
+		return
+	}
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	p.mutex.Lock()
+	p.fraction = fraction
+	p.message = message
+	if p.updated != nil {
+		close(p.updated)
+		p.updated = nil
+	}
+	p.mutex.Unlock()
+}
+
+// Get returns the fraction done and the message last set by the task.
+func (p *Progress) Get() (float64, string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.fraction, p.message
+}
+
+// Updated returns a channel that is closed when the task next sets its
+// progress.  Observers call Updated again after each update to subscribe to
+// the next one.
+func (p *Progress) Updated() <-chan struct{} {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.updated == nil {
+		p.updated = make(chan struct{})
+	}
+	return p.updated
+}
+
+type progressKey struct{}
+
+// ProgressFrom returns the Progress of the task that was given ctx, or nil if
+// the task was not submitted with a Progress.
+func ProgressFrom(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+	return p
+}
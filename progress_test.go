@@ -0,0 +1,66 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithTaskIndex(10))
+	defer wp.Stop()
+
+	progress := NewProgress()
+	updated := progress.Updated()
+	step := make(chan struct{})
+	h := wp.SubmitTask(Task{
+		Fn: func(ctx context.Context) {
+			p := ProgressFrom(ctx)
+			p.Set(0.5, "halfway")
+			<-step
+			p.Set(2, "done")
+		},
+		Progress: progress,
+	})
+
+	select {
+	case <-updated:
+	case <-time.After(5 * time.Second):
+		t.Fatal("progress update not signaled")
+	}
+	if fraction, message := progress.Get(); fraction != 0.5 || message != "halfway" {
+		t.Fatal("wrong progress:", fraction, message)
+	}
+	updated = progress.Updated()
+	close(step)
+	<-updated
+	wp.StopWait()
+
+	status, _ := wp.Status(h.ID())
+	if status.Progress != progress {
+		t.Fatal("expected progress in task status")
+	}
+	if fraction, message := status.Progress.Get(); fraction != 1 || message != "done" {
+		t.Fatal("wrong final progress:", fraction, message)
+	}
+
+	// A task without a Progress can still report progress.
+	wp2 := New(1)
+	defer wp2.Stop()
+	wp2.SubmitTask(Task{Fn: func(ctx context.Context) {
+		ProgressFrom(ctx).Set(1, "ignored")
+	}})
+	wp2.StopWait()
+
+	// The zero value is ready to use.
+	var zero Progress
+	zero.Set(0.25, "started")
+	updated = zero.Updated()
+	zero.Set(0.5, "halfway")
+	<-updated
+	if fraction, message := zero.Get(); fraction != 0.5 || message != "halfway" {
+		t.Fatal("wrong progress:", fraction, message)
+	}
+}
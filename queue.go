@@ -0,0 +1,74 @@
+package workerpool
+
+import "github.com/gammazero/deque"
+
+// Queue holds tasks that are waiting for a worker.  The dispatcher pushes a
+// task onto a queue when no worker is available, and pops the next task to
+// give to a worker when one becomes ready.  An implementation decides the
+// order that waiting tasks are started, and may, for example, order tasks by
+// priority.
+//
+// A Queue is only used by the dispatcher goroutine, so it does not need to be
+// safe for concurrent use.
+type Queue interface {
+	// Push adds a task to the queue.
+	Push(task QueuedTask)
+	// Pop removes and returns the next task to start.  Pop is only called
+	// when the queue is not empty.
+	Pop() QueuedTask
+	// Len returns the number of tasks in the queue.
+	Len() int
+}
+
+// QueuedTask is a task that is waiting in a Queue.  Its function cannot be
+// called directly, and it must be returned by Pop to be executed.
+type QueuedTask interface {
+	// Info describes the task.
+	Info() TaskInfo
+}
+
+// WithQueue sets the function that creates the queues that hold waiting
+// tasks.  Tasks are queued separately for each tenant, as described for
+// SubmitTenant, so a queue is created each time a tenant has tasks waiting
+// after having none.  Tasks submitted without a tenant are held in the queue
+// of the tenant with the empty name.  By default, queues are created by
+// NewQueue.
+func WithQueue(newQueue func() Queue) Option {
+	return func(p *WorkerPool) {
+		p.newQueue = newQueue
+	}
+}
+
+// NewQueue returns an unbounded in-memory queue that starts tasks in the order
+// that they are pushed.  This is the queue used by default.
+func NewQueue() Queue {
+	return &fifoQueue{}
+}
+
+// fifoQueue is a Queue that holds tasks in a deque.
+type fifoQueue struct {
+	tasks deque.Deque
+}
+
+func (q *fifoQueue) Push(task QueuedTask) {
+	q.tasks.PushBack(task)
+}
+
+func (q *fifoQueue) Pop() QueuedTask {
+	return q.tasks.PopFront().(QueuedTask)
+}
+
+func (q *fifoQueue) Len() int {
+	return q.tasks.Len()
+}
+
+// Info describes a task for a Queue or an Admission function.
+func (t *task) Info() TaskInfo {
+	return TaskInfo{
+		ID:       t.id,
+		Name:     t.name(),
+		Labels:   t.labels(),
+		Priority: t.priority,
+		Tenant:   t.tenant,
+	}
+}
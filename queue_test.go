@@ -0,0 +1,75 @@
+package workerpool
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// priorityQueue is a Queue that pops the task with the highest priority.
+type priorityQueue []QueuedTask
+
+func (q priorityQueue) Len() int            { return len(q) }
+func (q priorityQueue) Less(i, j int) bool  { return q[i].Info().Priority > q[j].Info().Priority }
+func (q priorityQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *priorityQueue) Push(x interface{}) { *q = append(*q, x.(QueuedTask)) }
+func (q *priorityQueue) Pop() interface{} {
+	old := *q
+	t := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return t
+}
+
+type heapQueue struct {
+	pq priorityQueue
+}
+
+func (q *heapQueue) Push(task QueuedTask) { heap.Push(&q.pq, task) }
+func (q *heapQueue) Pop() QueuedTask      { return heap.Pop(&q.pq).(QueuedTask) }
+func (q *heapQueue) Len() int             { return q.pq.Len() }
+
+func TestQueue(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithDebugDump(), WithQueue(func() Queue { return &heapQueue{} }))
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+
+	var mutex sync.Mutex
+	var order []int
+	priorities := []int{3, 1, 5, 2, 4}
+	for _, pri := range priorities {
+		pri := pri
+		wp.SubmitTask(Task{
+			Fn: func(context.Context) {
+				mutex.Lock()
+				order = append(order, pri)
+				mutex.Unlock()
+			},
+			Priority: pri,
+		})
+	}
+	for wp.WaitingQueueSize() != len(priorities) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Reading the waiting tasks leaves them in the queue.
+	if dump := wp.Dump(); len(dump.Waiting) != len(priorities) {
+		t.Fatal("expected 5 waiting tasks in dump, got", len(dump.Waiting))
+	}
+
+	close(release)
+	wp.StopWait()
+	if len(order) != len(priorities) {
+		t.Fatal("expected 5 tasks to run, ran", len(order))
+	}
+	for i, pri := range order {
+		if pri != len(priorities)-i {
+			t.Fatal("tasks not started in priority order:", order)
+		}
+	}
+}
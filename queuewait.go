@@ -0,0 +1,49 @@
+package workerpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// MetricQueueWait times how long each task waits between being submitted and
+// starting.
+const MetricQueueWait = "workerpool.task.queue_wait"
+
+// WithQueueWaitStats records how long each task waits between being submitted
+// and starting, in the QueueWait fields of the worker pool's Stats.  A long
+// queue wait with short task durations means that the worker pool has too few
+// workers, rather than slow tasks.  Queue wait is also recorded when using
+// WithMetrics.
+func WithQueueWaitStats() Option {
+	return func(p *WorkerPool) {
+		p.queueWaitStats = true
+		p.recordEnqueue = true
+	}
+}
+
+// recordQueueWait records how long a task waited for a worker.
+func (p *WorkerPool) recordQueueWait(t *task) {
+	if t.enqueued.IsZero() {
+		return
+	}
+	wait := time.Since(t.enqueued)
+	if p.shedding != nil || p.admission != nil || p.watermarks != nil {
+		atomic.StoreInt64(&p.queueWait, int64(wait))
+	}
+	if p.slo != nil {
+		p.slo.record(wait)
+	}
+	if p.metrics != nil {
+		p.metrics.Timing(MetricQueueWait, wait)
+	}
+	if p.queueWaitStats {
+		atomic.AddInt64(&p.queueWaitCount, 1)
+		atomic.AddInt64(&p.queueWaitTotal, int64(wait))
+		for {
+			max := atomic.LoadInt64(&p.queueWaitMax)
+			if int64(wait) <= max || atomic.CompareAndSwapInt64(&p.queueWaitMax, max, int64(wait)) {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package workerpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueWaitStats(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithQueueWaitStats())
+	wp.Submit(func() { time.Sleep(20 * time.Millisecond) })
+	wp.Submit(func() {})
+	wp.StopWait()
+
+	stats := wp.Stats()
+	if stats.QueueWaitCount != 2 {
+		t.Fatal("expected 2 queue waits, got", stats.QueueWaitCount)
+	}
+	if stats.QueueWaitMax < 15*time.Millisecond {
+		t.Fatal("queue wait too short:", stats.QueueWaitMax)
+	}
+	if stats.QueueWaitTotal < stats.QueueWaitMax {
+		t.Fatal("total queue wait less than max")
+	}
+}
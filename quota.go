@@ -0,0 +1,111 @@
+package workerpool
+
+import "fmt"
+
+// ErrQuotaExceeded is returned by SubmitTenant when a tenant already has the
+// maximum number of tasks queued allowed by its quota, and the quota does not
+// block.  It wraps ErrRejected.
+var ErrQuotaExceeded = fmt.Errorf("%w: tenant quota exceeded", ErrRejected)
+
+// Quota limits the tasks of one tenant in a worker pool.  A zero limit means
+// no limit.
+type Quota struct {
+	// MaxQueued is the maximum number of the tenant's tasks that may be
+	// submitted and waiting to start.
+	MaxQueued int
+	// MaxRunning is the maximum number of the tenant's tasks that workers
+	// execute concurrently.  The tenant's other tasks wait, without occupying
+	// workers, while other tenants' tasks run.
+	MaxRunning int
+	// Block makes SubmitTenant wait until the tenant is below MaxQueued,
+	// instead of returning ErrQuotaExceeded.
+	Block bool
+	// Weight is the number of the tenant's waiting tasks that are given to
+	// workers in each of its turns, so that tenants share workers in
+	// proportion to their weights while tasks are waiting.  Values less than
+	// 1 are 1.
+	Weight int
+}
+
+// WithTenantQuota sets a quota for the named tenant's tasks, which are
+// submitted using SubmitTenant.  Tenants without a quota are not limited.
+// This option may be given once for each tenant.
+func WithTenantQuota(tenant string, quota Quota) Option {
+	return func(p *WorkerPool) {
+		if p.quotas == nil {
+			p.quotas = map[string]*tenantQuota{}
+		}
+		tq := &tenantQuota{Quota: quota, name: tenant}
+		if quota.MaxQueued > 0 {
+			tq.slots = make(chan struct{}, quota.MaxQueued)
+		}
+		p.quotas[tenant] = tq
+	}
+}
+
+// tenantQuota is a tenant's quota and the tenant's current usage.
+type tenantQuota struct {
+	Quota
+	name string
+	// slots holds a value for each of the tenant's queued tasks.
+	slots chan struct{}
+	// running is the number of the tenant's tasks given to workers, and is
+	// only accessed by the dispatcher.
+	running int
+}
+
+// acquire reserves a queue slot for a task being submitted.  Returns false if
+// the quota is exceeded and does not block.
+func (q *tenantQuota) acquire() bool {
+	if q.slots == nil {
+		return true
+	}
+	if q.Block {
+		q.slots <- struct{}{}
+		return true
+	}
+	select {
+	case q.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// release frees the queue slot of a task that is no longer queued.
+func (q *tenantQuota) release() {
+	if q.slots != nil {
+		<-q.slots
+	}
+}
+
+// start records that a queued task of the tenant is given to a worker.
+func (q *tenantQuota) start() {
+	q.release()
+	if q.MaxRunning > 0 {
+		q.running++
+	}
+}
+
+// weight returns the number of tasks taken from the tenant in each turn.  A nil
+// quota has a weight of 1.
+func (q *tenantQuota) weight() int {
+	if q == nil || q.Weight < 1 {
+		return 1
+	}
+	return q.Weight
+}
+
+// full returns true if the tenant has its maximum number of tasks running.
+// A nil quota is never full.
+func (q *tenantQuota) full() bool {
+	return q != nil && q.MaxRunning > 0 && q.running >= q.MaxRunning
+}
+
+// taskFinished tells the dispatcher that a task with a running limit has
+// completed, so that the tenant's waiting tasks may be started.
+func (p *WorkerPool) taskFinished(t *task) {
+	if t.quota != nil && t.quota.MaxRunning > 0 {
+		p.enqueue(&task{finished: t.quota})
+	}
+}
@@ -0,0 +1,92 @@
+package workerpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQuotaMaxQueued(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithTenantQuota("a", Quota{MaxQueued: 2}),
+		WithTenantQuota("b", Quota{MaxQueued: 1, Block: true}))
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	wp.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	for i := 0; i < 2; i++ {
+		if err := wp.SubmitTenant("a", func() {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := wp.SubmitTenant("a", func() {}); err != ErrQuotaExceeded {
+		t.Fatal("expected ErrQuotaExceeded, got", err)
+	}
+	if err := wp.SubmitTenant("c", func() {}); err != nil {
+		t.Fatal("tenant without quota was limited:", err)
+	}
+
+	wp.SubmitTenant("b", func() {})
+	submitted := make(chan struct{})
+	go func() {
+		wp.SubmitTenant("b", func() {})
+		close(submitted)
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("submit over quota did not block")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-submitted
+	wp.Wait()
+	if err := wp.SubmitTenant("a", func() {}); err != nil {
+		t.Fatal("quota not released after tasks started:", err)
+	}
+}
+
+func TestQuotaMaxRunning(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4, WithTenantQuota("a", Quota{MaxRunning: 2}))
+	defer wp.Stop()
+
+	var running, maxRunning int32
+	var mutex sync.Mutex
+	for i := 0; i < 10; i++ {
+		wp.SubmitTenant("a", func() {
+			n := atomic.AddInt32(&running, 1)
+			mutex.Lock()
+			if n > maxRunning {
+				maxRunning = n
+			}
+			mutex.Unlock()
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+	}
+	// Tasks of other tenants are not held behind tenant a's tasks.
+	done := make(chan struct{})
+	wp.SubmitTenant("b", func() {
+		close(done)
+	})
+	select {
+	case <-done:
+	case <-time.After(20 * time.Millisecond):
+		t.Fatal("other tenant's task waited for held tenant")
+	}
+	wp.StopWait()
+
+	if maxRunning != 2 {
+		t.Fatal("expected at most 2 tasks running, had", maxRunning)
+	}
+}
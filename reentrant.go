@@ -0,0 +1,130 @@
+package workerpool
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrReentrantWait is reported when a task waits for tasks in the same worker
+// pool that is running it.  This deadlocks if all workers are busy running
+// tasks that are waiting, since no worker is left to run the tasks being
+// waited for.  Waiting for all tasks, using Wait or Flush, always deadlocks,
+// since the calling task is one of the tasks being waited for.
+var ErrReentrantWait = errors.New("workerpool: task waits for tasks in its own worker pool")
+
+// WithReentrantCheck enables detection of tasks that wait for other tasks in
+// the same worker pool, using SubmitWait, Wait, Flush, Future.Wait, group
+// Wait methods, or the Map, ForEach, Reduce, and FanOut helpers.  When this is
+// detected, handler is called, from the waiting task, with an error that wraps
+// ErrReentrantWait and names the waiting call.  The handler can log a warning,
+// or panic to escalate.  If handler is nil, detection panics with the error.
+//
+// Detection identifies worker goroutines by reading the calling goroutine's
+// stack, which has a cost each time a waiting call is made while tasks are
+// running.  It is intended for testing and debugging.
+func WithReentrantCheck(handler func(error)) Option {
+	return func(p *WorkerPool) {
+		if handler == nil {
+			handler = func(err error) { panic(err) }
+		}
+		p.reentrant = handler
+		p.trackTasks = true
+	}
+}
+
+// checkReentrant reports if the calling goroutine is one of the worker pool's
+// workers, when reentrant checking is enabled.
+func (p *WorkerPool) checkReentrant(op string) {
+	if p.reentrant == nil || !p.isWorker() {
+		return
+	}
+	p.reentrant(fmt.Errorf("%w: %s called from task", ErrReentrantWait, op))
+}
+
+// WithInlineNested makes tasks that are submitted and waited for by a task
+// running in the same worker pool execute inline, on the calling task's
+// worker, instead of being queued.  This applies to SubmitWait and to the Map,
+// ForEach, Reduce, and FanOut helpers.  It prevents recursive workloads, where
+// tasks submit and wait for subtasks, from deadlocking when all workers are
+// busy waiting.  Nested tasks run one at a time, so they do not use more than
+// the worker pool's maximum number of workers.
+//
+// Like WithReentrantCheck, this identifies worker goroutines by reading the
+// calling goroutine's stack, which has a cost each time a waiting call is
+// made while tasks are running.
+func WithInlineNested() Option {
+	return func(p *WorkerPool) {
+		p.inline = true
+		p.trackTasks = true
+	}
+}
+
+// nested is called by functions that submit tasks and wait for them.  Returns
+// true if the caller is a task running on one of the worker pool's workers and
+// nested tasks are run inline.  Otherwise, performs the reentrant wait check.
+func (p *WorkerPool) nested(op string) bool {
+	if p.inline && p.isWorker() {
+		return true
+	}
+	p.checkReentrant(op)
+	return false
+}
+
+// submitNested executes the task inline if inline is true, or submits it to
+// the worker pool otherwise.
+func (p *WorkerPool) submitNested(inline bool, task func()) {
+	if inline {
+		task()
+		return
+	}
+	p.submit(task)
+}
+
+// isWorker returns true if the calling goroutine is one of the worker pool's
+// workers, or one of the workers of its parent pools, which run the tasks of
+// child pools.  Workers are identified by the goroutine that started them, so
+// they do not need to be registered, and the calling goroutine's stack is only
+// read when a pool in the chain has busy workers.
+func (p *WorkerPool) isWorker() bool {
+	var id uint64
+	for q := p; q != nil; q = q.parent {
+		if atomic.LoadInt32(&q.busyWorkers) == 0 {
+			continue
+		}
+		if id == 0 {
+			if id = creatorGoroutineID(); id == 0 {
+				return false
+			}
+		}
+		q.workerMutex.Lock()
+		_, ok := q.spawners[id]
+		q.workerMutex.Unlock()
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// addSpawner records that the calling goroutine starts workers, so that
+// isWorker can identify them.  Returns the goroutine's ID, to give to
+// removeSpawner once it no longer starts workers.
+func (p *WorkerPool) addSpawner() uint64 {
+	id := curGoroutineID()
+	p.workerMutex.Lock()
+	if p.spawners == nil {
+		p.spawners = map[uint64]int{}
+	}
+	p.spawners[id]++
+	p.workerMutex.Unlock()
+	return id
+}
+
+func (p *WorkerPool) removeSpawner(id uint64) {
+	p.workerMutex.Lock()
+	if p.spawners[id]--; p.spawners[id] == 0 {
+		delete(p.spawners, id)
+	}
+	p.workerMutex.Unlock()
+}
@@ -0,0 +1,101 @@
+package workerpool
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReentrantCheck(t *testing.T) {
+	t.Parallel()
+
+	errs := make(chan error, 10)
+	wp := New(2, WithReentrantCheck(func(err error) { errs <- err }))
+	defer wp.Stop()
+
+	// Waiting from outside the pool is not reported.
+	wp.SubmitWait(func() {})
+	wp.Wait()
+
+	// SubmitWait from a task is reported.
+	wp.SubmitWait(func() {
+		wp.SubmitWait(func() {})
+	})
+	err := <-errs
+	if !errors.Is(err, ErrReentrantWait) {
+		t.Fatal("expected reentrant wait error, got", err)
+	}
+	if !strings.Contains(err.Error(), "SubmitWait") {
+		t.Fatal("error does not name call:", err)
+	}
+
+	// Waiting for a future from a task is reported.
+	wp.SubmitWait(func() {
+		wp.SubmitKeyed("key", func() {}).Wait()
+	})
+	if err = <-errs; !strings.Contains(err.Error(), "Future.Wait") {
+		t.Fatal("expected Future.Wait to be reported, got", err)
+	}
+
+	select {
+	case err = <-errs:
+		t.Fatal("unexpected report:", err)
+	default:
+	}
+}
+
+func TestReentrantCheckPanic(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithReentrantCheck(nil))
+	defer wp.Stop()
+
+	recovered := make(chan interface{}, 1)
+	wp.Submit(func() {
+		defer func() { recovered <- recover() }()
+		wp.Wait()
+	})
+	r := <-recovered
+	err, ok := r.(error)
+	if !ok || !errors.Is(err, ErrReentrantWait) {
+		t.Fatal("expected panic with reentrant wait error, got", r)
+	}
+}
+
+func TestInlineNested(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2, WithInlineNested(), WithReentrantCheck(func(err error) {
+		t.Error("unexpected reentrant wait:", err)
+	}))
+	defer wp.Stop()
+
+	// Recursive sum, where every level waits for the next, would deadlock
+	// with only two workers if nested tasks were queued.
+	var sum func(items []int) int
+	sum = func(items []int) int {
+		if len(items) == 1 {
+			return items[0]
+		}
+		mid := len(items) / 2
+		parts, _ := Map(wp, [][]int{items[:mid], items[mid:]}, func(part []int) (int, error) {
+			return sum(part), nil
+		})
+		return parts[0] + parts[1]
+	}
+	items := make([]int, 64)
+	for i := range items {
+		items[i] = i
+	}
+	if s := sum(items); s != 2016 {
+		t.Fatal("wrong sum:", s)
+	}
+
+	var ran bool
+	wp.SubmitWait(func() {
+		wp.SubmitWait(func() { ran = true })
+	})
+	if !ran {
+		t.Fatal("nested task did not run")
+	}
+}
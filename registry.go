@@ -0,0 +1,72 @@
+package workerpool
+
+import (
+	"sort"
+	"sync"
+)
+
+// registry holds the running worker pools that were created with a name.
+var registry = struct {
+	sync.Mutex
+	pools map[string]*WorkerPool
+}{pools: map[string]*WorkerPool{}}
+
+// WithName gives the worker pool a name, and registers the pool so that it can
+// be found using Get and listed using Names.  This lets metrics and debug
+// endpoints inspect all of a program's worker pools in one place.  The pool
+// is removed from the registry when it is stopped.  If another running pool
+// has the same name, the new pool replaces it in the registry.
+func WithName(name string) Option {
+	return func(p *WorkerPool) {
+		p.name = name
+	}
+}
+
+// Name returns the name given to the worker pool using WithName, or an empty
+// string if the pool does not have a name.
+func (p *WorkerPool) Name() string {
+	return p.name
+}
+
+// Get returns the running worker pool registered with the given name, or nil
+// if there is no such pool.
+func Get(name string) *WorkerPool {
+	registry.Lock()
+	defer registry.Unlock()
+	return registry.pools[name]
+}
+
+// Names returns the sorted names of all registered worker pools.
+func Names() []string {
+	registry.Lock()
+	names := make([]string, 0, len(registry.pools))
+	for name := range registry.pools {
+		names = append(names, name)
+	}
+	registry.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// register adds a named worker pool to the registry.
+func (p *WorkerPool) register() {
+	if p.name == "" {
+		return
+	}
+	registry.Lock()
+	registry.pools[p.name] = p
+	registry.Unlock()
+}
+
+// unregister removes a named worker pool from the registry, unless it has
+// been replaced by another pool with the same name.
+func (p *WorkerPool) unregister() {
+	if p.name == "" {
+		return
+	}
+	registry.Lock()
+	if registry.pools[p.name] == p {
+		delete(registry.pools, p.name)
+	}
+	registry.Unlock()
+}
@@ -0,0 +1,35 @@
+package workerpool
+
+import "testing"
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithName("registry-test"))
+	if wp.Name() != "registry-test" {
+		t.Fatal("wrong name:", wp.Name())
+	}
+	if Get("registry-test") != wp {
+		t.Fatal("named pool not registered")
+	}
+	var found bool
+	for _, name := range Names() {
+		if name == "registry-test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("named pool not listed")
+	}
+
+	unnamed := New(1)
+	defer unnamed.Stop()
+	if Get("") != nil {
+		t.Fatal("unnamed pool registered")
+	}
+
+	wp.Stop()
+	if Get("registry-test") != nil {
+		t.Fatal("stopped pool still registered")
+	}
+}
@@ -0,0 +1,71 @@
+package workerpool
+
+import "strconv"
+
+// RejectReason is why a task was not run, given to the function set using
+// WithOnReject.
+type RejectReason int
+
+const (
+	// RejectedByAdmission is the reason for a task rejected by the admission
+	// function, set using WithAdmission.
+	RejectedByAdmission RejectReason = iota
+	// RejectedByQuota is the reason for a task rejected because its tenant
+	// has the maximum number of tasks queued.
+	RejectedByQuota
+	// RejectedByShedding is the reason for a task rejected by load shedding.
+	RejectedByShedding
+	// RejectedByBreaker is the reason for a task rejected because the
+	// circuit breaker for its tag is open.
+	RejectedByBreaker
+	// RejectedByStop is the reason for a queued task that was dropped
+	// because the worker pool was stopped.
+	RejectedByStop
+	// RejectedByExpiry is the reason for a task submitted using SubmitTask
+	// whose context was canceled, or whose deadline passed, before it
+	// started.
+	RejectedByExpiry
+)
+
+var rejectReasonNames = [...]string{
+	RejectedByAdmission: "RejectedByAdmission",
+	RejectedByQuota:     "RejectedByQuota",
+	RejectedByShedding:  "RejectedByShedding",
+	RejectedByBreaker:   "RejectedByBreaker",
+	RejectedByStop:      "RejectedByStop",
+	RejectedByExpiry:    "RejectedByExpiry",
+}
+
+func (r RejectReason) String() string {
+	if r < 0 || int(r) >= len(rejectReasonNames) {
+		return "RejectReason(" + strconv.Itoa(int(r)) + ")"
+	}
+	return rejectReasonNames[r]
+}
+
+// WithOnReject sets a function that is called with each task that is not run,
+// for any reason, and the reason.  This lets the caller requeue the task
+// elsewhere, or record the loss, instead of tasks being dropped silently.  It
+// is called in addition to the callbacks of specific policies, such as
+// Shedding.Reject and WithOnDropped.  Tasks canceled by the caller, and tasks
+// saved by Checkpoint, are not rejected.
+//
+// For a task submitted using SubmitTask, task runs the task with its context,
+// or, if the task was rejected by expiry, with a new background context.  For
+// a task submitted using SubmitTagged, task runs the task and ignores its
+// error.  The function is called from the goroutine that submitted the task,
+// from a worker, or from the dispatcher while the pool is stopping, and must
+// not submit tasks to a stopping worker pool.
+func WithOnReject(fn func(task func(), reason RejectReason)) Option {
+	return func(p *WorkerPool) {
+		p.onReject = fn
+	}
+}
+
+// rejected calls the function set using WithOnReject, if any, with a task
+// that is not run.
+func (p *WorkerPool) rejected(fn func(), reason RejectReason) {
+	if p.onReject != nil && fn != nil {
+		p.onReject(fn, reason)
+	}
+}
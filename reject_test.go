@@ -0,0 +1,124 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOnReject(t *testing.T) {
+	t.Parallel()
+
+	var mutex sync.Mutex
+	reasons := map[RejectReason]int{}
+	onReject := func(task func(), reason RejectReason) {
+		mutex.Lock()
+		reasons[reason]++
+		mutex.Unlock()
+	}
+	wp := New(1,
+		WithOnReject(onReject),
+		WithTenantQuota("limited", Quota{MaxQueued: 1}),
+		WithAdmission(func(info TaskInfo, _ PoolStats) Decision {
+			if info.Name == "rejected" {
+				return Decision{Action: Reject}
+			}
+			return Decision{Action: Admit}
+		}))
+
+	release := make(chan struct{})
+	wp.Submit(func() {
+		<-release
+		wp.Stop()
+	})
+	wp.SubmitWithMetadata(Metadata{Name: "rejected"}, func() {})
+	wp.SubmitTenant("limited", func() {})
+	wp.SubmitTenant("limited", func() {})
+	ctx, cancel := context.WithCancel(context.Background())
+	wp.SubmitTask(Task{Fn: func(context.Context) {}, Ctx: ctx})
+	cancel()
+	wp.Submit(func() {})
+	for wp.WaitingQueueSize() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The queued tasks are rejected when the pool stops, which the blocking
+	// task does before it returns.
+	close(release)
+	<-wp.Done()
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if reasons[RejectedByAdmission] != 1 || reasons[RejectedByQuota] != 1 {
+		t.Fatal("expected admission and quota rejections, got", reasons)
+	}
+	if reasons[RejectedByStop]+reasons[RejectedByExpiry] != 3 {
+		t.Fatal("expected 3 tasks rejected by stop or expiry, got", reasons)
+	}
+	if RejectedByStop.String() != "RejectedByStop" {
+		t.Fatal("wrong reason name:", RejectedByStop)
+	}
+}
+
+func TestOnRejectExpiredRuns(t *testing.T) {
+	t.Parallel()
+
+	var rejects, runs int32
+	wp := New(1, WithOnReject(func(task func(), reason RejectReason) {
+		if atomic.AddInt32(&rejects, 1) > 10 {
+			t.Error("expired task rejected again")
+			return
+		}
+		task()
+	}))
+	defer wp.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	wp.SubmitTask(Task{Ctx: ctx, Fn: func(ctx context.Context) {
+		if ctx.Err() == nil {
+			atomic.AddInt32(&runs, 1)
+		}
+	}})
+	wp.Wait()
+	if atomic.LoadInt32(&rejects) != 1 || atomic.LoadInt32(&runs) != 1 {
+		t.Fatalf("expected 1 rejection and 1 run, got %d and %d", rejects, runs)
+	}
+}
+
+func TestOnRejectStoppedWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	var rejected []func()
+	wp := New(1, WithOnReject(func(task func(), reason RejectReason) {
+		if reason == RejectedByStop {
+			rejected = append(rejected, task)
+		}
+	}))
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	ran := make(chan struct{}, 2)
+	wp.Submit(func() { ran <- struct{}{} })
+	wp.SubmitWithTimeout(time.Minute, func(context.Context) { ran <- struct{}{} })
+	for wp.WaitingQueueSize() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	wp.Stop()
+
+	// Every dropped task is reported, including a task with a timeout.
+	if n := wp.Stats().DroppedTasks; n != 2 || len(rejected) != 2 {
+		t.Fatal("expected 2 dropped and reported tasks, got", n, len(rejected))
+	}
+	for _, task := range rejected {
+		task()
+	}
+	if len(ran) != 2 {
+		t.Fatal("expected reported tasks to run, got", len(ran))
+	}
+}
@@ -0,0 +1,182 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrSemaphoreWeight is returned by Semaphore.Acquire when the weight is less
+// than one or more than the maximum number of workers.
+var ErrSemaphoreWeight = errors.New("workerpool: semaphore weight out of range")
+
+// Semaphore reserves the workers of a worker pool with the same methods as
+// the Weighted semaphore of golang.org/x/sync/semaphore, so that code written
+// for a Weighted semaphore can use a worker pool instead, and gain its
+// metrics and lifecycle.  Each unit of weight is one worker, reserved using
+// Acquire.
+//
+// As with Weighted, callers acquire in the order that they call Acquire, and a
+// caller acquires all of its weight or none of it.  The order is kept across
+// all of the semaphores of a worker pool.
+type Semaphore struct {
+	pool     *WorkerPool
+	mutex    sync.Mutex
+	releases []func()
+}
+
+// Semaphore returns a Semaphore that reserves the worker pool's workers.
+func (p *WorkerPool) Semaphore() *Semaphore {
+	return &Semaphore{pool: p}
+}
+
+// semaphoreQueue holds the callers of Semaphore.Acquire that are waiting for
+// their turn to reserve workers.  One caller at a time has the turn, so that
+// callers that each hold part of their weight cannot block each other.
+type semaphoreQueue struct {
+	mutex   sync.Mutex
+	busy    bool
+	waiters []chan struct{}
+}
+
+// wait waits for the caller's turn, in the order that callers arrive.
+// Returns the context's error, without the turn, if ctx is done first.
+func (q *semaphoreQueue) wait(ctx context.Context) error {
+	q.mutex.Lock()
+	if !q.busy {
+		q.busy = true
+		q.mutex.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	q.waiters = append(q.waiters, ready)
+	q.mutex.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+	}
+	q.mutex.Lock()
+	for i, w := range q.waiters {
+		if w == ready {
+			q.waiters = append(q.waiters[:i], q.waiters[i+1:]...)
+			q.mutex.Unlock()
+			return ctx.Err()
+		}
+	}
+	q.mutex.Unlock()
+	// The turn was given to this caller as it gave up, so pass it on.
+	q.done()
+	return ctx.Err()
+}
+
+// tryTurn takes the turn if no other caller has it or is waiting for it.
+func (q *semaphoreQueue) tryTurn() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.busy {
+		return false
+	}
+	q.busy = true
+	return true
+}
+
+// done ends the caller's turn, and gives it to the next waiting caller.
+func (q *semaphoreQueue) done() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.waiters) == 0 {
+		q.busy = false
+		return
+	}
+	close(q.waiters[0])
+	q.waiters = q.waiters[1:]
+}
+
+// Acquire reserves n workers, blocking until they are available or ctx is
+// done.  On success, returns nil.  On failure, returns the context's error, or
+// ErrStopped if the worker pool is stopped, and reserves no workers.  Returns
+// ErrSemaphoreWeight if n is less than one or more than the maximum number of
+// workers.
+//
+// The caller whose turn it is reserves each worker as it becomes available, in
+// turn with submitted tasks, and is woken by each worker that is released.
+// Later callers wait for their turn, so that a caller that needs many workers
+// is not starved by callers that need few.
+func (s *Semaphore) Acquire(ctx context.Context, n int64) error {
+	if n < 1 || n > int64(s.pool.maxWorkers) {
+		return ErrSemaphoreWeight
+	}
+	if err := s.pool.semaphores.wait(ctx); err != nil {
+		return err
+	}
+	defer s.pool.semaphores.done()
+
+	releases := make([]func(), 0, n)
+	for int64(len(releases)) < n {
+		release, err := s.pool.Acquire(ctx)
+		if err != nil {
+			for _, release := range releases {
+				release()
+			}
+			return err
+		}
+		releases = append(releases, release)
+	}
+	s.hold(releases)
+	return nil
+}
+
+// TryAcquire reserves n workers without blocking.  On success, returns true.
+// On failure, returns false and reserves no workers.  Fails if another caller
+// is in Acquire, or if n is less than one or more than the maximum number of
+// workers.  A released worker is available to TryAcquire once it has returned
+// to the worker pool, which may be shortly after Release returns.
+func (s *Semaphore) TryAcquire(n int64) bool {
+	if n < 1 || n > int64(s.pool.maxWorkers) {
+		return false
+	}
+	if !s.pool.semaphores.tryTurn() {
+		return false
+	}
+	defer s.pool.semaphores.done()
+
+	releases := make([]func(), 0, n)
+	for int64(len(releases)) < n {
+		release, ok := s.pool.tryAcquire()
+		if !ok {
+			for _, release := range releases {
+				release()
+			}
+			return false
+		}
+		releases = append(releases, release)
+	}
+	s.hold(releases)
+	return true
+}
+
+// hold records reserved workers, to be released by Release.
+func (s *Semaphore) hold(releases []func()) {
+	s.mutex.Lock()
+	s.releases = append(s.releases, releases...)
+	s.mutex.Unlock()
+}
+
+// Release releases n workers reserved by Acquire.  Panics if more workers are
+// released than are reserved, or n is negative.
+func (s *Semaphore) Release(n int64) {
+	s.mutex.Lock()
+	if n < 0 || n > int64(len(s.releases)) {
+		s.mutex.Unlock()
+		panic("workerpool: semaphore released more than held")
+	}
+	i := len(s.releases) - int(n)
+	releases := s.releases[i:]
+	s.releases = s.releases[:i:i]
+	s.mutex.Unlock()
+	for _, release := range releases {
+		release()
+	}
+}
@@ -0,0 +1,153 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSemaphore(t *testing.T) {
+	t.Parallel()
+
+	wp := New(3)
+	defer wp.Stop()
+	sem := wp.Semaphore()
+
+	ctx := context.Background()
+	if err := sem.Acquire(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if wp.BusyWorkers() != 2 {
+		t.Fatal("expected 2 reserved workers, got", wp.BusyWorkers())
+	}
+
+	// Acquiring more than are available fails without reserving any.
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if err := sem.Acquire(timeout, 2); err != context.DeadlineExceeded {
+		t.Fatal("expected deadline exceeded, got", err)
+	}
+	// The worker reserved by the failed acquire is released once it returns
+	// to the worker pool.
+	deadline := time.Now().Add(time.Second)
+	for wp.BusyWorkers() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected failed acquire to release workers, got", wp.BusyWorkers())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := sem.Acquire(ctx, 4); err != ErrSemaphoreWeight {
+		t.Fatal("expected ErrSemaphoreWeight for more than max workers, got", err)
+	}
+	if err := sem.Acquire(ctx, 0); err != ErrSemaphoreWeight {
+		t.Fatal("expected ErrSemaphoreWeight for zero weight, got", err)
+	}
+	if sem.TryAcquire(2) {
+		t.Fatal("expected TryAcquire to fail with 1 worker available")
+	}
+	if sem.TryAcquire(-1) {
+		t.Fatal("expected TryAcquire to fail with negative weight")
+	}
+	// The worker released by the failed TryAcquire is available once it
+	// returns to the worker pool.
+	deadline = time.Now().Add(time.Second)
+	for !sem.TryAcquire(1) {
+		if time.Now().After(deadline) {
+			t.Fatal("expected TryAcquire to reserve the available worker")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sem.Release(1)
+
+	sem.Release(1)
+	if err := sem.Acquire(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	sem.Release(3)
+	if err := sem.Acquire(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	sem.Release(3)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic releasing more than held")
+		}
+	}()
+	sem.Release(1)
+}
+
+func TestSemaphoreSharedPool(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	// Two semaphores that each need all of the workers must not deadlock
+	// holding one worker each.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		sem := wp.Semaphore()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if err := sem.Acquire(ctx, 2); err != nil {
+					errs <- err
+					return
+				}
+				sem.Release(2)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+}
+
+func TestSemaphoreOrder(t *testing.T) {
+	t.Parallel()
+
+	wp := New(3)
+	defer wp.Stop()
+	sem := wp.Semaphore()
+	ctx := context.Background()
+	if err := sem.Acquire(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	// A caller that needs all of the workers is not starved by a later
+	// caller that needs fewer than are available.
+	order := make(chan int, 2)
+	go func() {
+		if err := sem.Acquire(ctx, 3); err != nil {
+			t.Error(err)
+		}
+		order <- 3
+		sem.Release(3)
+	}()
+	for wp.BusyWorkers() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			t.Error(err)
+		}
+		order <- 1
+		sem.Release(1)
+	}()
+	if sem.TryAcquire(1) {
+		t.Fatal("TryAcquire succeeded with callers waiting")
+	}
+	time.Sleep(20 * time.Millisecond)
+	sem.Release(2)
+	if first, second := <-order, <-order; first != 3 || second != 1 {
+		t.Fatal("callers acquired out of order:", first, second)
+	}
+}
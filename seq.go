@@ -0,0 +1,125 @@
+package workerpool
+
+import (
+	"iter"
+	"sync"
+)
+
+// SubmitSeq calls fn for each value produced by seq, using the worker pool to
+// execute the calls concurrently, and returns when all calls have completed.
+// Values are pulled from seq only as workers become available, so no more
+// than the pool's maximum number of workers are busy with calls at once and
+// the sequence is never buffered.  If the worker pool is stopped, no more
+// values are pulled from seq, and calls that were dropped are not made.
+func SubmitSeq[T any](p *WorkerPool, seq iter.Seq[T], fn func(T)) {
+	sem := make(chan struct{}, p.maxWorkers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for v := range seq {
+		sem <- struct{}{}
+		wg.Add(1)
+		done := func() {
+			<-sem
+			wg.Done()
+		}
+		t := p.newTask(func() {
+			defer done()
+			fn(v)
+		})
+		t.dropped = done
+		if p.enqueue(t) != nil {
+			return
+		}
+	}
+}
+
+// Results returns an iterator over the results of calling fn for each value
+// produced by seq, using the worker pool to execute the calls concurrently.
+// Results are yielded in the order that calls complete.
+//
+// Values are pulled from seq by the iterator itself, only when fewer than the
+// pool's maximum number of calls are running or have results waiting to be
+// yielded.  If iteration stops early, or the worker pool is stopped, no more
+// values are pulled from seq, and the iterator returns after calls already in
+// progress complete.  A call that panics has no result.
+func Results[T, R any](p *WorkerPool, seq iter.Seq[T], fn func(T) R) iter.Seq[R] {
+	return func(yield func(R) bool) {
+		next, stop := iter.Pull(seq)
+		defer stop()
+
+		var mutex sync.Mutex
+		ready := sync.NewCond(&mutex)
+		// The results of calls that returned and have not been yielded.
+		var completed []R
+		// The number of calls that are queued, running, or completed.
+		var pending int
+		var stopped bool
+
+		// finish records that a call has returned, with its result unless
+		// it panicked.
+		finish := func(r R, ok bool) {
+			mutex.Lock()
+			if ok {
+				completed = append(completed, r)
+			} else {
+				pending--
+			}
+			mutex.Unlock()
+			ready.Signal()
+		}
+
+		for {
+			mutex.Lock()
+			for !stopped && pending < p.maxWorkers {
+				mutex.Unlock()
+				v, ok := next()
+				mutex.Lock()
+				if !ok {
+					stopped = true
+					break
+				}
+				pending++
+				mutex.Unlock()
+				t := p.newTask(func() {
+					var r R
+					var ok bool
+					defer func() { finish(r, ok) }()
+					r = fn(v)
+					ok = true
+				})
+				t.dropped = func() {
+					mutex.Lock()
+					stopped = true
+					pending--
+					mutex.Unlock()
+					ready.Signal()
+				}
+				p.enqueue(t)
+				mutex.Lock()
+			}
+			for len(completed) == 0 && pending != 0 {
+				ready.Wait()
+			}
+			if len(completed) == 0 {
+				mutex.Unlock()
+				return
+			}
+			r := completed[0]
+			var zero R
+			completed[0] = zero
+			completed = completed[1:]
+			pending--
+			mutex.Unlock()
+
+			if !yield(r) {
+				break
+			}
+		}
+		// Wait for calls in progress to finish.
+		mutex.Lock()
+		for pending != len(completed) {
+			ready.Wait()
+		}
+		mutex.Unlock()
+	}
+}
@@ -0,0 +1,80 @@
+package workerpool
+
+import (
+	"slices"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSubmitSeq(t *testing.T) {
+	t.Parallel()
+
+	wp := New(3)
+	defer wp.Stop()
+
+	var sum int64
+	SubmitSeq(wp, slices.Values([]int{1, 2, 3, 4, 5}), func(n int) {
+		atomic.AddInt64(&sum, int64(n))
+	})
+	if sum != 15 {
+		t.Fatal("not all values processed before return, sum:", sum)
+	}
+}
+
+func TestResults(t *testing.T) {
+	t.Parallel()
+
+	wp := New(3)
+	defer wp.Stop()
+
+	var sum int
+	for r := range Results(wp, slices.Values([]int{1, 2, 3, 4, 5}), func(n int) int {
+		return n * 10
+	}) {
+		sum += r
+	}
+	if sum != 150 {
+		t.Fatal("wrong sum of results:", sum)
+	}
+
+	// Stop early from an infinite sequence.
+	naturals := func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	var count int
+	for range Results(wp, naturals, func(n int) int { return n }) {
+		count++
+		if count == 10 {
+			break
+		}
+	}
+	if count != 10 {
+		t.Fatal("expected to stop after 10 results")
+	}
+}
+
+func TestSeqStopped(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	wp.Stop()
+
+	var called int64
+	SubmitSeq(wp, slices.Values([]int{1, 2, 3}), func(int) {
+		atomic.AddInt64(&called, 1)
+	})
+	var count int
+	for range Results(wp, slices.Values([]int{1, 2, 3}), func(n int) int {
+		atomic.AddInt64(&called, 1)
+		return n
+	}) {
+		count++
+	}
+	if called != 0 || count != 0 {
+		t.Fatal("expected no calls on stopped pool, got", called, "calls")
+	}
+}
@@ -0,0 +1,106 @@
+package workerpool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownTask is returned when decoding a task whose name is not
+// registered.
+var ErrUnknownTask = errors.New("workerpool: unknown task name")
+
+// SerializableTask is a task that can be persisted and reconstructed, so that
+// it can be stored in a durable queue or sent to another process.  A task is
+// identified by its name, which selects the decoder that reconstructs it from
+// its payload.
+type SerializableTask interface {
+	// TaskName returns the name under which the task's decoder is
+	// registered.
+	TaskName() string
+	// Payload encodes the task's arguments.
+	Payload() ([]byte, error)
+	// Run executes the task.  An error means that the task may be retried.
+	Run(ctx context.Context) error
+}
+
+// Decoder reconstructs a task from its payload.
+type Decoder func(payload []byte) (SerializableTask, error)
+
+// TaskRegistry maps task names to the decoders that reconstruct tasks.  The
+// zero value is an empty registry, ready to use.  A TaskRegistry is safe for
+// concurrent use.
+type TaskRegistry struct {
+	mutex    sync.RWMutex
+	decoders map[string]Decoder
+}
+
+// Register sets the decoder for tasks with the given name, replacing any
+// previous decoder for the name.
+func (r *TaskRegistry) Register(name string, decode Decoder) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.decoders == nil {
+		r.decoders = map[string]Decoder{}
+	}
+	r.decoders[name] = decode
+}
+
+// Decode reconstructs a task from its name and payload.  Returns an error
+// wrapping ErrUnknownTask if no decoder is registered for the name.
+func (r *TaskRegistry) Decode(name string, payload []byte) (SerializableTask, error) {
+	r.mutex.RLock()
+	decode := r.decoders[name]
+	r.mutex.RUnlock()
+	if decode == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTask, name)
+	}
+	return decode(payload)
+}
+
+// RegisterJSON registers a handler for tasks, created by NewJSONTask, whose
+// argument is a T encoded as JSON.
+func RegisterJSON[T any](r *TaskRegistry, name string, fn func(context.Context, T) error) {
+	r.Register(name, func(payload []byte) (SerializableTask, error) {
+		var arg T
+		if err := json.Unmarshal(payload, &arg); err != nil {
+			return nil, fmt.Errorf("decoding task %q: %w", name, err)
+		}
+		return &jsonTask[T]{name: name, arg: arg, fn: fn}, nil
+	})
+}
+
+// NewJSONTask creates a task that calls fn with arg, and is encoded as the
+// task's name and arg encoded as JSON.  The handler registered with
+// RegisterJSON for the name is used when the task is decoded.
+func NewJSONTask[T any](name string, arg T, fn func(context.Context, T) error) SerializableTask {
+	return &jsonTask[T]{name: name, arg: arg, fn: fn}
+}
+
+type jsonTask[T any] struct {
+	name string
+	arg  T
+	fn   func(context.Context, T) error
+}
+
+func (t *jsonTask[T]) TaskName() string         { return t.name }
+func (t *jsonTask[T]) Payload() ([]byte, error) { return json.Marshal(t.arg) }
+func (t *jsonTask[T]) Run(ctx context.Context) error {
+	return t.fn(ctx, t.arg)
+}
+
+// SubmitSerializable enqueues a serializable task for a worker to execute.
+// In memory, this is the same as submitting a function that runs the task,
+// and the error returned by the task is ignored.  Keeping the task in its
+// serializable form lets queued tasks be persisted.  As with Submit, the task
+// is given to the admission function, if any.
+func (p *WorkerPool) SubmitSerializable(st SerializableTask) {
+	if st == nil {
+		return
+	}
+	t := p.newTask(func() { st.Run(context.Background()) })
+	t.serializable = st
+	p.enqueueAdmitted(t)
+}
@@ -0,0 +1,69 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestTaskRegistry(t *testing.T) {
+	t.Parallel()
+
+	var reg TaskRegistry
+	got := make(chan string, 1)
+	greet := func(ctx context.Context, name string) error {
+		got <- "hello " + name
+		return nil
+	}
+	RegisterJSON(&reg, "greet", greet)
+
+	orig := NewJSONTask("greet", "gopher", greet)
+	payload, err := orig.Payload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := reg.Decode(orig.TaskName(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wp := New(1)
+	defer wp.Stop()
+	wp.SubmitSerializable(decoded)
+	if s := <-got; s != "hello gopher" {
+		t.Fatal("wrong result:", s)
+	}
+
+	if _, err = reg.Decode("unknown", nil); !errors.Is(err, ErrUnknownTask) {
+		t.Fatal("expected ErrUnknownTask, got", err)
+	}
+	if _, err = reg.Decode("greet", []byte("{")); err == nil {
+		t.Fatal("expected error decoding bad payload")
+	}
+}
+
+func TestSubmitSerializableAdmission(t *testing.T) {
+	t.Parallel()
+
+	var rejected int
+	wp := New(1,
+		WithOnReject(func(_ func(), reason RejectReason) {
+			if reason == RejectedByAdmission {
+				rejected++
+			}
+		}),
+		WithAdmission(func(TaskInfo, PoolStats) Decision {
+			return Decision{Action: Reject}
+		}))
+	defer wp.Stop()
+
+	var ran bool
+	wp.SubmitSerializable(NewJSONTask("run", 1, func(context.Context, int) error {
+		ran = true
+		return nil
+	}))
+	wp.Wait()
+	if ran || rejected != 1 {
+		t.Fatal("expected serializable task to be rejected by admission")
+	}
+}
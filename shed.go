@@ -0,0 +1,90 @@
+package workerpool
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// Shedding configures load shedding, set using WithLoadShedding.
+type Shedding struct {
+	// MaxQueueWait is how long tasks may wait for a worker before the worker
+	// pool is overloaded.
+	MaxQueueWait time.Duration
+	// Fraction is the fraction, from 0 to 1, of low priority tasks that are
+	// rejected while the worker pool is overloaded.
+	Fraction float64
+	// MaxPriority is the highest priority of the tasks that may be rejected.
+	// Tasks with a zero Priority are rejected if MaxPriority is zero or
+	// more.
+	MaxPriority int
+	// Reject, if not nil, is called with each rejected task, from the
+	// goroutine that submitted it.
+	Reject func(task func())
+}
+
+// WithLoadShedding makes the worker pool reject some low priority tasks while
+// it is overloaded, so that the time that other tasks wait for a worker stays
+// bounded.  The worker pool is overloaded when tasks are waiting and the last
+// task given to a worker waited longer than MaxQueueWait.
+//
+// Only tasks submitted using SubmitTask are rejected, since other submit
+// functions have callers that wait for the task to run.  Rejected tasks are
+// not run, and are complete for the purposes of Wait and Flush.
+func WithLoadShedding(shedding Shedding) Option {
+	return func(p *WorkerPool) {
+		if shedding.MaxQueueWait > 0 && shedding.Fraction > 0 {
+			p.shedding = &shedding
+			p.recordEnqueue = true
+		}
+	}
+}
+
+// overloaded returns true if tasks are waiting longer than allowed.
+func (p *WorkerPool) overloaded() bool {
+	if p.slo != nil {
+		return p.slo.missed()
+	}
+	return atomic.LoadInt32(&p.waiting) != 0 &&
+		time.Duration(atomic.LoadInt64(&p.queueWait)) > p.shedding.MaxQueueWait
+}
+
+// shed returns true if a submitted task is rejected by load shedding.
+func (p *WorkerPool) shed(t *task) bool {
+	if !t.sheddable || t.priority > p.shedding.MaxPriority ||
+		rand.Float64() >= p.shedding.Fraction {
+		return false
+	}
+	p.discard(t)
+	p.count(MetricTasksRejected, 1)
+	p.emit(Rejected, t)
+	if p.shedding.Reject != nil {
+		p.shedding.Reject(t.fn)
+	}
+	p.rejected(t.fn, RejectedByShedding)
+	return true
+}
+
+// shedTasks rejects tasks from a submitted task or batch while the worker pool
+// is overloaded.  Returns nil if all tasks are rejected.
+func (p *WorkerPool) shedTasks(t *task) *task {
+	if t.finished != nil || t.dump != nil || !p.overloaded() {
+		return t
+	}
+	if t.batch == nil {
+		if p.shed(t) {
+			return nil
+		}
+		return t
+	}
+	batch := make([]*task, 0, len(t.batch))
+	for _, bt := range t.batch {
+		if !p.shed(bt) {
+			batch = append(batch, bt)
+		}
+	}
+	if len(batch) == 0 {
+		return nil
+	}
+	return &task{batch: batch}
+}
@@ -0,0 +1,56 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoadShedding(t *testing.T) {
+	t.Parallel()
+
+	var rejected int32
+	wp := New(2, WithLoadShedding(Shedding{
+		MaxQueueWait: 10 * time.Millisecond,
+		Fraction:     1,
+		Reject:       func(task func()) { atomic.AddInt32(&rejected, 1) },
+	}))
+	defer wp.Stop()
+
+	var ran int32
+	run := func(ctx context.Context) { atomic.AddInt32(&ran, 1) }
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		wp.Submit(func() { <-release })
+	}
+	wp.SubmitTask(Task{Fn: func(ctx context.Context) { <-release }})
+	time.Sleep(20 * time.Millisecond)
+	wp.SubmitTask(Task{Fn: run, Priority: 1})
+	for wp.WaitingQueueSize() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if rejected != 0 {
+		t.Fatal("tasks rejected before overloaded")
+	}
+
+	// Once a task has waited too long, low priority tasks are rejected.
+	release <- struct{}{}
+	for wp.WaitingQueueSize() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		wp.SubmitTask(Task{Fn: run})
+	}
+	wp.SubmitTask(Task{Fn: run, Priority: 1})
+	wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	if n := atomic.LoadInt32(&rejected); n != 10 {
+		t.Fatal("expected 10 rejected tasks, got", n)
+	}
+
+	close(release)
+	wp.Wait()
+	if ran != 3 {
+		t.Fatal("expected 3 tasks to run, ran", ran)
+	}
+}
@@ -0,0 +1,51 @@
+package workerpool
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// StopOnSignal waits until one of the given signals is received, or ctx is
+// done, and then shuts down the worker pool in two phases.  First, the pool is
+// stopped as with StopWait, executing the tasks that are already queued.  If
+// the pool has not stopped within the grace period, or another signal is
+// received, the queued tasks that have not started are abandoned as with Stop.
+// StopOnSignal returns once the running tasks have completed and the pool has
+// stopped, or immediately if the pool is stopped by other means first.
+//
+// If no signals are given, SIGINT and SIGTERM are used.  StopOnSignal is
+// usually called in its own goroutine, or at the end of main.
+func (p *WorkerPool) StopOnSignal(ctx context.Context, grace time.Duration, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, signals...)
+	defer signal.Stop(sigChan)
+
+	// Stop and wait for this run of the worker pool, even if it is stopped
+	// and rebooted by another goroutine meanwhile.
+	rs := p.runState.Load()
+	stopped := rs.stopped
+	select {
+	case <-sigChan:
+	case <-ctx.Done():
+	case <-stopped:
+		return
+	}
+
+	go p.stop(true, StoppedBySignal)
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-stopped:
+		return
+	case <-sigChan:
+	case <-timer.C:
+	}
+	rs.forceStop()
+	<-stopped
+}
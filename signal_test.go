@@ -0,0 +1,51 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStopOnSignal(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	var ran int32
+	started := make(chan struct{})
+	wp.Submit(func() {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&ran, 1)
+	})
+	<-started
+	for i := 0; i < 10; i++ {
+		wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	wp.StopOnSignal(ctx, 10*time.Millisecond)
+	if !wp.Stopped() {
+		t.Fatal("pool not stopped")
+	}
+	if ran != 1 {
+		t.Fatal("expected queued tasks to be abandoned after grace period, ran", ran)
+	}
+	wp.Wait()
+
+	// Tasks that complete within the grace period are all executed.
+	wp = New(2)
+	for i := 0; i < 10; i++ {
+		wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	wp.StopOnSignal(ctx, time.Second)
+	if ran != 11 {
+		t.Fatal("expected all tasks to run within grace period, ran", ran)
+	}
+
+	// Returns if the pool is stopped.
+	wp = New(1)
+	wp.Stop()
+	wp.StopOnSignal(context.Background(), time.Second)
+}
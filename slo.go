@@ -0,0 +1,157 @@
+package workerpool
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultSLOPercentile is the queue wait percentile of an SLO that does
+	// not set Percentile.
+	defaultSLOPercentile = 0.95
+	// defaultSLOWindow is how often an SLO that does not set Window is
+	// checked.
+	defaultSLOWindow = 100 * time.Millisecond
+)
+
+// SLO is a queue wait target for the worker pool to meet, set using WithSLO.
+type SLO struct {
+	// QueueWait is the target for how long tasks wait between being
+	// submitted and starting.
+	QueueWait time.Duration
+	// Percentile is the fraction, from 0 to 1, of tasks that must start
+	// within QueueWait.  The default is 0.95.
+	Percentile float64
+	// Window is how often the queue wait of the tasks that started since the
+	// last check is compared to the target.  The default is 100ms.
+	Window time.Duration
+	// MinWorkers is the lowest number of tasks that are executed at once.
+	// Values less than 1 are 1.
+	MinWorkers int
+	// ShedFraction is the fraction, from 0 to 1, of low priority tasks that
+	// are rejected while the target cannot be met at the maximum number of
+	// workers.  Zero means no tasks are rejected.
+	ShedFraction float64
+	// ShedMaxPriority is the highest priority of the tasks that may be
+	// rejected.
+	ShedMaxPriority int
+	// Unattainable, if not nil, is called with the observed queue wait
+	// percentile each time the target is missed while the maximum number of
+	// workers are executing tasks.  It is called from a worker, so it must
+	// not block.
+	Unattainable func(queueWait time.Duration)
+}
+
+// WithSLO makes the worker pool scale the number of tasks that it executes at
+// once, between MinWorkers and the maximum number of workers, to keep the
+// queue wait percentile within the target.  The concurrency limit starts at
+// the maximum number of workers, is lowered while the queue wait is well
+// within the target, and is raised when the target is missed.  This keeps
+// concurrency, and the load on the resources that tasks use, no higher than
+// needed to meet the target.
+//
+// When the target is missed at the maximum number of workers, the worker pool
+// reports it using Unattainable, and rejects low priority tasks as configured
+// by ShedFraction and ShedMaxPriority, in the same way as WithLoadShedding.
+// WithSLO replaces any limit set using WithAdaptiveConcurrency.
+func WithSLO(slo SLO) Option {
+	return func(p *WorkerPool) {
+		if slo.QueueWait <= 0 {
+			return
+		}
+		if slo.Percentile <= 0 || slo.Percentile > 1 {
+			slo.Percentile = defaultSLOPercentile
+		}
+		if slo.Window <= 0 {
+			slo.Window = defaultSLOWindow
+		}
+		if slo.MinWorkers < 1 {
+			slo.MinWorkers = 1
+		}
+		p.adaptive = &adaptiveLimit{Adaptive: Adaptive{MinWorkers: slo.MinWorkers}}
+		p.adaptive.cond = sync.NewCond(&p.adaptive.mutex)
+		p.slo = &sloState{SLO: slo, limit: p.adaptive}
+		p.recordEnqueue = true
+		if slo.ShedFraction > 0 {
+			p.shedding = &Shedding{
+				MaxQueueWait: slo.QueueWait,
+				Fraction:     slo.ShedFraction,
+				MaxPriority:  slo.ShedMaxPriority,
+			}
+		}
+	}
+}
+
+// sloState collects queue waits and adjusts the concurrency limit to meet an
+// SLO.
+type sloState struct {
+	SLO
+	limit *adaptiveLimit
+
+	mutex sync.Mutex
+	start time.Time
+	waits []time.Duration
+	// unattainable is 1 while the target is missed at the maximum number of
+	// workers.
+	unattainable int32
+}
+
+// missed returns true if the target was missed at the maximum number of
+// workers when last checked.
+func (s *sloState) missed() bool {
+	return atomic.LoadInt32(&s.unattainable) != 0
+}
+
+// record adds the queue wait of a started task, and adjusts the concurrency
+// limit once per window.
+func (s *sloState) record(wait time.Duration) {
+	now := time.Now()
+	s.mutex.Lock()
+	if s.start.IsZero() {
+		s.start = now
+	}
+	s.waits = append(s.waits, wait)
+	if now.Sub(s.start) < s.Window {
+		s.mutex.Unlock()
+		return
+	}
+	waits := s.waits
+	s.waits = nil
+	s.start = now
+	s.mutex.Unlock()
+
+	sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+	pct := waits[int(math.Ceil(s.Percentile*float64(len(waits))))-1]
+	if !s.adjust(pct) {
+		atomic.StoreInt32(&s.unattainable, 0)
+		return
+	}
+	atomic.StoreInt32(&s.unattainable, 1)
+	if s.Unattainable != nil {
+		s.Unattainable(pct)
+	}
+}
+
+// adjust raises the concurrency limit if the queue wait percentile missed the
+// target, and lowers it if the percentile is under half the target.  Returns
+// true if the target was missed with the limit already at its maximum.
+func (s *sloState) adjust(pct time.Duration) bool {
+	a := s.limit
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	switch {
+	case pct > s.QueueWait:
+		if a.limit >= float64(a.max) {
+			return true
+		}
+		// Raise the limit in proportion to how far the target was missed.
+		a.limit = math.Min(math.Ceil(a.limit*math.Min(2, float64(pct)/float64(s.QueueWait))), float64(a.max))
+		a.cond.Broadcast()
+	case pct < s.QueueWait/2 && a.limit > float64(a.MinWorkers):
+		a.limit--
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSLO(t *testing.T) {
+	t.Parallel()
+
+	var unattainable int32
+	wp := New(4, WithSLO(SLO{
+		QueueWait: 20 * time.Millisecond,
+		Window:    20 * time.Millisecond,
+		Unattainable: func(time.Duration) {
+			atomic.StoreInt32(&unattainable, 1)
+		},
+	}))
+	defer wp.Stop()
+
+	if limit := wp.ConcurrencyLimit(); limit != 4 {
+		t.Fatal("expected limit to start at 4, got", limit)
+	}
+
+	// Tasks that do not wait lower the limit to the minimum.
+	deadline := time.Now().Add(5 * time.Second)
+	for wp.ConcurrencyLimit() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected limit to decrease to 1, got", wp.ConcurrencyLimit())
+		}
+		wp.SubmitWait(func() {})
+		time.Sleep(time.Millisecond)
+	}
+
+	// A backlog raises the limit to the maximum, where the target is missed.
+	for i := 0; i < 200; i++ {
+		wp.Submit(func() { time.Sleep(2 * time.Millisecond) })
+	}
+	wp.WaitContext(context.Background())
+	if limit := wp.ConcurrencyLimit(); limit != 4 {
+		t.Fatal("expected limit to increase to 4, got", limit)
+	}
+	if atomic.LoadInt32(&unattainable) == 0 {
+		t.Fatal("expected SLO to be reported unattainable")
+	}
+}
@@ -0,0 +1,53 @@
+package workerpool
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithSmoothing spreads the start of tasks evenly over time, so that no two
+// tasks start within the interval of each other, as in a leaky bucket.  By
+// default, when tasks arrive after an idle period, all workers start tasks at
+// once, which can overload downstream systems.  With smoothing, a burst of
+// tasks is started at a steady rate, and the workers that are given tasks wait
+// for their turn.  A worker remains busy while it waits.
+func WithSmoothing(interval time.Duration) Option {
+	return func(p *WorkerPool) {
+		if interval > 0 {
+			p.smoothing = interval
+		}
+	}
+}
+
+// smooth reserves the next time that a task may start, and waits until that
+// time.  Returns false if the worker pool was stopped without waiting for
+// queued tasks while waiting.
+func (p *WorkerPool) smooth() bool {
+	now := p.clock.Now().UnixNano()
+	for {
+		next := atomic.LoadInt64(&p.smoothNext)
+		start := next
+		if start < now {
+			start = now
+		}
+		if atomic.CompareAndSwapInt64(&p.smoothNext, next, start+int64(p.smoothing)) {
+			return p.delay(time.Duration(start - now))
+		}
+	}
+}
+
+// delay waits for the duration to pass.  Returns false if the worker pool was
+// stopped without waiting for queued tasks while waiting.
+func (p *WorkerPool) delay(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := p.clock.NewTimer(d)
+	select {
+	case <-timer.C():
+		return true
+	case <-p.runState.Load().abandon:
+		timer.Stop()
+		return false
+	}
+}
@@ -0,0 +1,30 @@
+package workerpool
+
+import (
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSmoothing(t *testing.T) {
+	t.Parallel()
+
+	interval := 10 * time.Millisecond
+	wp := New(4, WithSmoothing(interval))
+	var mutex sync.Mutex
+	var starts []time.Time
+	for i := 0; i < 8; i++ {
+		wp.Submit(func() {
+			mutex.Lock()
+			starts = append(starts, time.Now())
+			mutex.Unlock()
+		})
+	}
+	wp.StopWait()
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	if spread := starts[len(starts)-1].Sub(starts[0]); spread < 6*interval {
+		t.Fatal("task starts not spread over time:", spread)
+	}
+}
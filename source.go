@@ -0,0 +1,114 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Source is an external source of tasks, such as a message queue consumer,
+// that a worker pool pulls tasks from using Consume.
+type Source interface {
+	// Next returns the next task, waiting until one is available or ctx is
+	// done.  Returns io.EOF when there are no more tasks.  A nil task with a
+	// nil error is ignored.
+	Next(ctx context.Context) (func(), error)
+}
+
+// SourceFunc adapts a function to the Source interface.
+type SourceFunc func(ctx context.Context) (func(), error)
+
+// Next calls f(ctx).
+func (f SourceFunc) Next(ctx context.Context) (func(), error) {
+	return f(ctx)
+}
+
+// ChanSource returns a Source that receives tasks from a channel.  The source
+// returns io.EOF once the channel is closed and empty.
+func ChanSource(ch <-chan func()) Source {
+	return SourceFunc(func(ctx context.Context) (func(), error) {
+		select {
+		case task, ok := <-ch:
+			if !ok {
+				return nil, io.EOF
+			}
+			return task, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+}
+
+// WithPrefetch lets Consume and Pipe pull up to n tasks from a source ahead of
+// the workers that are available to run them.  The prefetched tasks wait in
+// the pool's queue, so that a worker that completes a task starts the next one
+// without waiting for the source.  By default, no tasks are prefetched.
+func WithPrefetch(n int) Option {
+	return func(p *WorkerPool) {
+		if n > 0 {
+			p.prefetch = n
+		}
+	}
+}
+
+// Consume pulls tasks from the source and submits them to the worker pool,
+// until the source returns an error or ctx is done.  A task is only pulled
+// when fewer than the pool's maximum number of workers are busy with tasks
+// from the source, so that the source is not drained into the pool's queue,
+// and a message queue consumer gets natural backpressure.  WithPrefetch allows
+// a limited number of tasks to be pulled ahead.
+//
+// Consume returns after all of the tasks it submitted have completed.  Returns
+// nil if the source returned io.EOF, ErrStopped if the worker pool was
+// stopped, or otherwise the error from the source or ctx.
+func (p *WorkerPool) Consume(ctx context.Context, src Source) error {
+	p.checkReentrant("Consume")
+	sem := make(chan struct{}, p.maxWorkers+p.prefetch)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		task, err := src.Next(ctx)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if task == nil {
+			<-sem
+			continue
+		}
+		wg.Add(1)
+		done := func() {
+			<-sem
+			wg.Done()
+		}
+		t := p.newTask(func() {
+			defer done()
+			task()
+		})
+		t.dropped = done
+		if err = p.enqueue(t); err != nil {
+			return err
+		}
+	}
+}
+
+// Pipe submits the tasks received from the channel to the worker pool, until
+// the channel is closed or ctx is done, in the same way as Consume with a
+// ChanSource.  Pipe returns immediately, and the returned channel is closed
+// once piping has stopped and all of the piped tasks have completed.
+func (p *WorkerPool) Pipe(ctx context.Context, tasks <-chan func()) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Consume(ctx, ChanSource(tasks))
+	}()
+	return done
+}
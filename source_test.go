@@ -0,0 +1,136 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConsume(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	ch := make(chan func())
+	var running, maxRunning, ran int32
+	go func() {
+		for i := 0; i < 10; i++ {
+			ch <- func() {
+				n := atomic.AddInt32(&running, 1)
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				atomic.AddInt32(&ran, 1)
+			}
+		}
+		close(ch)
+	}()
+	if err := wp.Consume(context.Background(), ChanSource(ch)); err != nil {
+		t.Fatal(err)
+	}
+	if ran != 10 {
+		t.Fatal("expected 10 tasks to complete, got", ran)
+	}
+	if maxRunning > 2 {
+		t.Fatal("pulled more tasks than workers:", maxRunning)
+	}
+}
+
+func TestConsumeError(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	testErr := errors.New("source failed")
+	var n int
+	src := SourceFunc(func(ctx context.Context) (func(), error) {
+		if n++; n > 3 {
+			return nil, testErr
+		}
+		return func() {}, nil
+	})
+	if err := wp.Consume(context.Background(), src); err != testErr {
+		t.Fatal("expected source error, got", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := wp.Consume(ctx, ChanSource(make(chan func()))); err != context.Canceled {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+}
+
+func TestPipe(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	ch := make(chan func())
+	var ran int32
+	done := wp.Pipe(context.Background(), ch)
+	for i := 0; i < 10; i++ {
+		ch <- func() { atomic.AddInt32(&ran, 1) }
+	}
+	close(ch)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipe not done after channel closed")
+	}
+	if n := atomic.LoadInt32(&ran); n != 10 {
+		t.Fatal("expected 10 tasks to complete, got", n)
+	}
+
+	// Piping stops when the context is canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	done = wp.Pipe(ctx, make(chan func()))
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pipe not done after context canceled")
+	}
+}
+
+func TestPrefetch(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithPrefetch(2))
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	var pulled int32
+	src := SourceFunc(func(ctx context.Context) (func(), error) {
+		atomic.AddInt32(&pulled, 1)
+		return func() { <-release }, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		wp.Consume(ctx, src)
+		close(done)
+	}()
+
+	// One task runs and two more are prefetched before the consumer blocks.
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&pulled) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&pulled); n != 3 {
+		t.Fatal("expected 3 tasks pulled, got", n)
+	}
+	cancel()
+	close(release)
+	<-done
+}
@@ -0,0 +1,321 @@
+package workerpool
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// WithSpill bounds the memory used by a large backlog of waiting tasks.  When
+// threshold or more tasks are waiting for a worker, additional tasks that were
+// submitted using SubmitSerializable are written to a temporary file in dir,
+// instead of being held in memory.  As workers take tasks from the waiting
+// queue, spilled tasks are read back and decoded using the registry.  If dir
+// is empty, the default directory for temporary files is used.
+//
+// Tasks that are not serializable, and tasks of tenants with quotas, are
+// never spilled, and may run before spilled tasks that were submitted
+// earlier.  If writing a task fails, the task is kept in memory.  A spilled
+// task keeps its ID, tenant, priority, and metadata.
+//
+// The file is written and read by the dispatcher, so a slow disk delays the
+// dispatch of every task.  To bound the delay, each submitted task is written
+// at most once, and at most spillReadMax tasks are read back each time a
+// worker takes tasks from the waiting queue.
+func WithSpill(threshold int, dir string, registry *TaskRegistry) Option {
+	return func(p *WorkerPool) {
+		if threshold < 1 {
+			threshold = 1
+		}
+		p.spill = &spillQueue{
+			threshold: threshold,
+			dir:       dir,
+			registry:  registry,
+			epochs:    map[uint64]int{},
+		}
+	}
+}
+
+// spillQueue is a FIFO queue of serialized tasks stored in a temporary file.
+// Records are appended to the end of the file and read from the front, and
+// the file is truncated whenever the queue becomes empty.  The epochs of the
+// spilled tasks are kept in memory, so that the tasks can be recorded as done
+// if they cannot be read back.
+//
+// The spillQueue is only accessed by the dispatcher.
+type spillQueue struct {
+	threshold int
+	dir       string
+	registry  *TaskRegistry
+	file      *os.File
+	readOff   int64
+	writeOff  int64
+	count     int
+	epochs    map[uint64]int
+	buf       []byte
+}
+
+// errSpillCorrupt is returned when a spilled record cannot be parsed.
+var errSpillCorrupt = errors.New("workerpool: corrupt spill record")
+
+// spillReadMax is the most tasks that unspill reads back at once.
+const spillReadMax = 32
+
+// spillable returns true if the task can be written to the spill file.  Only
+// a serializable task without a quota or callbacks, which cannot be written,
+// is spilled.
+func (t *task) spillable() bool {
+	return t.serializable != nil && t.quota == nil && t.errDone == nil &&
+		t.dropped == nil && t.needed == nil && t.progress == nil
+}
+
+// push writes a task to the end of the queue.  Returns false if the task
+// could not be written.
+func (s *spillQueue) push(t *task) bool {
+	payload, err := t.serializable.Payload()
+	if err != nil {
+		return false
+	}
+	if s.file == nil {
+		if s.file, err = os.CreateTemp(s.dir, "workerpool-spill-*"); err != nil {
+			return false
+		}
+	}
+	rec := append(s.buf[:0], 0, 0, 0, 0)
+	rec = binary.AppendUvarint(rec, t.epoch)
+	rec = binary.AppendUvarint(rec, uint64(t.id))
+	rec = binary.AppendVarint(rec, int64(t.priority))
+	rec = binary.AppendVarint(rec, t.enqueued.UnixNano())
+	rec = appendBytes(rec, []byte(t.tenant))
+	var meta Metadata
+	if t.meta != nil {
+		meta = *t.meta
+	}
+	rec = appendBytes(rec, []byte(meta.Name))
+	rec = binary.AppendUvarint(rec, uint64(len(meta.Labels)))
+	for k, v := range meta.Labels {
+		rec = appendBytes(rec, []byte(k))
+		rec = appendBytes(rec, []byte(v))
+	}
+	rec = appendBytes(rec, []byte(t.serializable.TaskName()))
+	rec = appendBytes(rec, payload)
+	binary.LittleEndian.PutUint32(rec, uint32(len(rec)-4))
+	s.buf = rec
+	if _, err = s.file.WriteAt(rec, s.writeOff); err != nil {
+		return false
+	}
+	s.writeOff += int64(len(rec))
+	s.count++
+	s.epochs[t.epoch]++
+	return true
+}
+
+// pop reads the task at the front of the queue, and reconstructs it using the
+// registry.  If the record is read but cannot be decoded, the task is returned
+// along with the error, without a function, so that it can be recorded as
+// done.  Any other error means the remaining records cannot be read.
+func (s *spillQueue) pop() (*task, error) {
+	var hdr [4]byte
+	if _, err := s.file.ReadAt(hdr[:], s.readOff); err != nil {
+		return nil, err
+	}
+	rec := make([]byte, binary.LittleEndian.Uint32(hdr[:]))
+	if _, err := s.file.ReadAt(rec, s.readOff+4); err != nil {
+		return nil, err
+	}
+	t, rec, ok := readTask(rec)
+	if !ok {
+		return nil, errSpillCorrupt
+	}
+	var name, payload []byte
+	if name, rec, ok = readBytes(rec); !ok {
+		return nil, errSpillCorrupt
+	}
+	if payload, _, ok = readBytes(rec); !ok {
+		return nil, errSpillCorrupt
+	}
+
+	s.readOff += 4 + int64(binary.LittleEndian.Uint32(hdr[:]))
+	s.count--
+	if s.epochs[t.epoch]--; s.epochs[t.epoch] == 0 {
+		delete(s.epochs, t.epoch)
+	}
+	if s.count == 0 {
+		s.reset()
+	}
+
+	st, err := s.registry.Decode(string(name), payload)
+	if err != nil {
+		return t, err
+	}
+	t.fn = func() { st.Run(context.Background()) }
+	t.serializable = st
+	return t, nil
+}
+
+// readTask reads the fields of a spilled task that precede its name and
+// payload.
+func readTask(rec []byte) (*task, []byte, bool) {
+	t := &task{}
+	var n int
+	if t.epoch, n = binary.Uvarint(rec); n <= 0 {
+		return nil, nil, false
+	}
+	rec = rec[n:]
+	id, n := binary.Uvarint(rec)
+	if n <= 0 {
+		return nil, nil, false
+	}
+	t.id = TaskID(id)
+	rec = rec[n:]
+	priority, n := binary.Varint(rec)
+	if n <= 0 {
+		return nil, nil, false
+	}
+	t.priority = int(priority)
+	rec = rec[n:]
+	enqueued, n := binary.Varint(rec)
+	if n <= 0 {
+		return nil, nil, false
+	}
+	if enqueued > 0 {
+		t.enqueued = time.Unix(0, enqueued)
+	}
+	rec = rec[n:]
+	tenant, rec, ok := readBytes(rec)
+	if !ok {
+		return nil, nil, false
+	}
+	t.tenant = string(tenant)
+	name, rec, ok := readBytes(rec)
+	if !ok {
+		return nil, nil, false
+	}
+	nlabels, n := binary.Uvarint(rec)
+	if n <= 0 || nlabels > uint64(len(rec)) {
+		return nil, nil, false
+	}
+	rec = rec[n:]
+	var labels map[string]string
+	for i := uint64(0); i < nlabels; i++ {
+		var k, v []byte
+		if k, rec, ok = readBytes(rec); !ok {
+			return nil, nil, false
+		}
+		if v, rec, ok = readBytes(rec); !ok {
+			return nil, nil, false
+		}
+		if labels == nil {
+			labels = make(map[string]string, nlabels)
+		}
+		labels[string(k)] = string(v)
+	}
+	if len(name) != 0 || labels != nil {
+		t.meta = &Metadata{Name: string(name), Labels: labels}
+	}
+	return t, rec, true
+}
+
+// drop empties the queue, and calls fn with a task for each spilled task, for
+// recording that the tasks are done.
+func (s *spillQueue) drop(fn func(*task)) {
+	for epoch, n := range s.epochs {
+		for i := 0; i < n; i++ {
+			fn(&task{epoch: epoch})
+		}
+	}
+	s.epochs = map[uint64]int{}
+	s.count = 0
+	s.reset()
+}
+
+// reset truncates the file when the queue is empty.
+func (s *spillQueue) reset() {
+	s.readOff = 0
+	s.writeOff = 0
+	if s.file != nil {
+		s.file.Truncate(0)
+	}
+}
+
+// close removes the file.  The queue must be empty.
+func (s *spillQueue) close() {
+	if s.file != nil {
+		s.file.Close()
+		os.Remove(s.file.Name())
+		s.file = nil
+	}
+}
+
+func appendBytes(b, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func readBytes(b []byte) (v, rest []byte, ok bool) {
+	n, size := binary.Uvarint(b)
+	if size <= 0 || uint64(len(b)-size) < n {
+		return nil, nil, false
+	}
+	b = b[size:]
+	return b[:n], b[n:], true
+}
+
+// queueTask adds a task to the waiting queue, or spills it to disk if enough
+// tasks are already waiting.  A task that must not wait is refused.
+func (p *WorkerPool) queueTask(t *task) {
+	if t.refused != nil {
+		if p.discard(t) {
+			t.refused()
+		}
+		return
+	}
+	if s := p.spill; s != nil && t.spillable() &&
+		p.waitingQueue.runnable() >= s.threshold && s.push(t) {
+		atomic.AddInt64(&p.spilledTasks, 1)
+		return
+	}
+	p.waitingQueue.push(t)
+}
+
+// unspill reads spilled tasks back into the waiting queue, until the queue is
+// at the spill threshold, there are no more spilled tasks, or spillReadMax
+// tasks have been read.  Tasks that
+// cannot be read back are dropped.
+func (p *WorkerPool) unspill() {
+	s := p.spill
+	if s == nil {
+		return
+	}
+	for i := 0; i < spillReadMax && s.count != 0 &&
+		p.waitingQueue.runnable() < s.threshold; i++ {
+		t, err := s.pop()
+		if err == nil {
+			p.taskIndex.restore(t)
+			p.waitingQueue.push(t)
+			continue
+		}
+		if t != nil {
+			atomic.AddInt64(&p.spillErrors, 1)
+			p.drop(t)
+			continue
+		}
+		// The rest of the file is unreadable.
+		s.drop(func(t *task) {
+			atomic.AddInt64(&p.spillErrors, 1)
+			p.drop(t)
+		})
+	}
+}
+
+// waitingLen returns the number of waiting tasks, including spilled tasks.
+func (p *WorkerPool) waitingLen() int {
+	n := p.waitingQueue.len()
+	if p.spill != nil {
+		n += p.spill.count
+	}
+	return n
+}
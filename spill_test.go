@@ -0,0 +1,85 @@
+package workerpool
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSpill(t *testing.T) {
+	t.Parallel()
+
+	var mutex sync.Mutex
+	var order []int
+	record := func(ctx context.Context, n int) error {
+		mutex.Lock()
+		order = append(order, n)
+		mutex.Unlock()
+		return nil
+	}
+	var reg TaskRegistry
+	RegisterJSON(&reg, "record", record)
+
+	dir := t.TempDir()
+	wp := New(1, WithSpill(2, dir, &reg), WithTaskIndex(20))
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	wp.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+	for i := 0; i < 10; i++ {
+		wp.SubmitSerializable(NewJSONTask("record", i, record))
+	}
+	deadline := time.Now().Add(time.Second)
+	for wp.WaitingQueueSize() != 10 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected 10 waiting tasks, have", wp.WaitingQueueSize())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := wp.Stats().SpilledTasks; n != 8 {
+		t.Fatal("expected 8 spilled tasks, got", n)
+	}
+
+	close(release)
+	wp.Wait()
+	if len(order) != 10 {
+		t.Fatal("expected 10 tasks to run, got", len(order))
+	}
+	for i := range order {
+		if order[i] != i {
+			t.Fatal("spilled tasks run out of order:", order)
+		}
+	}
+	if wp.Stats().SpillErrors != 0 {
+		t.Fatal("unexpected spill errors")
+	}
+	// Spilled tasks keep their IDs, so their status is recorded.
+	for id := TaskID(2); id <= 11; id++ {
+		if status, ok := wp.Status(id); !ok || status.State != StateDone {
+			t.Fatal("expected task", id, "to be done, got", status.State)
+		}
+	}
+
+	// Stopping abandons the spilled tasks and removes the spill file.
+	started = make(chan struct{})
+	wp.Submit(func() {
+		close(started)
+		time.Sleep(10 * time.Millisecond)
+	})
+	<-started
+	for i := 0; i < 10; i++ {
+		wp.SubmitSerializable(NewJSONTask("record", i, record))
+	}
+	wp.Stop()
+	wp.Wait()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatal("spill file not removed")
+	}
+}
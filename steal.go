@@ -0,0 +1,122 @@
+package workerpool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// workQueue holds the tasks of a batch that a worker has not yet started.
+// While the worker executes one task from its batch, other workers that are
+// idle can steal the remaining tasks, so that short tasks do not wait behind
+// a long task that was given to the same worker.
+type workQueue struct {
+	mutex sync.Mutex
+	tasks []*task
+}
+
+// takeAll removes all remaining tasks from the queue.
+func (wq *workQueue) takeAll() []*task {
+	wq.mutex.Lock()
+	defer wq.mutex.Unlock()
+	tasks := wq.tasks
+	wq.tasks = nil
+	return tasks
+}
+
+// pop removes the next task from the front of the queue, or returns nil if
+// the queue is empty.
+func (wq *workQueue) pop() *task {
+	wq.mutex.Lock()
+	defer wq.mutex.Unlock()
+	if len(wq.tasks) == 0 {
+		return nil
+	}
+	t := wq.tasks[0]
+	wq.tasks[0] = nil
+	wq.tasks = wq.tasks[1:]
+	return t
+}
+
+// runBatch executes the tasks in a batch.  The tasks are kept in a work queue
+// that other workers can steal from until they are started.  If the worker
+// pool is stopped without waiting for queued tasks, then the tasks in the
+// batch that have not started are abandoned.  Returns true if the worker was
+// abandoned while running a task.
+func (p *WorkerPool) runBatch(ws *workerState, batch []*task) bool {
+	wq := &workQueue{tasks: batch}
+	p.stealMutex.Lock()
+	p.workQueues[wq] = struct{}{}
+	atomic.AddInt32(&p.stealable, 1)
+	p.stealMutex.Unlock()
+	ws.wq.Store(wq)
+	abandon := p.runState.Load().abandon
+
+	defer func() {
+		ws.wq.Store(nil)
+		p.stealMutex.Lock()
+		delete(p.workQueues, wq)
+		atomic.AddInt32(&p.stealable, -1)
+		p.stealMutex.Unlock()
+	}()
+
+	for t := wq.pop(); t != nil; t = wq.pop() {
+		select {
+		case <-abandon:
+			p.abandon(t)
+			for t = wq.pop(); t != nil; t = wq.pop() {
+				p.abandon(t)
+			}
+			return false
+		default:
+		}
+		if p.runTask(ws, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// steal takes the back half of the remaining tasks from the work queue with
+// the most remaining tasks.  Returns nil if there are no tasks to steal, or if
+// the worker pool has been stopped without waiting for queued tasks.
+func (p *WorkerPool) steal() []*task {
+	if atomic.LoadInt32(&p.stealable) == 0 {
+		return nil
+	}
+	select {
+	case <-p.runState.Load().abandon:
+		return nil
+	default:
+	}
+
+	p.stealMutex.Lock()
+	defer p.stealMutex.Unlock()
+	var victim *workQueue
+	var most int
+	for wq := range p.workQueues {
+		wq.mutex.Lock()
+		n := len(wq.tasks)
+		wq.mutex.Unlock()
+		if n > most {
+			victim, most = wq, n
+		}
+	}
+	if victim == nil {
+		return nil
+	}
+
+	victim.mutex.Lock()
+	defer victim.mutex.Unlock()
+	remaining := len(victim.tasks)
+	if remaining == 0 {
+		return nil
+	}
+	n := (remaining + 1) / 2
+	stolen := make([]*task, n)
+	copy(stolen, victim.tasks[remaining-n:])
+	for i := remaining - n; i < remaining; i++ {
+		victim.tasks[i] = nil
+	}
+	victim.tasks = victim.tasks[:remaining-n]
+	return stolen
+}
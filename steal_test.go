@@ -0,0 +1,48 @@
+package workerpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkStealing(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	// Keep both workers busy so that the following tasks are queued and then
+	// given to the workers in batches.
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		wp.Submit(func() { <-release })
+	}
+	longDone := make(chan struct{})
+	wp.Submit(func() {
+		time.Sleep(500 * time.Millisecond)
+		close(longDone)
+	})
+	var quick int32
+	for i := 0; i < 7; i++ {
+		wp.Submit(func() { atomic.AddInt32(&quick, 1) })
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	// Quick tasks batched behind the long task are stolen by the other
+	// worker, so they all complete while the long task is still running.
+	deadline := time.After(250 * time.Millisecond)
+	for atomic.LoadInt32(&quick) != 7 {
+		select {
+		case <-deadline:
+			t.Fatal("quick tasks waited behind long task, completed", atomic.LoadInt32(&quick))
+		case <-time.After(time.Millisecond):
+		}
+	}
+	select {
+	case <-longDone:
+		t.Fatal("long task should still be running")
+	default:
+	}
+}
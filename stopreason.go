@@ -0,0 +1,110 @@
+package workerpool
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// StopReason identifies how a worker pool was stopped.
+type StopReason int
+
+const (
+	// NotStopped is the reason of a worker pool that has not stopped.
+	NotStopped StopReason = iota
+	// StoppedByStop is the reason when Stop was called.
+	StoppedByStop
+	// StoppedByStopWait is the reason when StopWait was called.
+	StoppedByStopWait
+	// StoppedBySignal is the reason when StopOnSignal stopped the pool.
+	StoppedBySignal
+	// StoppedByCheckpoint is the reason when Checkpoint was called.
+	StoppedByCheckpoint
+	// StoppedByParent is the reason for a child pool that was stopped by
+	// stopping its parent.
+	StoppedByParent
+	// StoppedBySuspend is the reason when Suspend was called.
+	StoppedBySuspend
+	// StoppedByRelease is the reason when Release was called.
+	StoppedByRelease
+)
+
+var stopReasonNames = [...]string{
+	NotStopped:          "NotStopped",
+	StoppedByStop:       "StoppedByStop",
+	StoppedByStopWait:   "StoppedByStopWait",
+	StoppedBySignal:     "StoppedBySignal",
+	StoppedByCheckpoint: "StoppedByCheckpoint",
+	StoppedByParent:     "StoppedByParent",
+	StoppedBySuspend:    "StoppedBySuspend",
+	StoppedByRelease:    "StoppedByRelease",
+}
+
+func (r StopReason) String() string {
+	if r < 0 || int(r) >= len(stopReasonNames) {
+		return "StopReason(" + strconv.Itoa(int(r)) + ")"
+	}
+	return stopReasonNames[r]
+}
+
+// StopResult describes how a worker pool stopped, for auditing shutdowns.
+type StopResult struct {
+	// Reason is how the worker pool was stopped.
+	Reason StopReason
+	// Ran is the number of tasks that finished running while the worker
+	// pool was stopping, including the tasks that were running when it was
+	// told to stop.  Tasks that were canceled, skipped, or expired did not
+	// run.
+	Ran int
+	// Dropped is the number of queued tasks that were not run.  Tasks saved
+	// by Checkpoint are not dropped.
+	Dropped int
+	// Forced is true if the worker pool was stopped while waiting for its
+	// queued tasks, such as when the grace period of StopOnSignal ended, so
+	// that the remaining queued tasks were dropped.
+	Forced bool
+}
+
+// StopResult returns how the worker pool stopped.  The result has the reason
+// NotStopped until the worker pool has stopped and the channel returned by
+// Done is closed.
+func (p *WorkerPool) StopResult() StopResult {
+	if r := p.stopResult.Load(); r != nil {
+		return *r
+	}
+	return StopResult{}
+}
+
+// StopReason returns how the worker pool was stopped, or NotStopped if it has
+// not stopped.  This is the Reason of StopResult.
+func (p *WorkerPool) StopReason() StopReason {
+	return p.StopResult().Reason
+}
+
+// beginStopResult records the reason and the current counters when the worker
+// pool is told to stop.  Must be called with stopMutex held.
+func (p *WorkerPool) beginStopResult(reason StopReason) {
+	p.stopReason = reason
+	p.stopRan = atomic.LoadInt64(&p.ranTasks)
+	p.stopDropped = atomic.LoadInt64(&p.droppedTasks)
+}
+
+// endStopResult records the result once the dispatcher has stopped all
+// workers.
+func (p *WorkerPool) endStopResult() {
+	ran := atomic.LoadInt64(&p.ranTasks) - p.stopRan
+	dropped := atomic.LoadInt64(&p.droppedTasks) - p.stopDropped
+	var forced bool
+	if p.stopWait {
+		select {
+		case <-p.runState.Load().abandon:
+			forced = true
+		default:
+		}
+	}
+	p.stopResult.Store(&StopResult{
+		Reason:  p.stopReason,
+		Ran:     int(ran),
+		Dropped: int(dropped),
+		Forced:  forced,
+	})
+}
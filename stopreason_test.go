@@ -0,0 +1,53 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestStopResult(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	if r := wp.StopReason(); r != NotStopped {
+		t.Fatal("expected NotStopped, got", r)
+	}
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	for i := 0; i < 3; i++ {
+		wp.Submit(func() {})
+	}
+	for wp.WaitingQueueSize() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	wp.Stop()
+	want := StopResult{Reason: StoppedByStop, Ran: 1, Dropped: 3}
+	if r := wp.StopResult(); r != want {
+		t.Fatalf("expected %+v, got %+v", want, r)
+	}
+	if s := wp.StopReason().String(); s != "StoppedByStop" {
+		t.Fatal("wrong reason name:", s)
+	}
+
+	wp.Reboot()
+	if r := wp.StopReason(); r != NotStopped {
+		t.Fatal("expected NotStopped after Reboot, got", r)
+	}
+	for i := 0; i < 3; i++ {
+		wp.Submit(func() {})
+	}
+	// An expired task does not count as run.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	wp.SubmitTask(Task{Ctx: ctx, Fn: func(context.Context) {}})
+	wp.StopWait()
+	want = StopResult{Reason: StoppedByStopWait, Ran: 3}
+	if r := wp.StopResult(); r != want {
+		t.Fatalf("expected %+v, got %+v", want, r)
+	}
+}
@@ -0,0 +1,61 @@
+package workerpool
+
+import (
+	"context"
+	"sync"
+)
+
+// Process reads items from the in channel and calls fn for each item, using
+// the worker pool to execute the calls concurrently.  Results are written to
+// the returned channel in the order that calls complete.
+//
+// No more than the pool's maximum number of workers are used at once, and
+// items are only read from in when there is capacity to process them.  A
+// worker is held until its result is read from the output channel, so a slow
+// reader applies backpressure to the input.
+//
+// The output channel is closed after in is closed and all results are
+// written.  If ctx is canceled, Process stops reading input and the output
+// channel is closed once calls in progress have completed; their results are
+// discarded if not read.
+func Process[T, R any](ctx context.Context, p *WorkerPool, in <-chan T, fn func(T) R) <-chan R {
+	out := make(chan R)
+	sem := make(chan struct{}, p.maxWorkers)
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(out)
+		}()
+		for {
+			var item T
+			var ok bool
+			select {
+			case item, ok = <-in:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			wg.Add(1)
+			p.submit(func() {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+				r := fn(item)
+				select {
+				case out <- r:
+				case <-ctx.Done():
+				}
+			})
+		}
+	}()
+	return out
+}
@@ -0,0 +1,76 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProcess(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.Stop()
+
+	in := make(chan int)
+	go func() {
+		for i := 1; i <= 100; i++ {
+			in <- i
+		}
+		close(in)
+	}()
+
+	var running, maxRunning int32
+	out := Process(context.Background(), wp, in, func(n int) int {
+		r := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if r <= m || atomic.CompareAndSwapInt32(&maxRunning, m, r) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return n * n
+	})
+
+	var sum, count int
+	for r := range out {
+		sum += r
+		count++
+	}
+	if count != 100 {
+		t.Fatal("expected 100 results, got", count)
+	}
+	if sum != 338350 {
+		t.Fatal("wrong sum of results:", sum)
+	}
+	if maxRunning > 4 {
+		t.Fatal("exceeded pool concurrency:", maxRunning)
+	}
+}
+
+func TestProcessCancel(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	in := make(chan int)
+	out := Process(ctx, wp, in, func(n int) int { return n })
+
+	in <- 1
+	<-out
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		if ok {
+			t.Fatal("expected no more results")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("output not closed after cancel")
+	}
+}
@@ -0,0 +1,104 @@
+package workerpool
+
+import (
+	"sync"
+
+	"github.com/gammazero/deque"
+)
+
+// Limited is a view of a worker pool whose tasks are capped at a number of
+// concurrent tasks among themselves, while still running on, and counting
+// against, the worker pool's workers.  This lets a library that is given a
+// shared worker pool promise to use no more than some of its workers.
+//
+// Tasks submitted through the view beyond its limit wait in the view, without
+// occupying workers, and are pending for the worker pool's Wait and Flush.
+// They are always admitted by the worker pool.  A view should not be used
+// after its worker pool is stopped, since tasks that the worker pool drops do
+// not make room for the view's waiting tasks.
+type Limited struct {
+	pool    *WorkerPool
+	limit   int
+	mutex   sync.Mutex
+	running int
+	waiting deque.Deque
+}
+
+// WithLimit returns a view of the worker pool that runs at most n of the tasks
+// submitted through it at once.  There must be at least one.
+func (p *WorkerPool) WithLimit(n int) *Limited {
+	if n < 1 {
+		n = 1
+	}
+	return &Limited{pool: p, limit: n}
+}
+
+// Submit enqueues a function for a worker to execute, once fewer than the
+// view's limit of its tasks are running.
+func (l *Limited) Submit(task func()) {
+	if task == nil {
+		return
+	}
+	t := l.pool.newTask(nil)
+	t.fn = func() {
+		defer l.release()
+		task()
+	}
+	l.mutex.Lock()
+	if l.running < l.limit {
+		l.running++
+		l.mutex.Unlock()
+		l.pool.enqueue(t)
+		return
+	}
+	l.waiting.PushBack(t)
+	l.mutex.Unlock()
+}
+
+// SubmitWait enqueues the given function, the same as Submit, and waits for it
+// to be executed.
+func (l *Limited) SubmitWait(task func()) {
+	if task == nil {
+		return
+	}
+	if l.pool.nested("SubmitWait") {
+		task()
+		return
+	}
+	doneChan := make(chan struct{})
+	l.Submit(func() {
+		defer close(doneChan)
+		task()
+	})
+	<-doneChan
+}
+
+// Running returns the number of the view's tasks that are given to the worker
+// pool, up to the view's limit.
+func (l *Limited) Running() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.running
+}
+
+// Waiting returns the number of the view's tasks that are waiting for one of
+// its running tasks to complete.
+func (l *Limited) Waiting() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.waiting.Len()
+}
+
+// release gives the worker pool the next waiting task when one of the view's
+// tasks completes.
+func (l *Limited) release() {
+	l.mutex.Lock()
+	if l.waiting.Len() == 0 {
+		l.running--
+		l.mutex.Unlock()
+		return
+	}
+	t := l.waiting.PopFront().(*task)
+	l.mutex.Unlock()
+	l.pool.enqueue(t)
+}
@@ -0,0 +1,55 @@
+package workerpool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithLimit(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.Stop()
+	view := wp.WithLimit(2)
+
+	var running, maxRunning int32
+	for i := 0; i < 10; i++ {
+		view.Submit(func() {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				max := atomic.LoadInt32(&maxRunning)
+				if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+	}
+	if view.Running() != 2 || view.Waiting() != 8 {
+		t.Fatal("expected 2 running and 8 waiting, got", view.Running(), view.Waiting())
+	}
+
+	// Other tasks use the rest of the workers.
+	other := make(chan struct{})
+	wp.Submit(func() { close(other) })
+	select {
+	case <-other:
+	case <-time.After(5 * time.Second):
+		t.Fatal("view's tasks blocked other tasks")
+	}
+
+	var ran bool
+	view.SubmitWait(func() { ran = true })
+	if !ran {
+		t.Fatal("SubmitWait did not run task")
+	}
+	wp.Wait()
+	if max := atomic.LoadInt32(&maxRunning); max != 2 {
+		t.Fatal("expected at most 2 tasks running at once, got", max)
+	}
+	if view.Running() != 0 || view.Waiting() != 0 {
+		t.Fatal("view not idle after Wait")
+	}
+}
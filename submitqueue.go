@@ -0,0 +1,129 @@
+package workerpool
+
+import "sync/atomic"
+
+// submitQueue is an unbounded, lock-free, multi-producer single-consumer
+// queue of submitted tasks.  Any number of goroutines may push tasks, and only
+// the dispatcher pops them.  This lets Submit return without waiting for the
+// dispatcher to receive each task, and lets the dispatcher receive all tasks
+// submitted since it last looked with a single wakeup.
+//
+// This is an intrusive queue, based on Dmitry Vyukov's non-intrusive MPSC
+// node-based queue, where each task is linked to the next by its next field.
+type submitQueue struct {
+	head atomic.Pointer[task]
+	tail *task
+	stub task
+}
+
+func (q *submitQueue) init() {
+	q.head.Store(&q.stub)
+	q.tail = &q.stub
+}
+
+// push adds a task to the back of the queue.  Safe to call from any
+// goroutine.
+func (q *submitQueue) push(t *task) {
+	t.next.Store(nil)
+	prev := q.head.Swap(t)
+	prev.next.Store(t)
+}
+
+// pop removes a task from the front of the queue.  Returns nil if the queue is
+// empty, or if the next task is still being linked into the queue by a
+// producer.  Must only be called by the dispatcher.
+func (q *submitQueue) pop() *task {
+	tail := q.tail
+	next := tail.next.Load()
+	if tail == &q.stub {
+		if next == nil {
+			return nil
+		}
+		q.tail = next
+		tail = next
+		next = next.next.Load()
+	}
+	if next != nil {
+		q.tail = next
+		return tail
+	}
+	if tail != q.head.Load() {
+		// A producer has swapped the head but not yet linked it.
+		return nil
+	}
+	q.push(&q.stub)
+	if next = tail.next.Load(); next != nil {
+		q.tail = next
+		return tail
+	}
+	return nil
+}
+
+// enqueue pushes a task onto the submit queue, and wakes the dispatcher if it
+// has not already been signaled to look for submitted tasks.  A synchronous
+// worker pool runs the task instead, unless it is stopped.  If the worker
+// pool was stopped, and not suspended, the task is dropped and ErrStopped is
+// returned.
+func (p *WorkerPool) enqueue(t *task) error {
+	control := t.finished != nil || t.dump != nil
+	if p.shedding != nil && !control {
+		if t = p.shedTasks(t); t == nil {
+			return nil
+		}
+	}
+	if p.synchronous && !control && !p.Stopped() {
+		p.emitQueued(t)
+		p.runInline(t)
+		return nil
+	}
+	p.submitMutex.RLock()
+	if p.submitClosed && !control {
+		p.submitMutex.RUnlock()
+		p.dropSubmitted(t)
+		return ErrStopped
+	}
+	p.emitQueued(t)
+	p.submitQueue.push(t)
+	if atomic.CompareAndSwapInt32(&p.submitSignaled, 0, 1) {
+		p.submitted <- struct{}{}
+	}
+	p.submitMutex.RUnlock()
+	return nil
+}
+
+// submitsClosed returns true if tasks submitted now are dropped, because the
+// worker pool was stopped and not suspended.
+func (p *WorkerPool) submitsClosed() bool {
+	p.submitMutex.RLock()
+	defer p.submitMutex.RUnlock()
+	return p.submitClosed
+}
+
+// dropSubmitted drops a task, or each task of a batch, submitted after the
+// worker pool was stopped.
+func (p *WorkerPool) dropSubmitted(t *task) {
+	tasks := t.batch
+	if tasks == nil {
+		tasks = []*task{t}
+	}
+	for _, t := range tasks {
+		if t.quota != nil {
+			t.quota.release()
+			t.quota = nil
+		}
+		if t.claim() {
+			p.drop(t)
+		}
+	}
+}
+
+// receiveSubmitted pops all submitted tasks and gives each to accept.  Called
+// by the dispatcher after receiving from the submitted channel.
+func (p *WorkerPool) receiveSubmitted(accept func(*task)) {
+	// Clear the signal before popping, so that a task pushed after the queue
+	// is found empty signals the dispatcher again.
+	atomic.StoreInt32(&p.submitSignaled, 0)
+	for t := p.submitQueue.pop(); t != nil; t = p.submitQueue.pop() {
+		accept(t)
+	}
+}
@@ -0,0 +1,70 @@
+package workerpool
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestSubmitQueue(t *testing.T) {
+	t.Parallel()
+
+	var q submitQueue
+	q.init()
+	if q.pop() != nil {
+		t.Fatal("expected empty queue")
+	}
+	a, b := &task{id: 1}, &task{id: 2}
+	q.push(a)
+	q.push(b)
+	if q.pop() != a || q.pop() != b || q.pop() != nil {
+		t.Fatal("tasks not popped in pushed order")
+	}
+	// The queue is reusable after being emptied.
+	q.push(a)
+	if q.pop() != a || q.pop() != nil {
+		t.Fatal("task not popped after queue was emptied")
+	}
+}
+
+func TestSubmitQueueProducers(t *testing.T) {
+	t.Parallel()
+
+	const producers = 8
+	const perProducer = 10000
+
+	var q submitQueue
+	q.init()
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				q.push(&task{id: TaskID(p*perProducer + i)})
+			}
+		}(p)
+	}
+
+	// Each task must arrive exactly once, and the tasks of each producer in
+	// the order pushed.
+	next := make([]int, producers)
+	for n := 0; n < producers*perProducer; {
+		t1 := q.pop()
+		if t1 == nil {
+			// Empty, or a producer is still linking its task.
+			runtime.Gosched()
+			continue
+		}
+		p, i := int(t1.id)/perProducer, int(t1.id)%perProducer
+		if i != next[p] {
+			t.Fatalf("producer %d: expected task %d, got %d", p, next[p], i)
+		}
+		next[p]++
+		n++
+	}
+	wg.Wait()
+	if q.pop() != nil {
+		t.Fatal("expected empty queue after all tasks popped")
+	}
+}
@@ -0,0 +1,43 @@
+package workerpool
+
+import "sync/atomic"
+
+// NewSynchronous creates a worker pool that executes each submitted task on
+// the goroutine that submits it, before the submit function returns.  This
+// makes unit tests of code that uses a worker pool deterministic, without
+// sleeping or polling for tasks to complete.
+//
+// A synchronous worker pool has the same methods as a worker pool created by
+// New, and accepts the same options.  Since tasks are not run concurrently,
+// Pause has no effect, and a task that waits for another task submitted after
+// it never returns.
+func NewSynchronous(options ...Option) *WorkerPool {
+	return New(1, append(options, func(p *WorkerPool) {
+		p.synchronous = true
+	})...)
+}
+
+// runInline executes a submitted task, or each task in a submitted batch, on
+// the calling goroutine.
+func (p *WorkerPool) runInline(t *task) {
+	tasks := t.batch
+	if tasks == nil {
+		tasks = []*task{t}
+	}
+	ws := &workerState{}
+	for _, t := range tasks {
+		if t.quota != nil {
+			// The task is not queued, and does not occupy a worker.
+			t.quota.release()
+			t.quota = nil
+		}
+		p.addBusy(1)
+		if p.runTask(ws, t) {
+			// The task timed out, and the worker started to replace this
+			// goroutine is now one of the dispatcher's workers.
+			atomic.AddInt32(&p.workerCount, 1)
+			continue
+		}
+		p.addBusy(-1)
+	}
+}
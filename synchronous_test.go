@@ -0,0 +1,49 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSynchronous(t *testing.T) {
+	t.Parallel()
+
+	wp := NewSynchronous(WithTenantQuota("t", Quota{MaxQueued: 1, MaxRunning: 1}))
+
+	var order []int
+	for i := 0; i < 3; i++ {
+		i := i
+		wp.Submit(func() { order = append(order, i) })
+		if len(order) != i+1 {
+			t.Fatal("task did not run before Submit returned")
+		}
+	}
+	wp.SubmitAll(func() { order = append(order, 3) }, func() { order = append(order, 4) })
+	for i := 0; i < 3; i++ {
+		if err := wp.SubmitTenant("t", func() { order = append(order, 5) }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(order) != 8 {
+		t.Fatal("wrong number of tasks run:", len(order))
+	}
+	for i, n := range order[:5] {
+		if n != i {
+			t.Fatal("tasks run out of order:", order)
+		}
+	}
+
+	var nested bool
+	wp.SubmitWait(func() {
+		wp.Submit(func() { nested = true })
+	})
+	if !nested {
+		t.Fatal("nested task did not run")
+	}
+
+	wp.Pause(context.Background())
+	wp.Wait()
+	wp.StopWait()
+
+	wp.Submit(func() { t.Error("task ran after stop") })
+}
@@ -0,0 +1,105 @@
+package workerpool
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Task describes a task for SubmitTask.  Only Fn is required.
+type Task struct {
+	// Fn is the function that a worker executes.  Its context is Ctx, with
+	// the Deadline applied.
+	Fn func(ctx context.Context)
+	// Name identifies the task in reports, the same as Metadata.Name.
+	Name string
+	// Priority is the task's priority, where higher values are more urgent.
+	// It is not used by worker pools that do not order tasks by priority.
+	Priority int
+	// Deadline, if not zero, is when the task's context is canceled.  A task
+	// that has not started by its deadline is not run.
+	Deadline time.Time
+	// Ctx is the parent of the task's context.  A task whose context is
+	// canceled before the task starts is not run.  If nil, the background
+	// context is used.
+	Ctx context.Context
+	// Labels describe the task in reports, the same as Metadata.Labels.
+	Labels map[string]string
+	// Progress, if not nil, is given to the task in its context, to report
+	// its progress.  The task gets it using ProgressFrom.
+	Progress *Progress
+	// Needed, if not nil, is called just before the task starts, and the
+	// task is skipped if it returns false, the same as SubmitIfNeeded.
+	Needed func() bool
+}
+
+// SubmitTask enqueues a task for a worker to execute, and returns a handle
+// that can cancel the task before it starts.  The task is skipped, instead of
+// being run, if its context is canceled or its deadline passes before a worker
+// starts it.  A skipped task is still complete for the purposes of Wait and
+// Flush.  If task.Fn is nil, nothing is submitted and the handle is nil.
+//
+// If the worker pool uses preemption, set using WithPreemption, the task may
+// preempt a running task of lower priority.
+func (p *WorkerPool) SubmitTask(task Task) *Handle {
+	if task.Fn == nil {
+		return nil
+	}
+	t := p.newTask(nil)
+	var cancel *atomic.Pointer[context.CancelCauseFunc]
+	if p.preemption != nil {
+		cancel = &t.cancel
+	}
+	t.fn = func() {
+		if !task.run(cancel) {
+			t.expired = true
+			// The task's context is done, so the rejected task runs with a
+			// new context instead, and is not rejected again.
+			retry := task
+			retry.Ctx, retry.Deadline = nil, time.Time{}
+			p.rejected(func() { retry.run(nil) }, RejectedByExpiry)
+		}
+	}
+	t.ctxFn = task.Fn
+	if task.Name != "" || task.Labels != nil {
+		t.meta = &Metadata{Name: task.Name, Labels: task.Labels}
+	}
+	t.priority = task.Priority
+	t.progress = task.Progress
+	t.needed = task.Needed
+	t.sheddable = true
+	if p.enqueueAdmitted(t) == nil && p.preemption != nil {
+		p.preempt(t)
+	}
+	return &Handle{pool: p, task: t}
+}
+
+// run runs the task's function with its context, unless the context is
+// already done.  If cancel is not nil, the context can be canceled using the
+// function stored in cancel, to preempt the task.  Returns false if the task
+// did not run.
+func (task Task) run(cancel *atomic.Pointer[context.CancelCauseFunc]) bool {
+	ctx := task.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !task.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, task.Deadline)
+		defer cancel()
+	}
+	if cancel != nil {
+		var preempt context.CancelCauseFunc
+		ctx, preempt = context.WithCancelCause(ctx)
+		defer preempt(nil)
+		cancel.Store(&preempt)
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	if task.Progress != nil {
+		ctx = context.WithValue(ctx, progressKey{}, task.Progress)
+	}
+	task.Fn(ctx)
+	return true
+}
@@ -0,0 +1,60 @@
+package workerpool
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubmitTask(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithDebugDump())
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	wp.SubmitTask(Task{
+		Fn:     func(ctx context.Context) { <-release },
+		Name:   "blocker",
+		Labels: map[string]string{"k": "v"},
+	})
+
+	var ran int32
+	run := func(ctx context.Context) { atomic.AddInt32(&ran, 1) }
+	ctx, cancel := context.WithCancel(context.Background())
+	wp.SubmitTask(Task{Fn: run, Ctx: ctx})
+	wp.SubmitTask(Task{Fn: run, Deadline: time.Now().Add(time.Millisecond)})
+	var deadline bool
+	wp.SubmitTask(Task{
+		Fn: func(ctx context.Context) {
+			_, deadline = ctx.Deadline()
+			atomic.AddInt32(&ran, 1)
+		},
+		Deadline: time.Now().Add(time.Hour),
+	})
+
+	for wp.WaitingQueueSize() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	d := wp.Dump()
+	cancel()
+	time.Sleep(2 * time.Millisecond)
+	close(release)
+	wp.Wait()
+
+	if d.Workers[0].Task != "blocker" || d.Workers[0].Labels["k"] != "v" {
+		t.Fatal("wrong running task:", d.Workers[0])
+	}
+	if len(d.Waiting) != 3 || !strings.HasPrefix(d.Waiting[2].Task, "github.com/gammazero/workerpool.TestSubmitTask") {
+		t.Fatal("wrong waiting tasks:", d.Waiting)
+	}
+
+	if ran != 1 {
+		t.Fatal("expected 1 task to run, ran", ran)
+	}
+	if !deadline {
+		t.Fatal("task context does not have deadline")
+	}
+}
@@ -0,0 +1,128 @@
+package workerpool
+
+import (
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// TaskError describes a task that failed, by returning an error or by
+// panicking.
+type TaskError struct {
+	// Err is the error returned by the task, or a *PanicError describing
+	// the panic.
+	Err error
+	// Panic is the value that the task panicked with, or nil if the task
+	// returned an error.
+	Panic interface{}
+	// Stack is the stack trace of a task that panicked.
+	Stack []byte
+	// Task is the name of the task.
+	Task string
+	// Labels are the labels of the task, if submitted with metadata.
+	Labels map[string]string
+	// Time is when the task failed.
+	Time time.Time
+}
+
+func (e TaskError) Error() string {
+	return "workerpool: task " + e.Task + " failed: " + e.Err.Error()
+}
+
+func (e TaskError) Unwrap() error {
+	return e.Err
+}
+
+// WithErrors makes the worker pool send the failures of its tasks on the
+// channel returned by Errors, so that one goroutine can handle all failures.
+// Failures are the errors returned by tasks submitted using SubmitWithCallback
+// or SubmitTagged, and the panics of any task.  With this option, a panic in a
+// task is recovered, instead of crashing the program, and the task is then
+// complete.
+//
+// Up to buffer failures are held until they are received.  Failures are
+// dropped when the buffer is full, and are counted in the DroppedErrors field
+// of the worker pool's Stats.
+func WithErrors(buffer int) Option {
+	return func(p *WorkerPool) {
+		if buffer < 1 {
+			buffer = 1
+		}
+		p.errors = make(chan TaskError, buffer)
+	}
+}
+
+// Errors returns the channel that the worker pool sends task failures on, or
+// nil if the worker pool was not created using WithErrors.  The channel is not
+// closed when the worker pool stops.
+func (p *WorkerPool) Errors() <-chan TaskError {
+	return p.errors
+}
+
+// newErrTask creates a task for a function that returns an error.  The error
+// is given to done, if not nil, and is reported as a failure of the task.  If
+// the task panics and the panic is recovered, done is given a *PanicError, so
+// that a caller waiting for done is not left waiting.
+func (p *WorkerPool) newErrTask(fn func() error, done func(error)) *task {
+	t := p.newTask(nil)
+	t.errFn = fn
+	t.errDone = done
+	t.fn = func() {
+		t.err = fn()
+		// Clear errDone first, so that it is not called again if it panics.
+		if done := t.errDone; done != nil {
+			t.errDone = nil
+			done(t.err)
+		}
+	}
+	return t
+}
+
+// execTask executes a task's function.  If failures are reported, a panic is
+// recovered and reported, and the error of a task that returns an error is
+// reported.  If panics are backed off, a panic is recovered and recorded in
+// the task.  A recovered panic is given to the task's errDone function, if it
+// has not been called.
+func (p *WorkerPool) execTask(t *task, fn func()) {
+	if p.errors == nil && p.panicBackoff == nil {
+		p.exec(fn)
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			t.panicked = true
+			pe, ok := r.(forwardedPanic)
+			if !ok {
+				pe.err = &PanicError{Value: r, Stack: debug.Stack()}
+			}
+			if done := t.errDone; done != nil {
+				t.errDone = nil
+				done(pe.err)
+			}
+			if p.errors == nil {
+				return
+			}
+			p.reportError(t, TaskError{
+				Err:   pe.err,
+				Panic: pe.err.Value,
+				Stack: pe.err.Stack,
+			})
+		}
+	}()
+	p.exec(fn)
+	if t.err != nil && p.errors != nil {
+		p.reportError(t, TaskError{Err: t.err})
+	}
+}
+
+// reportError sends a task failure without blocking.
+func (p *WorkerPool) reportError(t *task, te TaskError) {
+	te.Task = t.name()
+	te.Labels = t.labels()
+	te.Time = time.Now()
+	select {
+	case p.errors <- te:
+	default:
+		atomic.AddInt64(&p.droppedErrors, 1)
+	}
+}
@@ -0,0 +1,75 @@
+package workerpool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2, WithErrors(10))
+	errFail := errors.New("fail")
+	wp.SubmitWithCallback(func() error { return errFail }, nil)
+	wp.SubmitWithCallback(func() error { return nil }, nil)
+	wp.SubmitWithMetadata(Metadata{Name: "panicker"}, func() { panic("boom") })
+	wp.Submit(func() {})
+	wp.StopWait()
+
+	if len(wp.Errors()) != 2 {
+		t.Fatal("expected 2 errors, got", len(wp.Errors()))
+	}
+	for i := 0; i < 2; i++ {
+		te := <-wp.Errors()
+		if te.Panic != nil {
+			if te.Panic != "boom" || te.Task != "panicker" {
+				t.Fatal("wrong panic error:", te)
+			}
+			if !bytes.Contains(te.Stack, []byte("TestErrors")) {
+				t.Fatalf("stack does not show task:\n%s", te.Stack)
+			}
+			continue
+		}
+		if !errors.Is(te, errFail) {
+			t.Fatal("wrong error:", te)
+		}
+	}
+}
+
+func TestRecoveredPanicCompletesWaiters(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2, WithErrors(10))
+	defer wp.Stop()
+	boom := func() { panic("boom") }
+
+	wp.SubmitWait(boom)
+
+	var pe *PanicError
+	_, err := wp.SubmitKeyed("key", boom).Wait()
+	if !errors.As(err, &pe) || pe.Value != "boom" {
+		t.Fatal("expected keyed future to fail with panic, got", err)
+	}
+
+	_, err = FanOut(context.Background(), wp, func(context.Context) (int, error) {
+		panic("boom")
+	})
+	if !errors.As(err, &pe) {
+		t.Fatal("expected FanOut to return panic, got", err)
+	}
+
+	done := make(chan error, 1)
+	wp.SubmitWithCallback(func() error { panic("boom") }, func(err error) { done <- err })
+	if err = <-done; !errors.As(err, &pe) {
+		t.Fatal("expected callback to be given panic, got", err)
+	}
+
+	child := wp.Child(1, WithErrors(10))
+	child.SubmitWait(boom)
+	child.StopWait()
+	if te := <-child.Errors(); te.Panic != "boom" {
+		t.Fatal("child did not report panic:", te)
+	}
+}
@@ -0,0 +1,241 @@
+package workerpool
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TaskID identifies a task submitted to a worker pool.  IDs are assigned in
+// the order that tasks are submitted, starting at 1, and are unique within the
+// worker pool.
+type TaskID uint64
+
+// TaskState is the state of a task recorded by the task index, set using
+// WithTaskIndex.
+type TaskState int
+
+const (
+	// StateQueued is the state of a task that is waiting for a worker.
+	StateQueued TaskState = iota
+	// StateRunning is the state of a task that a worker is running.
+	StateRunning
+	// StateDone is the state of a task that returned.
+	StateDone
+	// StateFailed is the state of a task that returned an error, panicked,
+	// or whose worker was abandoned because it did not return after its
+	// timeout.  Panics are only recorded when they are recovered, using
+	// WithErrors or WithPanicBackoff.
+	StateFailed
+	// StateCanceled is the state of a task that was canceled before it
+	// started.
+	StateCanceled
+	// StateDropped is the state of a queued task that was not run because
+	// the worker pool stopped, including a task saved by Checkpoint.
+	StateDropped
+	// StateSkipped is the state of a task that was not run because it was no
+	// longer needed.
+	StateSkipped
+)
+
+var taskStateNames = [...]string{
+	StateQueued:   "Queued",
+	StateRunning:  "Running",
+	StateDone:     "Done",
+	StateFailed:   "Failed",
+	StateCanceled: "Canceled",
+	StateDropped:  "Dropped",
+	StateSkipped:  "Skipped",
+}
+
+func (s TaskState) String() string {
+	if s < 0 || int(s) >= len(taskStateNames) {
+		return "TaskState(" + strconv.Itoa(int(s)) + ")"
+	}
+	return taskStateNames[s]
+}
+
+// WithTaskIndex records the state of the worker pool's most recently submitted
+// tasks, by task ID, keeping at most size tasks, for Status to report.  When
+// more tasks are submitted, the oldest are forgotten, whatever their state.
+func WithTaskIndex(size int) Option {
+	return func(p *WorkerPool) {
+		if size > 0 {
+			p.taskIndex = &taskIndex{
+				ids:     make([]TaskID, size),
+				records: make(map[TaskID]*taskRecord, size),
+			}
+		}
+	}
+}
+
+// SubmitWithID enqueues a function for a worker to execute, the same as
+// Submit, and returns the ID of the task.  Returns zero if task is nil.
+func (p *WorkerPool) SubmitWithID(task func()) TaskID {
+	if task == nil {
+		return 0
+	}
+	t := p.newTask(task)
+	p.enqueueAdmitted(t)
+	return t.id
+}
+
+// ID returns the ID of the task.
+func (h *Handle) ID() TaskID {
+	if h == nil {
+		return 0
+	}
+	return h.task.id
+}
+
+// taskIndex holds the records of the most recently submitted tasks.
+type taskIndex struct {
+	mutex   sync.Mutex
+	records map[TaskID]*taskRecord
+	// ids is a ring of the IDs of the records, oldest first from next.
+	ids  []TaskID
+	next int
+}
+
+// taskRecord is the state of a task in the task index.
+type taskRecord struct {
+	state     TaskState
+	submitted time.Time
+	started   time.Time
+	finished  time.Time
+	// task is the task until it finishes, when its name and labels are
+	// copied from it, so that the record does not hold the task's function.
+	task     *task
+	name     string
+	labels   map[string]string
+	progress *Progress
+}
+
+// add records a submitted task, forgetting the oldest task if the index is
+// full.  Does nothing if the index is nil.
+func (x *taskIndex) add(t *task) {
+	if x == nil {
+		return
+	}
+	x.mutex.Lock()
+	delete(x.records, x.ids[x.next])
+	x.ids[x.next] = t.id
+	x.next = (x.next + 1) % len(x.ids)
+	x.records[t.id] = &taskRecord{
+		state:     StateQueued,
+		submitted: time.Now(),
+		task:      t,
+	}
+	x.mutex.Unlock()
+}
+
+// restore points the record of a task at the task read back from the spill
+// file, which replaces the task that was spilled.
+func (x *taskIndex) restore(t *task) {
+	if x == nil {
+		return
+	}
+	x.mutex.Lock()
+	if r := x.records[t.id]; r != nil && r.task != nil {
+		r.task = t
+	}
+	x.mutex.Unlock()
+}
+
+// start records that a task started running.  Does nothing if the index is
+// nil.
+func (x *taskIndex) start(t *task) {
+	if x == nil {
+		return
+	}
+	x.mutex.Lock()
+	if r := x.records[t.id]; r != nil {
+		r.state = StateRunning
+		r.started = time.Now()
+	}
+	x.mutex.Unlock()
+}
+
+// finish records that a task finished in the given state.  Does nothing if the
+// index is nil.
+func (x *taskIndex) finish(t *task, state TaskState) {
+	if x == nil {
+		return
+	}
+	x.mutex.Lock()
+	if r := x.records[t.id]; r != nil && r.task != nil {
+		r.state = state
+		r.finished = time.Now()
+		r.name = t.name()
+		r.labels = t.labels()
+		r.progress = t.progress
+		r.task = nil
+	}
+	x.mutex.Unlock()
+}
+
+// TaskStatus is the recorded state of a task, returned by Status.
+type TaskStatus struct {
+	ID    TaskID
+	State TaskState
+	// Name is the name of the task.
+	Name string
+	// Labels are the labels of the task, if submitted with metadata.
+	Labels map[string]string
+	// Submitted is when the task was submitted.
+	Submitted time.Time
+	// Started is when a worker started the task, or zero if it has not
+	// started.
+	Started time.Time
+	// Finished is when the task finished, or zero if it has not finished.
+	Finished time.Time
+	// Running is how long the task has been running, or how long it ran if
+	// it has finished.
+	Running time.Duration
+	// Progress is the progress of a task submitted using SubmitTask with a
+	// Progress, or nil.
+	Progress *Progress
+}
+
+// Status returns the recorded state of the task with the given ID.  Returns
+// false if the task is not in the task index, set using WithTaskIndex, because
+// the task index is not used, or because the task was forgotten after more
+// recent tasks were submitted.
+func (p *WorkerPool) Status(id TaskID) (TaskStatus, bool) {
+	x := p.taskIndex
+	if x == nil {
+		return TaskStatus{}, false
+	}
+	x.mutex.Lock()
+	r := x.records[id]
+	if r == nil {
+		x.mutex.Unlock()
+		return TaskStatus{}, false
+	}
+	status := TaskStatus{
+		ID:        id,
+		State:     r.state,
+		Name:      r.name,
+		Labels:    r.labels,
+		Submitted: r.submitted,
+		Started:   r.started,
+		Finished:  r.finished,
+		Progress:  r.progress,
+	}
+	t := r.task
+	x.mutex.Unlock()
+
+	if t != nil {
+		status.Name = t.name()
+		status.Labels = t.labels()
+		status.Progress = t.progress
+	}
+	switch {
+	case status.Started.IsZero():
+	case status.Finished.IsZero():
+		status.Running = time.Since(status.Started)
+	default:
+		status.Running = status.Finished.Sub(status.Started)
+	}
+	return status, true
+}
@@ -0,0 +1,97 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTaskIDs(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithTaskIndex(2))
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	first := wp.SubmitWithID(func() { <-release })
+	if first == 0 {
+		t.Fatal("expected task ID")
+	}
+	second := wp.SubmitWithID(func() {})
+	if second <= first {
+		t.Fatal("expected increasing task IDs:", first, second)
+	}
+	h := wp.SubmitTask(Task{Fn: func(context.Context) {}})
+	if h.ID() <= second {
+		t.Fatal("expected increasing task IDs:", second, h.ID())
+	}
+	if wp.SubmitWithID(nil) != 0 {
+		t.Fatal("expected zero ID for nil task")
+	}
+
+	// The index keeps only the most recent tasks.
+	x := wp.taskIndex
+	x.mutex.Lock()
+	if _, ok := x.records[first]; ok || len(x.records) != 2 {
+		t.Fatal("expected oldest task to be forgotten")
+	}
+	if r := x.records[second]; r == nil || r.state != StateQueued {
+		t.Fatal("expected second task to be queued")
+	}
+	x.mutex.Unlock()
+
+	if !h.Cancel() {
+		t.Fatal("expected task to be canceled")
+	}
+	close(release)
+	wp.StopWait()
+
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	if r := x.records[second]; r.state != StateDone || r.finished.Before(r.started) {
+		t.Fatal("expected second task to be done, got", r.state)
+	}
+	if r := x.records[h.ID()]; r.state != StateCanceled {
+		t.Fatal("expected canceled task, got", r.state)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithTaskIndex(10))
+	defer wp.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	running := wp.SubmitWithID(func() {
+		close(started)
+		<-release
+	})
+	queued := wp.SubmitTask(Task{Fn: func(context.Context) {}, Name: "queued"}).ID()
+	<-started
+
+	status, ok := wp.Status(running)
+	if !ok || status.State != StateRunning || status.Running <= 0 {
+		t.Fatal("expected running task, got", status.State)
+	}
+	status, ok = wp.Status(queued)
+	if !ok || status.State != StateQueued || status.Name != "queued" || !status.Started.IsZero() {
+		t.Fatal("expected queued task, got", status.State, status.Name)
+	}
+	if _, ok = wp.Status(queued + 1); ok {
+		t.Fatal("expected no status for unknown task")
+	}
+
+	close(release)
+	wp.StopWait()
+	status, _ = wp.Status(running)
+	if status.State != StateDone || status.Running != status.Finished.Sub(status.Started) {
+		t.Fatal("expected done task, got", status.State)
+	}
+
+	wp2 := New(1)
+	defer wp2.Stop()
+	if _, ok = wp2.Status(wp2.SubmitWithID(func() {})); ok {
+		t.Fatal("expected no status without task index")
+	}
+}
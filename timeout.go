@@ -0,0 +1,176 @@
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTimedOut is returned by SubmitTimeout when a worker does not start the
+// task within the timeout.
+var ErrTimedOut = errors.New("workerpool: timed out waiting for a worker")
+
+// defaultTimeoutGrace is how long a task submitted with SubmitWithTimeout has
+// to return after its context is canceled, unless set using
+// WithTimeoutGrace.
+const defaultTimeoutGrace = time.Second
+
+// Run states of a task submitted with SubmitWithTimeout.
+const (
+	timeoutRunning int32 = iota
+	timeoutReturned
+	timeoutAbandoned
+)
+
+// WithTimeoutGrace sets how long a task submitted with SubmitWithTimeout has to
+// return after its context is canceled, before its worker is abandoned.  The
+// default is one second.
+func WithTimeoutGrace(grace time.Duration) Option {
+	return func(p *WorkerPool) {
+		if grace > 0 {
+			p.timeoutGrace = grace
+		}
+	}
+}
+
+// SubmitWithTimeout enqueues a function for a worker to execute, giving the
+// function a context that is canceled after the timeout period d, measured
+// from when the task starts, or when the worker pool's Context is canceled.
+//
+// Since a goroutine cannot be stopped, the task must return when its context
+// is canceled.  If the task does not return within the grace period after
+// its context is canceled, then the task's worker is abandoned and replaced by
+// a new worker, so that the task no longer counts against the maximum number
+// of workers, or the limits of WithLimiter and WithAdaptiveConcurrency.  The
+// task is then considered complete, the abandoned goroutine is leaked until
+// the task returns, and the leak is counted in the AbandonedTasks field of the
+// worker pool's Stats.
+func (p *WorkerPool) SubmitWithTimeout(d time.Duration, task func(context.Context)) {
+	if task == nil {
+		return
+	}
+	t := p.newTask(nil)
+	t.ctxFn = task
+	t.timeout = d
+	if t.timeout <= 0 {
+		t.timeout = 1
+	}
+	p.enqueue(t)
+}
+
+// runFunc returns a function that runs the task, to give a task that was not
+// run to the OnDropped and OnReject functions.  A task submitted with
+// SubmitWithTimeout is run with a context that has its timeout.
+func (t *task) runFunc() func() {
+	if t.fn != nil || t.ctxFn == nil {
+		return t.fn
+	}
+	fn, d := t.ctxFn, t.timeout
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), d)
+		defer cancel()
+		fn(ctx)
+	}
+}
+
+// runWithTimeout executes a task submitted with SubmitWithTimeout.  If the task
+// does not return within the grace period after its timeout, the worker is
+// abandoned, release is called to give up the task's concurrency slots, and a
+// replacement worker is started.  The replacement is given any tasks remaining
+// in the abandoned worker's batch.  Returns true if the worker was abandoned,
+// or false if the task returned and the caller must call release.
+func (p *WorkerPool) runWithTimeout(ws *workerState, t *task, release func()) bool {
+	ctx, cancel := context.WithTimeout(p.Context(), t.timeout)
+	defer cancel()
+
+	var state int32
+	timer := time.AfterFunc(t.timeout+p.timeoutGrace, func() {
+		if !atomic.CompareAndSwapInt32(&state, timeoutRunning, timeoutAbandoned) {
+			return
+		}
+		atomic.AddInt64(&p.abandonedTasks, 1)
+		p.taskIndex.finish(t, StateFailed)
+		release()
+		p.addBusy(-1)
+		var rescued *task
+		if wq := ws.wq.Load(); wq != nil {
+			if remaining := wq.takeAll(); len(remaining) != 0 {
+				rescued = &task{batch: remaining}
+			}
+		}
+		p.taskDone(t)
+		p.count(MetricTasksAbandoned, 1)
+		p.emit(TaskDone, t)
+		if !ws.worker {
+			// The abandoned goroutine, running a task inline, was not
+			// a worker that the dispatcher waits for.
+			p.workerGroup.Add(1)
+		}
+		id := p.addSpawner()
+		go func() {
+			defer p.removeSpawner(id)
+			p.worker(rescued)
+		}()
+	})
+
+	p.execTask(t, func() { t.ctxFn(ctx) })
+
+	if !atomic.CompareAndSwapInt32(&state, timeoutRunning, timeoutReturned) {
+		return true
+	}
+	timer.Stop()
+	return false
+}
+
+// SubmitTimeout enqueues a function for a worker to execute, and waits up to
+// duration d for a worker to start it.  If no worker starts the task within
+// d, then the task is canceled, so that it does not run later, and
+// ErrTimedOut is returned.  This lets callers degrade gracefully when the
+// worker pool is backed up, instead of adding to the backlog.  Returns nil as
+// soon as the task starts, without waiting for it to complete.
+//
+// The worker pool's queue is unbounded, so submitting never blocks.  Unlike
+// SubmitWithTimeout, which limits how long a task runs, SubmitTimeout limits
+// how long a task waits in the queue to run.  A canceled task is skipped when
+// the dispatcher reaches it, the same as a task canceled using Handle.Cancel.
+//
+// If the task is rejected or degraded by the worker pool's admission function,
+// then ErrQueueFull is returned.  If the worker pool is stopped without
+// running the task, then ErrStopped is returned.
+func (p *WorkerPool) SubmitTimeout(d time.Duration, task func()) error {
+	if task == nil {
+		return nil
+	}
+	started := make(chan struct{})
+	dropped := make(chan struct{})
+	t := p.newTask(func() {
+		close(started)
+		task()
+	})
+	t.waited = true
+	t.dropped = func() { close(dropped) }
+	if err := p.enqueueAdmitted(t); err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-started:
+		return nil
+	case <-dropped:
+		return ErrStopped
+	case <-timer.C:
+	}
+	if p.discard(t) {
+		return ErrTimedOut
+	}
+	// A worker started the task, or it was dropped, as the timer fired.
+	select {
+	case <-started:
+		return nil
+	case <-dropped:
+		return ErrStopped
+	}
+}
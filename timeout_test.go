@@ -0,0 +1,162 @@
+package workerpool
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSubmitWithTimeout(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithTimeoutGrace(20*time.Millisecond))
+	defer wp.Stop()
+
+	// Task that honors its context is not abandoned.
+	done := make(chan error, 1)
+	wp.SubmitWithTimeout(10*time.Millisecond, func(ctx context.Context) {
+		<-ctx.Done()
+		done <- ctx.Err()
+	})
+	if err := <-done; err != context.DeadlineExceeded {
+		t.Fatal("expected deadline exceeded, got", err)
+	}
+	wp.Wait()
+	if n := wp.Stats().AbandonedTasks; n != 0 {
+		t.Fatal("expected no abandoned tasks, got", n)
+	}
+
+	// Task that ignores its context is abandoned, and its worker replaced so
+	// that other tasks can run.
+	hung := make(chan struct{})
+	defer close(hung)
+	wp.SubmitWithTimeout(10*time.Millisecond, func(ctx context.Context) {
+		<-hung
+	})
+	ran := make(chan struct{})
+	wp.Submit(func() { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task blocked behind hung task")
+	}
+	wp.Wait()
+	if n := wp.Stats().AbandonedTasks; n != 1 {
+		t.Fatal("expected 1 abandoned task, got", n)
+	}
+	if n := wp.WorkerCount(); n != 1 {
+		t.Fatal("abandoned worker should be replaced, have", n, "workers")
+	}
+}
+
+func TestSubmitWithTimeoutLimiter(t *testing.T) {
+	t.Parallel()
+
+	// An abandoned task gives up its slot in the limiter, so that the
+	// replacement worker can run tasks.
+	wp := New(2, WithTimeoutGrace(20*time.Millisecond), WithLimiter(NewLimiter(1)))
+	hung := make(chan struct{})
+	defer close(hung)
+	wp.SubmitWithTimeout(10*time.Millisecond, func(ctx context.Context) {
+		<-hung
+	})
+	ran := make(chan struct{})
+	wp.Submit(func() { close(ran) })
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task blocked by the limiter slot of an abandoned task")
+	}
+
+	// The task's context is canceled when the worker pool is stopped.
+	canceled := make(chan error, 1)
+	wp.SubmitWithTimeout(time.Hour, func(ctx context.Context) {
+		<-ctx.Done()
+		canceled <- context.Cause(ctx)
+	})
+	for wp.BusyWorkers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	wp.Stop()
+	if err := <-canceled; err != ErrStopped {
+		t.Fatal("expected ErrStopped, got", err)
+	}
+}
+
+func TestSubmitWithTimeoutBatch(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithTimeoutGrace(20*time.Millisecond))
+	defer wp.Stop()
+
+	// Queue enough tasks that the hung task is given to the worker in a
+	// batch with other tasks.
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	hung := make(chan struct{})
+	defer close(hung)
+	wp.SubmitWithTimeout(10*time.Millisecond, func(ctx context.Context) {
+		<-hung
+	})
+	ran := make(chan struct{}, 10)
+	for i := 0; i < 10; i++ {
+		wp.Submit(func() { ran <- struct{}{} })
+	}
+	close(release)
+
+	// Tasks batched behind the hung task are given to the replacement.
+	for i := 0; i < 10; i++ {
+		select {
+		case <-ran:
+		case <-time.After(5 * time.Second):
+			t.Fatal("task in batch blocked behind hung task")
+		}
+	}
+}
+
+func TestSubmitTimeout(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithEvents(20))
+	defer wp.Stop()
+
+	ran := make(chan struct{})
+	if err := wp.SubmitTimeout(time.Second, func() { close(ran) }); err != nil {
+		t.Fatal(err)
+	}
+	<-ran
+
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	var late bool
+	err := wp.SubmitTimeout(10*time.Millisecond, func() { late = true })
+	if err != ErrTimedOut {
+		t.Fatal("expected ErrTimedOut, got", err)
+	}
+	close(release)
+	wp.StopWait()
+	if late {
+		t.Fatal("timed out task ran")
+	}
+	// The timed out task was canceled, instead of left to run as a no-op.
+	var started int
+	for len(wp.Events()) != 0 {
+		if ev := <-wp.Events(); ev.Type == TaskStarted {
+			started++
+		}
+	}
+	if started != 2 {
+		t.Fatal("expected 2 tasks to start, got", started)
+	}
+	if err = wp.SubmitTimeout(time.Second, func() {}); err != ErrStopped {
+		t.Fatal("expected ErrStopped, got", err)
+	}
+
+	degrading := New(1, WithAdmission(func(TaskInfo, PoolStats) Decision {
+		return Decision{Action: Degrade, Fallback: func() {}}
+	}))
+	defer degrading.Stop()
+	if err = degrading.SubmitTimeout(time.Second, func() {}); err != ErrQueueFull {
+		t.Fatal("expected ErrQueueFull, got", err)
+	}
+}
@@ -0,0 +1,119 @@
+package workerpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MetricUtilization is the worker pool's utilization, when enabled using
+// WithUtilization.
+const MetricUtilization = "workerpool.utilization"
+
+// utilizationSamples is the number of samples kept for the rolling window.
+const utilizationSamples = 10
+
+// WithUtilization records the worker pool's utilization over a rolling window
+// of the given duration, for Utilization to report.
+func WithUtilization(window time.Duration) Option {
+	return func(p *WorkerPool) {
+		if window > 0 {
+			p.utilization = &utilization{window: window}
+		}
+	}
+}
+
+// Utilization returns the fraction of the worker pool's capacity, from 0 to
+// 1, that was spent running tasks over the recent window set using
+// WithUtilization.  Capacity is the maximum number of workers, so a worker
+// pool that is often near 1 needs more workers, and one that stays low has
+// more than it needs.  Returns 0 if utilization is not recorded.
+func (p *WorkerPool) Utilization() float64 {
+	if p.utilization == nil {
+		return 0
+	}
+	return p.utilization.get(p.clock.Now(), p.maxWorkers)
+}
+
+// utilization integrates the number of busy workers over time.
+type utilization struct {
+	window time.Duration
+
+	mutex sync.Mutex
+	busy  int
+	last  time.Time
+	// total is the busy worker time since the first change.
+	total   time.Duration
+	samples []utilizationSample
+}
+
+// utilizationSample is the busy worker time total at a point in time.
+type utilizationSample struct {
+	time  time.Time
+	total time.Duration
+}
+
+// advance adds the time since the last change, at the previous number of busy
+// workers, and samples the total at intervals.
+//
+// Must be called with mutex held.
+func (u *utilization) advance(now time.Time) {
+	if u.last.IsZero() {
+		u.last = now
+		u.samples = append(u.samples, utilizationSample{now, 0})
+		return
+	}
+	u.total += time.Duration(u.busy) * now.Sub(u.last)
+	u.last = now
+	if now.Sub(u.samples[len(u.samples)-1].time) >= u.window/utilizationSamples {
+		if len(u.samples) > utilizationSamples {
+			u.samples = append(u.samples[:0], u.samples[1:]...)
+		}
+		u.samples = append(u.samples, utilizationSample{now, u.total})
+	}
+}
+
+// change records a change in the number of busy workers.
+func (u *utilization) change(now time.Time, delta int) {
+	u.mutex.Lock()
+	u.advance(now)
+	u.busy += delta
+	u.mutex.Unlock()
+}
+
+// get returns the utilization over the window ending now.
+func (u *utilization) get(now time.Time, maxWorkers int) float64 {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.advance(now)
+	// Find the busy worker time total at the start of the window, by
+	// interpolating between the samples before and after it.
+	cutoff := now.Add(-u.window)
+	start := u.samples[0]
+	if start.time.Before(cutoff) {
+		for _, s := range u.samples[1:] {
+			if !s.time.Before(cutoff) {
+				frac := float64(cutoff.Sub(start.time)) / float64(s.time.Sub(start.time))
+				start = utilizationSample{
+					time:  cutoff,
+					total: start.total + time.Duration(frac*float64(s.total-start.total)),
+				}
+				break
+			}
+			start = s
+		}
+	}
+	elapsed := now.Sub(start.time)
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(u.total-start.total) / (float64(elapsed) * float64(maxWorkers))
+}
+
+// addBusy changes the number of busy workers.
+func (p *WorkerPool) addBusy(delta int32) {
+	atomic.AddInt32(&p.busyWorkers, delta)
+	if p.utilization != nil {
+		p.utilization.change(p.clock.Now(), int(delta))
+	}
+}
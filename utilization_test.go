@@ -0,0 +1,40 @@
+package workerpool
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUtilization(t *testing.T) {
+	t.Parallel()
+
+	clock := NewManualClock(time.Now())
+	wp := New(2, WithClock(clock), WithUtilization(10*time.Second))
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	for atomic.LoadInt32(&wp.busyWorkers) != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(10 * time.Second)
+	if u := wp.Utilization(); math.Abs(u-0.5) > 0.001 {
+		t.Fatal("expected utilization 0.5, got", u)
+	}
+
+	close(release)
+	wp.Wait()
+	for atomic.LoadInt32(&wp.busyWorkers) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	clock.Advance(5 * time.Second)
+	if u := wp.Utilization(); math.Abs(u-0.25) > 0.001 {
+		t.Fatal("expected utilization 0.25, got", u)
+	}
+	clock.Advance(5 * time.Second)
+	if u := wp.Utilization(); u != 0 {
+		t.Fatal("expected utilization 0, got", u)
+	}
+}
@@ -0,0 +1,261 @@
+package workerpool
+
+import (
+	"bytes"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// workerState is the state of a worker.  Workers are only registered, and
+// their task start times recorded, when options that inspect running tasks
+// are used.
+type workerState struct {
+	// wq is the work queue of the batch the worker is running, if any.
+	wq atomic.Pointer[workQueue]
+	// started is the time, in Unix nanoseconds, that the worker's current task
+	// started, or zero if the worker is not running a task.
+	started int64
+	// task is the worker's current task, if tasks are tracked.
+	task atomic.Pointer[task]
+	// reported is the start time of the task last reported as stuck.
+	reported int64
+	// profiled is the start time of the task last profiled as slow.
+	profiled int64
+	// goroutineID identifies the worker's goroutine in stack dumps.
+	goroutineID uint64
+	// nextStart is the earliest time the worker may start its next task,
+	// when worker rates are limited.  It is only used by the worker.
+	nextStart time.Time
+	// panics is the number of consecutive tasks of the worker that panicked,
+	// when panics are backed off.  It is only used by the worker.
+	panics int
+	// worker is set for the state of a worker goroutine, and not for a task
+	// run inline by a synchronous worker pool.
+	worker bool
+}
+
+// StuckTask describes a task that has been running longer than the watchdog
+// threshold, or than the duration given to StackTraces.
+type StuckTask struct {
+	// Running is how long the task has been running.
+	Running time.Duration
+	// Stack is the stack trace of the worker goroutine running the task.
+	Stack []byte
+	// Name is the name of the task.
+	Name string
+	// Labels are the labels of the task, if submitted with metadata.
+	Labels map[string]string
+}
+
+type watchdog struct {
+	threshold time.Duration
+	report    func(StuckTask)
+}
+
+// WithWatchdog enables a watchdog that calls report for each task that is
+// still running after the threshold duration.  Each task is reported once.
+// This helps to detect tasks that are hung, for example waiting on a
+// downstream call that never returns.
+//
+// Running tasks are checked at intervals of half the threshold, so a task may
+// run for up to 1.5 times the threshold before being reported.  The report
+// function is called from the watchdog goroutine, and should return quickly.
+func WithWatchdog(threshold time.Duration, report func(StuckTask)) Option {
+	return func(p *WorkerPool) {
+		if threshold > 0 && report != nil {
+			p.watchdog = &watchdog{threshold, report}
+			p.trackTasks = true
+		}
+	}
+}
+
+// addWorkerState creates the state of a new worker, and registers it by its
+// goroutine ID if tasks are being tracked.  Must be called from the worker
+// goroutine.
+func (p *WorkerPool) addWorkerState() *workerState {
+	if !p.trackTasks {
+		return &workerState{}
+	}
+	ws := &workerState{goroutineID: curGoroutineID()}
+	p.workerMutex.Lock()
+	if p.workerStates == nil {
+		p.workerStates = map[uint64]*workerState{}
+	}
+	p.workerStates[ws.goroutineID] = ws
+	p.workerMutex.Unlock()
+	return ws
+}
+
+func (p *WorkerPool) removeWorkerState(ws *workerState) {
+	if !p.trackTasks {
+		return
+	}
+	p.workerMutex.Lock()
+	delete(p.workerStates, ws.goroutineID)
+	p.workerMutex.Unlock()
+}
+
+// runWatchdog periodically checks for tasks that have been running longer
+// than the watchdog threshold, until the worker pool stops.
+func (p *WorkerPool) runWatchdog(stoppedChan <-chan struct{}) {
+	ticker := time.NewTicker(p.watchdog.threshold / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-stoppedChan:
+			return
+		}
+
+		now := time.Now().UnixNano()
+		var stuck []*workerState
+		var tasks []*task
+		p.workerMutex.Lock()
+		for _, ws := range p.workerStates {
+			started := atomic.LoadInt64(&ws.started)
+			if started == 0 || time.Duration(now-started) < p.watchdog.threshold {
+				continue
+			}
+			if atomic.SwapInt64(&ws.reported, started) != started {
+				stuck = append(stuck, ws)
+				tasks = append(tasks, ws.task.Load())
+			}
+		}
+		p.workerMutex.Unlock()
+		if len(stuck) == 0 {
+			continue
+		}
+
+		stacks := allStacks()
+		for i, ws := range stuck {
+			st := StuckTask{
+				Running: time.Duration(now - atomic.LoadInt64(&ws.reported)),
+				Stack:   goroutineStack(stacks, ws.goroutineID),
+			}
+			if t := tasks[i]; t != nil {
+				st.Name = t.name()
+				st.Labels = t.labels()
+			}
+			p.watchdog.report(st)
+		}
+	}
+}
+
+// StackTraces returns the stack traces of the workers whose current task has
+// been running longer than olderThan, longest running first.  This helps to
+// diagnose stuck tasks without dumping the stacks of the whole process.
+//
+// Task start times are only recorded when an option that inspects running
+// tasks, such as WithDebugDump or WithWatchdog, is used.  Otherwise,
+// StackTraces returns nil.
+func (p *WorkerPool) StackTraces(olderThan time.Duration) []StuckTask {
+	if !p.trackTasks {
+		return nil
+	}
+	now := time.Now().UnixNano()
+	var ids []uint64
+	var traces []StuckTask
+	p.workerMutex.Lock()
+	for _, ws := range p.workerStates {
+		started := atomic.LoadInt64(&ws.started)
+		if started == 0 || time.Duration(now-started) <= olderThan {
+			continue
+		}
+		st := StuckTask{Running: time.Duration(now - started)}
+		if t := ws.task.Load(); t != nil {
+			st.Name = t.name()
+			st.Labels = t.labels()
+		}
+		ids = append(ids, ws.goroutineID)
+		traces = append(traces, st)
+	}
+	p.workerMutex.Unlock()
+	if len(traces) == 0 {
+		return nil
+	}
+
+	stacks := allStacks()
+	for i := range traces {
+		traces[i].Stack = goroutineStack(stacks, ids[i])
+	}
+	sort.Slice(traces, func(i, j int) bool { return traces[i].Running > traces[j].Running })
+	return traces
+}
+
+// curGoroutineID returns the ID of the calling goroutine.
+func curGoroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	// Stack begins with "goroutine 123 [running]:"
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
+
+// creatorGoroutineID returns the ID of the goroutine that started the calling
+// goroutine, if it was started by a function of this package, or zero
+// otherwise.
+func creatorGoroutineID() uint64 {
+	buf := make([]byte, 1024)
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	// Stack ends with "created by <function> in goroutine 123" followed by
+	// the location of the go statement.
+	i := bytes.LastIndex(buf, []byte("\ncreated by "))
+	if i < 0 {
+		return 0
+	}
+	line := buf[i+len("\ncreated by "):]
+	if i = bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	if !bytes.HasPrefix(line, []byte(pkgPrefix)) {
+		return 0
+	}
+	if i = bytes.LastIndex(line, []byte(" in goroutine ")); i < 0 {
+		return 0
+	}
+	id, _ := strconv.ParseUint(string(line[i+len(" in goroutine "):]), 10, 64)
+	return id
+}
+
+// pkgPrefix is the prefix of the names of this package's functions in stack
+// traces.
+var pkgPrefix = reflect.TypeOf(WorkerPool{}).PkgPath() + "."
+
+// allStacks returns the stack traces of all goroutines.
+func allStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
+
+// goroutineStack returns the stack trace of the goroutine with the given ID
+// from a dump of all goroutine stacks, or nil if the goroutine is not found.
+func goroutineStack(stacks []byte, id uint64) []byte {
+	header := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, stack := range bytes.Split(stacks, []byte("\n\n")) {
+		if bytes.HasPrefix(stack, header) {
+			return stack
+		}
+	}
+	return nil
+}
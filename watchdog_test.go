@@ -0,0 +1,91 @@
+package workerpool
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	t.Parallel()
+
+	threshold := 50 * time.Millisecond
+	reports := make(chan StuckTask, 10)
+	wp := New(2, WithWatchdog(threshold, func(st StuckTask) {
+		reports <- st
+	}))
+	defer wp.Stop()
+
+	// Quick tasks are not reported.
+	for i := 0; i < 10; i++ {
+		wp.Submit(func() {})
+	}
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+
+	var st StuckTask
+	select {
+	case st = <-reports:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stuck task not reported")
+	}
+	if st.Running < threshold {
+		t.Fatal("reported task before threshold:", st.Running)
+	}
+	if !bytes.Contains(st.Stack, []byte("TestWatchdog")) {
+		t.Fatalf("stack does not show stuck task:\n%s", st.Stack)
+	}
+
+	// The same task is not reported again.
+	select {
+	case <-reports:
+		t.Fatal("stuck task reported more than once")
+	case <-time.After(3 * threshold):
+	}
+	close(release)
+	wp.Wait()
+
+	select {
+	case st = <-reports:
+		t.Fatal("unexpected report after task completed")
+	case <-time.After(2 * threshold):
+	}
+}
+
+func stuckTask(release chan struct{}) {
+	<-release
+}
+
+func TestStackTraces(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2, WithDebugDump())
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	wp.SubmitWithMetadata(Metadata{Name: "stuck"}, func() { stuckTask(release) })
+	time.Sleep(50 * time.Millisecond)
+	wp.Submit(func() { <-release })
+	time.Sleep(10 * time.Millisecond)
+
+	traces := wp.StackTraces(30 * time.Millisecond)
+	if len(traces) != 1 {
+		t.Fatal("expected 1 stack trace, got", len(traces))
+	}
+	if traces[0].Name != "stuck" {
+		t.Fatal("wrong task name:", traces[0].Name)
+	}
+	if !bytes.Contains(traces[0].Stack, []byte("stuckTask")) {
+		t.Fatal("stack does not contain task function:", string(traces[0].Stack))
+	}
+	if n := len(wp.StackTraces(0)); n != 2 {
+		t.Fatal("expected 2 stack traces, got", n)
+	}
+	close(release)
+
+	wp2 := New(1)
+	defer wp2.Stop()
+	if wp2.StackTraces(0) != nil {
+		t.Fatal("expected no stack traces without task tracking")
+	}
+}
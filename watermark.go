@@ -0,0 +1,119 @@
+package workerpool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Watermarks configures when the worker pool is saturated, set using
+// WithWatermarks.  A zero high watermark is not checked.
+type Watermarks struct {
+	// High is the number of waiting tasks at which the worker pool becomes
+	// saturated.
+	High int
+	// Low is the number of waiting tasks at or below which a saturated
+	// worker pool is no longer saturated.
+	Low int
+	// HighWait is the queue wait, of the last task given to a worker, above
+	// which the worker pool becomes saturated.
+	HighWait time.Duration
+	// LowWait is the queue wait at or below which a saturated worker pool is
+	// no longer saturated.
+	LowWait time.Duration
+}
+
+// WithWatermarks signals when the worker pool becomes saturated, because its
+// waiting tasks or their queue wait reach a high watermark, and when it is no
+// longer saturated, because both fall to their low watermarks.  Producers can
+// use Saturated and Unsaturated to pause and resume submitting tasks, without
+// polling WaitingQueueSize.
+func WithWatermarks(watermarks Watermarks) Option {
+	return func(p *WorkerPool) {
+		if watermarks.High <= 0 && watermarks.HighWait <= 0 {
+			return
+		}
+		p.watermarks = &watermarkState{
+			Watermarks: watermarks,
+			saturated:  make(chan struct{}),
+			resumed:    make(chan struct{}),
+		}
+		close(p.watermarks.resumed)
+		if watermarks.HighWait > 0 {
+			p.recordEnqueue = true
+		}
+	}
+}
+
+// watermarkState holds the channels that signal saturation.
+type watermarkState struct {
+	Watermarks
+	mutex sync.Mutex
+	// saturated is closed while the worker pool is saturated, and resumed is
+	// closed while it is not.
+	saturated chan struct{}
+	resumed   chan struct{}
+	on        bool
+}
+
+// Saturated returns a channel that is closed when the worker pool is
+// saturated, as configured using WithWatermarks.  Once the pool is no longer
+// saturated, Saturated returns a new channel.  Without watermarks, the channel
+// is never closed.
+func (p *WorkerPool) Saturated() <-chan struct{} {
+	w := p.watermarks
+	if w == nil {
+		return nil
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.saturated
+}
+
+// Unsaturated returns a channel that is closed when the worker pool is not
+// saturated.  Once the pool becomes saturated, Unsaturated returns a new
+// channel.  Without watermarks, the channel is always closed.
+func (p *WorkerPool) Unsaturated() <-chan struct{} {
+	w := p.watermarks
+	if w == nil {
+		return closedChan
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.resumed
+}
+
+// closedChan is a channel that is always closed.
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// checkWatermarks updates the saturation signals from the number of waiting
+// tasks and the last queue wait.  Only called by the dispatcher.
+func (p *WorkerPool) checkWatermarks(waiting int) {
+	w := p.watermarks
+	wait := time.Duration(atomic.LoadInt64(&p.queueWait))
+	if waiting == 0 {
+		// The last queue wait is stale once no tasks are waiting.
+		wait = 0
+	}
+	if !w.on {
+		if (w.High > 0 && waiting >= w.High) || (w.HighWait > 0 && wait > w.HighWait) {
+			w.mutex.Lock()
+			w.on = true
+			close(w.saturated)
+			w.resumed = make(chan struct{})
+			w.mutex.Unlock()
+		}
+		return
+	}
+	if (w.High <= 0 || waiting <= w.Low) && (w.HighWait <= 0 || wait <= w.LowWait) {
+		w.mutex.Lock()
+		w.on = false
+		close(w.resumed)
+		w.saturated = make(chan struct{})
+		w.mutex.Unlock()
+	}
+}
@@ -0,0 +1,72 @@
+package workerpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatermarks(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1, WithWatermarks(Watermarks{High: 4, Low: 1}))
+	defer wp.Stop()
+
+	select {
+	case <-wp.Unsaturated():
+	default:
+		t.Fatal("new pool should not be saturated")
+	}
+
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	for i := 0; i < 3; i++ {
+		wp.Submit(func() { time.Sleep(5 * time.Millisecond) })
+	}
+	select {
+	case <-wp.Saturated():
+		t.Fatal("pool should not be saturated below high watermark")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	wp.Submit(func() { time.Sleep(5 * time.Millisecond) })
+	select {
+	case <-wp.Saturated():
+	case <-time.After(time.Second):
+		t.Fatal("pool should be saturated at high watermark")
+	}
+	select {
+	case <-wp.Unsaturated():
+		t.Fatal("saturated pool should not signal unsaturated")
+	default:
+	}
+
+	close(release)
+	select {
+	case <-wp.Unsaturated():
+	case <-time.After(time.Second):
+		t.Fatal("pool should not be saturated at low watermark")
+	}
+	select {
+	case <-wp.Saturated():
+		t.Fatal("pool should not stay saturated")
+	default:
+	}
+}
+
+func TestWatermarksUnset(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	defer wp.Stop()
+
+	select {
+	case <-wp.Unsaturated():
+	default:
+		t.Fatal("pool without watermarks should not be saturated")
+	}
+	select {
+	case <-wp.Saturated():
+		t.Fatal("pool without watermarks should never be saturated")
+	default:
+	}
+}
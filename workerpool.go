@@ -1,9 +1,12 @@
 package workerpool
 
 import (
-	"github.com/gammazero/deque"
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/gammazero/deque"
 )
 
 const (
@@ -15,62 +18,623 @@ const (
 	// If worker pool receives no new work for this period of time, then stop
 	// a worker goroutine.
 	idleTimeoutSec = 5
+
+	// This is the maximum number of queued tasks that the dispatcher gives to
+	// a worker at once.  Giving a worker a batch of tasks reduces the number
+	// of channel operations per task when there is a backlog of waiting tasks.
+	maxBatchSize = 16
 )
 
+// Option configures optional behavior of a WorkerPool.  Options are given to
+// New when creating the worker pool.
+type Option func(*WorkerPool)
+
 // New creates and starts a pool of worker goroutines.
 //
 // The maxWorkers parameter specifies the maximum number of workers that will
 // execute tasks concurrently.  After each timeout period, a worker goroutine
-// is stopped until there are no remaining workers.
-func New(maxWorkers int) *WorkerPool {
+// is stopped until there are no remaining workers, or only the minimum number
+// set by WithMinWorkers.
+func New(maxWorkers int, options ...Option) *WorkerPool {
 	// There must be at least one worker.
 	if maxWorkers < 1 {
 		maxWorkers = 1
 	}
 
 	pool := &WorkerPool{
-		taskQueue:    make(chan func(), 1),
 		maxWorkers:   maxWorkers,
-		readyWorkers: make(chan chan func(), readyQueueSize),
+		readyWorkers: make(chan chan *task, readyQueueSize),
 		timeout:      time.Second * idleTimeoutSec,
-		stoppedChan:  make(chan struct{}),
+		workQueues:   map[*workQueue]struct{}{},
+		keyed:        map[string]*Future{},
+		idleChan:     make(chan struct{}),
+		epochPending: map[uint64]int{},
+	}
+	close(pool.idleChan)
+	pool.submitted = make(chan struct{}, 1)
+	pool.submitQueue.init()
+	pool.options = append([]Option(nil), options...)
+	for _, option := range options {
+		option(pool)
+	}
+	if pool.newQueue == nil {
+		pool.newQueue = NewQueue
+	}
+	if pool.clock == nil {
+		pool.clock = systemClock{}
+	}
+	if pool.timeoutGrace == 0 {
+		pool.timeoutGrace = defaultTimeoutGrace
+	}
+	if pool.minWorkers > pool.maxWorkers {
+		pool.minWorkers = pool.maxWorkers
 	}
+	if pool.adaptive != nil {
+		pool.adaptive.init(pool.maxWorkers)
+	}
+	pool.start()
+	return pool
+}
+
+// start creates the channels used while the worker pool is running, and
+// starts the dispatcher and any other goroutines.  When restarting, tasks
+// left in the waiting queue were abandoned when stopping, unless the worker
+// pool was suspended, and tasks submitted while suspended remain in the submit
+// queue to be received by the new dispatcher.
+func (p *WorkerPool) start() {
+	if !p.suspended {
+		p.waitingQueue = waitQueue{newQueue: p.newQueue}
+	}
+	p.suspended = false
+	p.submitMutex.Lock()
+	p.submitClosed = false
+	p.submitMutex.Unlock()
+	p.checkpoint = nil
+	p.stopResult.Store(nil)
+	p.stopChan = make(chan struct{})
+	rs := &runState{
+		stopped: make(chan struct{}),
+		abandon: make(chan struct{}),
+	}
+	rs.ctx, rs.cancel = context.WithCancelCause(context.Background())
+	p.runState.Store(rs)
+
+	if p.watchdog != nil {
+		go p.runWatchdog(rs.stopped)
+	}
+	if p.profiler != nil {
+		go p.runProfiler(rs.stopped)
+	}
+	// The minimum workers are started by the dispatcher, which starts all
+	// workers, so that isWorker can identify them.
+	atomic.AddInt32(&p.workerCount, int32(p.minWorkers))
+	p.register()
 
 	// Start the task dispatcher.
-	go pool.dispatch()
+	go p.dispatch()
+}
 
-	return pool
+// runState holds the channels and context of one run of the worker pool,
+// from when it is started until it has stopped.  Reboot replaces it, so it is
+// loaded atomically by functions that can be called at any time, which cannot
+// take stopMutex while a stop holds it to wait for running tasks.
+type runState struct {
+	// stopped is closed once the worker pool has stopped.
+	stopped chan struct{}
+	// abandon is closed, and ctx canceled, when queued tasks are abandoned.
+	abandon     chan struct{}
+	abandonOnce sync.Once
+	ctx         context.Context
+	cancel      context.CancelCauseFunc
+}
+
+// task is a function submitted to the worker pool, along with the information
+// used to track it until it completes.
+type task struct {
+	fn    func()
+	next  atomic.Pointer[task]
+	epoch uint64
+	// ctxFn and timeout are set for tasks submitted with SubmitWithTimeout,
+	// which are executed using ctxFn instead of fn.  A task submitted with
+	// SubmitTask has ctxFn set to its function, which fn calls.
+	ctxFn   func(context.Context)
+	timeout time.Duration
+	// batch holds the tasks submitted together by SubmitAll, or given to a
+	// worker together by the dispatcher.  A task with a batch only carries the
+	// batch and has no function.
+	batch []*task
+	// tenant is the name of the tenant that submitted the task, and quota is
+	// the tenant's quota, if it has one.
+	tenant string
+	quota  *tenantQuota
+	// finished is set, instead of a function, on a message from a worker
+	// telling the dispatcher that a task of the tenant with this quota has
+	// completed.
+	finished *tenantQuota
+	// serializable is the task submitted using SubmitSerializable.
+	serializable SerializableTask
+	// meta is the metadata submitted with the task, if any.
+	meta *Metadata
+	// priority is the priority of a task submitted using SubmitTask, and
+	// sheddable is set on these tasks to allow load shedding to reject them.
+	priority  int
+	sheddable bool
+	// cancel cancels the context of a running task submitted using
+	// SubmitTask, when preemption is used, and preempted is set once the
+	// task is preempted.
+	cancel    atomic.Pointer[context.CancelCauseFunc]
+	preempted int32
+	// cpuTime is the CPU time used by the task, if recorded.
+	cpuTime time.Duration
+	// state is the task's run state, which records whether the task has
+	// started or been canceled.
+	state int32
+	// errFn is the function of a task that returns an error, which fn calls
+	// and stores the returned error in err.  errDone, if not nil, is given the
+	// error once errFn returns, or a *PanicError if the task's panic is
+	// recovered.
+	errFn   func() error
+	err     error
+	errDone func(error)
+	// dropped, if not nil, is called if the task is dropped without running
+	// because the worker pool was stopped.
+	dropped func()
+	// refused, if not nil, is called instead of queuing the task when no
+	// worker can start it right away.  The task is canceled.
+	refused func()
+	// panicked is set when the task panics, if panics are recovered.
+	panicked bool
+	// expired is set when a task submitted using SubmitTask is not run
+	// because its context is done.
+	expired bool
+	// waited is set on a task whose submitter waits for the task's own
+	// function to start, so that it is not degraded.
+	waited bool
+	// id identifies the task.  Control markers and batches do not have IDs.
+	id TaskID
+	// progress is the progress of a task submitted using SubmitTask with a
+	// Progress.
+	progress *Progress
+	// pause is set on the tasks that pause workers, which do not count
+	// against concurrency limits.
+	pause bool
+	// needed, if not nil, is called before the task starts, and the task is
+	// skipped if it returns false.
+	needed func() bool
+	// enqueued is the time the task was submitted, if recorded for Dump.
+	enqueued time.Time
+	// dump is set, instead of a function, on a request from Dump for the
+	// waiting tasks.
+	dump chan<- Dump
+}
+
+// idleWorker is a ready worker held by the dispatcher when using LIFO worker
+// selection.
+type idleWorker struct {
+	taskChan chan *task
+	since    time.Time
+}
+
+// WithLIFOWorkers makes the dispatcher give tasks to the most recently ready
+// worker, instead of the worker that has been ready the longest.  This keeps
+// the stacks and caches of busy workers warm, and leaves the remaining workers
+// idle so that they are stopped after the idle timeout, even when tasks
+// continue to arrive at a rate that only needs some of the workers.
+func WithLIFOWorkers() Option {
+	return func(p *WorkerPool) {
+		p.lifo = true
+	}
+}
+
+// WithStrictOrder makes the dispatcher give waiting tasks to workers one at a
+// time, in the order that they were submitted.  By default, when there is a
+// backlog, a worker is given a batch of waiting tasks, and idle workers steal
+// tasks from other workers' batches, so that tasks submitted later may start
+// before tasks submitted earlier.  Tasks of a tenant that is held by its quota
+// still wait for the tenant's running tasks, as with SubmitTenant.
+func WithStrictOrder() Option {
+	return func(p *WorkerPool) {
+		p.strictOrder = true
+	}
+}
+
+// WithIdleTimeout sets the period of time that the worker pool must receive
+// no new tasks before an idle worker is stopped.  The default is 5 seconds.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(p *WorkerPool) {
+		if timeout > 0 {
+			p.timeout = timeout
+		}
+	}
+}
+
+// WithMinWorkers starts n workers when the worker pool is created, and keeps
+// at least n workers running instead of stopping them when idle.  This avoids
+// the latency of starting workers for a pool that receives bursts of tasks
+// after idle periods.  The number is limited to the maximum number of workers.
+func WithMinWorkers(n int) Option {
+	return func(p *WorkerPool) {
+		p.minWorkers = n
+	}
+}
+
+// WithMaxWorkers sets the maximum number of workers, replacing the number
+// given to New.  This is useful with Clone.  There must be at least one.
+func WithMaxWorkers(n int) Option {
+	return func(p *WorkerPool) {
+		if n > 0 {
+			p.maxWorkers = n
+		}
+	}
+}
+
+// WithOnIdle sets a function that is called each time the worker pool becomes
+// idle, when the last queued or running task completes.  This can be used to
+// trigger actions when a batch of work is complete, or to decide when to scale
+// down resources.
+//
+// The function is called by the goroutine that completed the last task, and
+// should return quickly.  Since the pool may become busy and idle again before
+// the function returns, calls may overlap.  The function may submit tasks.
+func WithOnIdle(fn func()) Option {
+	return func(p *WorkerPool) {
+		p.onIdle = fn
+	}
+}
+
+// WithOnDropped sets a function that is called with each queued task that is
+// dropped, without running, because the worker pool was stopped using Stop.
+// This lets the caller resubmit or record work that was not done, instead of
+// it being lost silently.  Tasks saved by Checkpoint are not dropped.
+//
+// The function is called by the dispatcher or by a worker while the pool is
+// stopping, and must not submit tasks to the stopping worker pool.
+func WithOnDropped(fn func(task func())) Option {
+	return func(p *WorkerPool) {
+		p.onDropped = fn
+	}
+}
+
+// flushWaiter is a call to Flush waiting for the remaining tasks submitted
+// before its epoch ended to complete.
+type flushWaiter struct {
+	epoch     uint64
+	remaining int
+	done      chan struct{}
 }
 
 // WorkerPool is a collection of goroutines, where the number of concurrent
 // goroutines processing requests does not exceed the specified maximum.
 type WorkerPool struct {
-	maxWorkers   int
-	timeout      time.Duration
-	taskQueue    chan func()
-	readyWorkers chan chan func()
-	stoppedChan  chan struct{}
-	waitingQueue deque.Deque
-	stopMutex    sync.Mutex
-	stopped      bool
+	name            string
+	options         []Option
+	maxWorkers      int
+	minWorkers      int
+	timeout         time.Duration
+	submitQueue     submitQueue
+	submitted       chan struct{}
+	submitSignaled  int32
+	submitMutex     sync.RWMutex
+	submitClosed    bool
+	stopChan        chan struct{}
+	stopWait        bool
+	suspended       bool
+	stopReason      StopReason
+	stopRan         int64
+	stopDropped     int64
+	stopResult      atomic.Pointer[StopResult]
+	readyWorkers    chan chan *task
+	runState        atomic.Pointer[runState]
+	stealMutex      sync.Mutex
+	workQueues      map[*workQueue]struct{}
+	stealable       int32
+	workerCount     int32
+	busyWorkers     int32
+	waitingQueue    waitQueue
+	newQueue        func() Queue
+	waiting         int32
+	quotas          map[string]*tenantQuota
+	limiter         *Limiter
+	adaptive        *adaptiveLimit
+	slo             *sloState
+	parent          *WorkerPool
+	childMutex      sync.Mutex
+	children        map[*WorkerPool]struct{}
+	childrenStopped bool
+	lifo            bool
+	strictOrder     bool
+	idleWorkers     deque.Deque
+	stopMutex       sync.Mutex
+	stopped         bool
+	pendingMutex    sync.Mutex
+	pending         int
+	completed       uint64
+	lastTaskID      TaskID
+	taskIndex       *taskIndex
+	idleChan        chan struct{}
+	epoch           uint64
+	epochPending    map[uint64]int
+	flushes         []*flushWaiter
+	onIdle          func()
+	onDropped       func(task func())
+	onReject        func(task func(), reason RejectReason)
+	ranTasks        int64
+	droppedTasks    int64
+	skippedTasks    int64
+	trackTasks      bool
+	workerMutex     sync.Mutex
+	workerStates    map[uint64]*workerState
+	workerGroup     sync.WaitGroup
+	spawners        map[uint64]int
+	reentrant       func(error)
+	inline          bool
+	watchdog        *watchdog
+	panicBackoff    *panicBackoff
+	backingOff      int32
+	profiler        *profiler
+	timeoutGrace    time.Duration
+	workerInterval  time.Duration
+	smoothing       time.Duration
+	smoothNext      int64
+	abandonedTasks  int64
+	health          *health
+	debugDump       bool
+	recordEnqueue   bool
+	events          chan Event
+	metrics         MetricsSink
+	synchronous     bool
+	clock           Clock
+	chaos           *chaosInjector
+	breaker         *Breaker
+	circuitMutex    sync.Mutex
+	circuits        map[string]*circuit
+	shedding        *Shedding
+	preemption      *Preemption
+	watermarks      *watermarkState
+	admission       Admission
+	cpuTime         bool
+	taskCPUTime     int64
+	queueWaitStats  bool
+	queueWaitCount  int64
+	queueWaitTotal  int64
+	queueWaitMax    int64
+	utilization     *utilization
+	callbacks       *WorkerPool
+	errors          chan TaskError
+	droppedErrors   int64
+	queueWait       int64
+	droppedEvents   int64
+	heartbeat       uint64
+	spill           *spillQueue
+	checkpoint      *checkpoint
+	spilledTasks    int64
+	spillErrors     int64
+	batchers        map[string]*keyBatch
+	semaphores      semaphoreQueue
+	delayMutex      sync.Mutex
+	delayed         map[*task]*time.Timer
+	prefetch        int
+	keyedMutex      sync.Mutex
+	keyed           map[string]*Future
+	cacheTTL        time.Duration
+	cache           map[string]cachedResult
+	cacheSweep      int
 }
 
 // Stop stops the worker pool and waits for only currently running tasks to
 // complete.  Pending tasks that are not currently running are abandoned.
-// Tasks must not be submitted to the worker pool after calling stop.
+// Tasks submitted after calling Stop are dropped, and reported to the
+// functions set using WithOnDropped and WithOnReject, and the submit functions
+// that return an error return ErrStopped.  The worker pool's Context is
+// canceled, so that running tasks submitted using SubmitContext can return
+// early.
 //
 // Since creating the worker pool starts at least one goroutine, for the
 // dispatcher, Stop() or StopWait() should be called when the worker pool is no
 // longer needed.
+//
+// If Stop is called by a task running in the worker pool, or in a parent pool
+// that runs this pool's tasks, it returns without waiting, since the calling
+// task cannot complete until Stop returns.  The worker pool finishes stopping
+// after the task returns, and the channel returned by Done is closed then.
 func (p *WorkerPool) Stop() {
-	p.stop(false)
+	p.stop(false, StoppedByStop)
 }
 
 // StopWait stops the worker pool and waits for all queued tasks tasks to
 // complete.  No additional tasks may be submitted, but all pending tasks are
-// executed by workers before this function returns.
+// executed by workers before this function returns.  Like Stop, StopWait
+// returns without waiting if called by a task running in the worker pool.
 func (p *WorkerPool) StopWait() {
-	p.stop(true)
+	p.stop(true, StoppedByStopWait)
+}
+
+// Release stops the worker pool without waiting for running tasks to
+// complete, so that it can later be restarted using Reboot.  Queued tasks are
+// abandoned, the same as with Stop.  Running tasks complete in the background,
+// and the channel returned by Done is closed once they have.
+func (p *WorkerPool) Release() {
+	p.stopMutex.Lock()
+	defer p.stopMutex.Unlock()
+	if !p.stopped {
+		p.beginStop(false, StoppedByRelease)
+	}
+}
+
+// Suspend stops the worker pool so that it can later be restarted using
+// Reboot, keeping the tasks that are waiting for a worker instead of
+// abandoning them.  The kept tasks are executed after the worker pool is
+// restarted.  Suspend waits for running tasks, and the remainder of any batch
+// given to a worker, to complete.  Child pools are stopped as with Stop.
+//
+// The kept tasks are still pending, so Wait and Flush do not return until they
+// have run after a restart.  Tasks submitted while the worker pool is
+// suspended are kept the same way.
+func (p *WorkerPool) Suspend() {
+	p.stop(false, StoppedBySuspend)
+}
+
+// Reboot restarts a worker pool that was stopped, keeping its configuration,
+// counters, and name, and the waiting tasks kept by Suspend.  This lets a
+// long-lived service stop processing between phases of work without replacing
+// the worker pool.  Reboot does nothing if the worker pool is running.
+//
+// Reboot can be called while other goroutines submit tasks.  A task submitted
+// before the restart is dropped if the worker pool was stopped, or kept if it
+// was suspended.  If the worker pool is still stopping, such as after Release,
+// Reboot waits for it to finish stopping, so it must not be called by a task
+// running in the worker pool.  The channel returned by Done before the restart
+// stays closed.
+func (p *WorkerPool) Reboot() {
+	p.stopMutex.Lock()
+	defer p.stopMutex.Unlock()
+	if !p.stopped {
+		return
+	}
+	<-p.runState.Load().stopped
+	p.childMutex.Lock()
+	p.childrenStopped = false
+	p.childMutex.Unlock()
+	p.start()
+	p.stopped = false
+}
+
+// Done returns a channel that is closed when the worker pool has stopped,
+// after Stop or StopWait is called and all workers have finished.  This allows
+// waiting for the worker pool to stop in a select statement.
+func (p *WorkerPool) Done() <-chan struct{} {
+	return p.runState.Load().stopped
+}
+
+// WorkerCount returns the current number of workers.  Workers are started as
+// tasks are submitted, up to the maximum number of workers, and are stopped
+// when idle.
+func (p *WorkerPool) WorkerCount() int {
+	return int(atomic.LoadInt32(&p.workerCount))
+}
+
+// BusyWorkers returns the number of workers that are currently executing
+// tasks.
+func (p *WorkerPool) BusyWorkers() int {
+	return int(atomic.LoadInt32(&p.busyWorkers))
+}
+
+// IdleWorkers returns the number of workers that are waiting for tasks.
+func (p *WorkerPool) IdleWorkers() int {
+	// The counts are read separately, so a worker that is starting or
+	// finishing a task could be counted as both.
+	idle := atomic.LoadInt32(&p.workerCount) - atomic.LoadInt32(&p.busyWorkers)
+	if idle < 0 {
+		return 0
+	}
+	return int(idle)
+}
+
+// Size returns the maximum number of concurrent workers.
+func (p *WorkerPool) Size() int {
+	return p.maxWorkers
+}
+
+// WaitingQueueSize returns the number of tasks that are waiting for a worker.
+// Tasks that were submitted but not yet received by the dispatcher are not
+// counted.
+func (p *WorkerPool) WaitingQueueSize() int {
+	return int(atomic.LoadInt32(&p.waiting))
+}
+
+// Pause causes all workers to wait on the given Context, making them
+// unavailable to run tasks.  Pause returns when all workers are waiting.
+// Tasks can continue to be submitted to the worker pool, but are not executed
+// until the Context is canceled or times out.
+//
+// Calling Pause when the worker pool is already paused causes Pause to wait
+// until all previous pauses are canceled.  This allows a goroutine to take
+// control of pausing and unpausing the pool as soon as other goroutines have
+// unpaused it.
+//
+// Paused workers do not count against the limits of WithLimiter,
+// WithAdaptiveConcurrency, or WithSLO, so that all workers can be paused while
+// the limit is below the maximum number of workers.
+//
+// When the worker pool is stopped, workers are unpaused and queued tasks are
+// executed during StopWait.
+func (p *WorkerPool) Pause(ctx context.Context) {
+	p.stopMutex.Lock()
+	defer p.stopMutex.Unlock()
+	if p.stopped || p.synchronous {
+		return
+	}
+	ready := new(sync.WaitGroup)
+	ready.Add(p.maxWorkers)
+	for i := 0; i < p.maxWorkers; i++ {
+		t := p.newTask(func() {
+			ready.Done()
+			select {
+			case <-ctx.Done():
+			case <-p.stopChan:
+			}
+		})
+		t.pause = true
+		p.enqueue(t)
+	}
+	// Wait for workers to all be paused.
+	ready.Wait()
+}
+
+// Stats holds counters that describe the activity of a worker pool.
+type Stats struct {
+	// AbandonedTasks is the number of tasks, submitted with a timeout, that
+	// did not return within the grace period after their timeout.  The
+	// workers running these tasks were replaced, and their goroutines leaked
+	// until the tasks return.
+	AbandonedTasks int64
+	// SpilledTasks is the number of tasks that were written to disk while
+	// waiting, using WithSpill.
+	SpilledTasks int64
+	// SpillErrors is the number of spilled tasks that could not be read back
+	// from disk or decoded, and were dropped.
+	SpillErrors int64
+	// DroppedEvents is the number of events that were dropped because the
+	// events channel was full.
+	DroppedEvents int64
+	// TaskCPUTime is the total CPU time used by tasks, if recorded using
+	// WithCPUTime.
+	TaskCPUTime time.Duration
+	// DroppedErrors is the number of task failures that were dropped because
+	// the errors channel was full.
+	DroppedErrors int64
+	// QueueWaitCount is the number of tasks whose queue wait was recorded
+	// using WithQueueWaitStats.
+	QueueWaitCount int64
+	// QueueWaitTotal is the total time that these tasks waited between being
+	// submitted and starting.  Divide by QueueWaitCount for the average.
+	QueueWaitTotal time.Duration
+	// QueueWaitMax is the longest time that one of these tasks waited.
+	QueueWaitMax time.Duration
+	// DroppedTasks is the number of queued tasks that were not run because
+	// the worker pool was stopped using Stop, or because they were spilled
+	// and could not be read back.
+	DroppedTasks int64
+	// SkippedTasks is the number of tasks that were not run because they were
+	// no longer needed, when submitted using SubmitIfNeeded.
+	SkippedTasks int64
+}
+
+// Stats returns the worker pool's current counters.
+func (p *WorkerPool) Stats() Stats {
+	return Stats{
+		AbandonedTasks: atomic.LoadInt64(&p.abandonedTasks),
+		SpilledTasks:   atomic.LoadInt64(&p.spilledTasks),
+		SpillErrors:    atomic.LoadInt64(&p.spillErrors),
+		DroppedEvents:  atomic.LoadInt64(&p.droppedEvents),
+		TaskCPUTime:    time.Duration(atomic.LoadInt64(&p.taskCPUTime)),
+		DroppedErrors:  atomic.LoadInt64(&p.droppedErrors),
+		QueueWaitCount: atomic.LoadInt64(&p.queueWaitCount),
+		QueueWaitTotal: time.Duration(atomic.LoadInt64(&p.queueWaitTotal)),
+		QueueWaitMax:   time.Duration(atomic.LoadInt64(&p.queueWaitMax)),
+		DroppedTasks:   atomic.LoadInt64(&p.droppedTasks),
+		SkippedTasks:   atomic.LoadInt64(&p.skippedTasks),
+	}
 }
 
 // Stopped returns true if this worker pool has been stopped.
@@ -103,169 +667,903 @@ func (p *WorkerPool) Stopped() bool {
 // is no need to retain idle workers.
 func (p *WorkerPool) Submit(task func()) {
 	if task != nil {
-		p.taskQueue <- task
+		p.enqueueAdmitted(p.newTask(task))
 	}
 }
 
-// SubmitWait enqueues the given function and waits for it to be executed.
+// SubmitAll enqueues all of the given functions for workers to execute.  This
+// is the same as calling Submit for each function, except that the functions
+// are passed to the dispatcher together, reducing the overhead of submitting a
+// large number of tasks at once.  Nil functions are ignored.
+func (p *WorkerPool) SubmitAll(tasks ...func()) {
+	batch := p.admitAll(p.newTasks(tasks))
+	if len(batch) != 0 {
+		p.enqueue(&task{batch: batch})
+	}
+}
+
+// SubmitWait enqueues the given function and waits for it to be executed.  If
+// the worker pool is stopped without executing the function, SubmitWait
+// returns once the function is dropped.
 func (p *WorkerPool) SubmitWait(task func()) {
 	if task == nil {
 		return
 	}
-	doneChan := make(chan struct{})
-	p.taskQueue <- func() {
+	if p.nested("SubmitWait") {
 		task()
-		close(doneChan)
+		return
 	}
+	doneChan := make(chan struct{})
+	t := p.newTask(func() {
+		// Signal completion even if the task panics and the panic is
+		// recovered.
+		defer close(doneChan)
+		task()
+	})
+	// Stop waiting if the worker pool is stopped without running the task.
+	t.dropped = func() { close(doneChan) }
+	p.enqueue(t)
 	<-doneChan
 }
 
+// SubmitWaitContext enqueues the given function, the same as Submit, and waits
+// for it to be executed.  If ctx is done before a worker starts the function,
+// the function is canceled and the context's error is returned.  Once the
+// function starts, SubmitWaitContext waits for it to return.  A panic in the
+// function is recovered on the worker, and raised again in the calling
+// goroutine.
+//
+// Returns ErrQueueFull if the function is rejected by the admission function,
+// set using WithAdmission, or ErrStopped if the worker pool is stopped without
+// executing the function.
+func (p *WorkerPool) SubmitWaitContext(ctx context.Context, task func()) error {
+	if task == nil {
+		return nil
+	}
+	if p.nested("SubmitWaitContext") {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		task()
+		return nil
+	}
+	var panicVal interface{}
+	doneChan := make(chan struct{})
+	droppedChan := make(chan struct{})
+	t := p.newTask(func() {
+		defer func() {
+			panicVal = recover()
+			close(doneChan)
+		}()
+		task()
+	})
+	t.waited = true
+	t.dropped = func() { close(droppedChan) }
+	if err := p.enqueueAdmitted(t); err != nil {
+		return err
+	}
+	select {
+	case <-doneChan:
+	case <-droppedChan:
+		return ErrStopped
+	case <-ctx.Done():
+		if p.discard(t) {
+			return ctx.Err()
+		}
+		// The function has started, or is being dropped.
+		select {
+		case <-doneChan:
+		case <-droppedChan:
+			return ErrStopped
+		}
+	}
+	if panicVal != nil {
+		panic(panicVal)
+	}
+	return nil
+}
+
+// SubmitAllWait enqueues all of the given functions together, the same as
+// SubmitAll, and waits for all of them to be executed.  Returns the error
+// returned by each function, in the same order as the functions.  Nil
+// functions are ignored, and have a nil error.  Functions that are dropped
+// because the worker pool is stopped have ErrStopped.
+func (p *WorkerPool) SubmitAllWait(tasks []func() error) []error {
+	return p.SubmitAllWaitContext(context.Background(), tasks)
+}
+
+// SubmitAllWaitContext is the same as SubmitAllWait, except that functions
+// that have not started when ctx is done are canceled, and have the context's
+// error.  It still waits for the functions that have started to return, but
+// not for canceled functions to reach a worker.
+func (p *WorkerPool) SubmitAllWaitContext(ctx context.Context, tasks []func() error) []error {
+	errs := make([]error, len(tasks))
+	if p.nested("SubmitAllWait") {
+		for i, task := range tasks {
+			if task == nil {
+				continue
+			}
+			if errs[i] = ctx.Err(); errs[i] == nil {
+				errs[i] = task()
+			}
+		}
+		return errs
+	}
+	var wg sync.WaitGroup
+	fns := make([]func(), len(tasks))
+	var indexes []int
+	for i, task := range tasks {
+		if task == nil {
+			continue
+		}
+		i, task := i, task
+		wg.Add(1)
+		fns[i] = func() {
+			defer wg.Done()
+			if errs[i] = ctx.Err(); errs[i] == nil {
+				errs[i] = task()
+			}
+		}
+		indexes = append(indexes, i)
+	}
+	batch := p.newTasks(fns)
+	for j, t := range batch {
+		i := indexes[j]
+		t.dropped = func() {
+			errs[i] = ErrStopped
+			wg.Done()
+		}
+	}
+	if len(batch) != 0 {
+		p.enqueue(&task{batch: batch})
+	}
+	if ctx.Done() == nil {
+		wg.Wait()
+		return errs
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return errs
+	case <-ctx.Done():
+	}
+	// Cancel the tasks that have not started, so that only the running
+	// tasks are waited for.
+	for j, t := range batch {
+		if p.discard(t) {
+			errs[indexes[j]] = ctx.Err()
+			wg.Done()
+		}
+	}
+	<-done
+	return errs
+}
+
+// Wait blocks until all tasks submitted so far have completed, without
+// stopping the worker pool.  Tasks may continue to be submitted while waiting,
+// and Wait returns when there are no tasks queued or running.  This allows
+// a program to process work in phases, waiting for each phase to complete
+// before starting the next, while reusing the same worker pool.
+//
+// Wait must not be called from within a task, since the calling task is
+// itself never complete while waiting.
+func (p *WorkerPool) Wait() {
+	p.WaitContext(context.Background())
+}
+
+// WaitContext is the same as Wait, except that it returns the context's error
+// if ctx is done before all tasks have completed.  This drains the worker pool
+// without stopping it: when it returns nil, no tasks are queued or running,
+// and the pool accepts new tasks as before.
+func (p *WorkerPool) WaitContext(ctx context.Context) error {
+	p.checkReentrant("Wait")
+	p.pendingMutex.Lock()
+	idleChan := p.idleChan
+	p.pendingMutex.Unlock()
+	select {
+	case <-idleChan:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush returns once all tasks submitted before calling Flush have completed.
+// Unlike Wait, tasks submitted after calling Flush are not waited for, so
+// Flush returns even if the worker pool is continuously busy.  This is useful
+// for checkpointing progress of a long-running job.
+//
+// Flush must not be called from within a task, since the calling task is
+// itself never complete while waiting.
+func (p *WorkerPool) Flush() {
+	p.FlushContext(context.Background())
+}
+
+// FlushContext is the same as Flush, except that it returns the context's
+// error if ctx is done before the tasks have completed.
+func (p *WorkerPool) FlushContext(ctx context.Context) error {
+	p.checkReentrant("Flush")
+	p.pendingMutex.Lock()
+	fw := &flushWaiter{epoch: p.epoch}
+	p.epoch++
+	for e, n := range p.epochPending {
+		if e <= fw.epoch {
+			fw.remaining += n
+		}
+	}
+	if fw.remaining == 0 {
+		p.pendingMutex.Unlock()
+		return nil
+	}
+	fw.done = make(chan struct{})
+	p.flushes = append(p.flushes, fw)
+	p.pendingMutex.Unlock()
+
+	select {
+	case <-fw.done:
+		return nil
+	case <-ctx.Done():
+		p.pendingMutex.Lock()
+		for i := range p.flushes {
+			if p.flushes[i] == fw {
+				p.flushes = append(p.flushes[:i], p.flushes[i+1:]...)
+				break
+			}
+		}
+		p.pendingMutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// newTask creates a task for the function and records that it is pending.
+func (p *WorkerPool) newTask(fn func()) *task {
+	t := &task{fn: fn}
+	p.pendingMutex.Lock()
+	p.addPending(t)
+	p.pendingMutex.Unlock()
+	return t
+}
+
+// newTasks creates tasks for the non-nil functions and records that they are
+// pending.
+func (p *WorkerPool) newTasks(fns []func()) []*task {
+	tasks := make([]*task, 0, len(fns))
+	p.pendingMutex.Lock()
+	for _, fn := range fns {
+		if fn != nil {
+			t := &task{fn: fn}
+			p.addPending(t)
+			tasks = append(tasks, t)
+		}
+	}
+	p.pendingMutex.Unlock()
+	return tasks
+}
+
+// addPending records that the task is pending in the current epoch.
+//
+// Must be called with pendingMutex held.
+func (p *WorkerPool) addPending(t *task) {
+	if p.pending == 0 {
+		p.idleChan = make(chan struct{})
+	}
+	p.pending++
+	p.lastTaskID++
+	t.id = p.lastTaskID
+	p.taskIndex.add(t)
+	t.epoch = p.epoch
+	if p.recordEnqueue {
+		t.enqueued = time.Now()
+	}
+	p.epochPending[t.epoch]++
+}
+
+// taskDone records that a task was completed or abandoned, and releases any
+// waiters that no longer have remaining tasks.
+func (p *WorkerPool) taskDone(t *task) {
+	p.pendingMutex.Lock()
+	if p.epochPending[t.epoch]--; p.epochPending[t.epoch] == 0 {
+		delete(p.epochPending, t.epoch)
+	}
+	if len(p.flushes) != 0 {
+		flushes := p.flushes[:0]
+		for _, fw := range p.flushes {
+			if t.epoch <= fw.epoch {
+				if fw.remaining--; fw.remaining == 0 {
+					close(fw.done)
+					continue
+				}
+			}
+			flushes = append(flushes, fw)
+		}
+		p.flushes = flushes
+	}
+	p.completed++
+	var idle bool
+	if p.pending--; p.pending == 0 {
+		close(p.idleChan)
+		idle = true
+	}
+	p.pendingMutex.Unlock()
+
+	if idle && p.onIdle != nil {
+		p.onIdle()
+	}
+}
+
 // dispatch sends the next queued task to an available worker.
 func (p *WorkerPool) dispatch() {
-	defer close(p.stoppedChan)
-	timeout := time.NewTimer(p.timeout)
+	rs := p.runState.Load()
+	defer close(rs.stopped)
+	defer p.endStopResult()
+	defer rs.cancel(ErrStopped)
+	defer p.removeSpawner(p.addSpawner())
+
+	for i := 0; i < p.minWorkers; i++ {
+		p.startWorker(nil)
+	}
+
+	// The idle timer is only armed while there are workers to stop.  Instead
+	// of resetting the timer each time tasks arrive, the time of the last
+	// activity is recorded.  When the timer fires, it is rearmed for the
+	// remaining time if there was activity since it was armed.
+	timeout := p.clock.NewTimer(p.timeout)
+	timeout.Stop()
 	var (
-		workerCount    int
-		task           func()
-		ok, wait       bool
-		workerTaskChan chan func()
+		wait           bool
+		timerArmed     bool
+		lastActive     time.Time
+		workerTaskChan chan *task
 	)
-	startReady := make(chan chan func())
+
+	// readyWorker returns the task channel of a ready worker, or nil if no
+	// worker is ready.  When using LIFO worker selection, the most recently
+	// ready worker is returned, and other workers that have been idle longer
+	// than the idle timeout are stopped.
+	readyWorker := func() chan *task {
+		if !p.lifo {
+			select {
+			case ch := <-p.readyWorkers:
+				return ch
+			default:
+				return nil
+			}
+		}
+		now := p.clock.Now()
+		p.collectIdleWorkers(now)
+		for p.idleWorkers.Len() > 1 && int(atomic.LoadInt32(&p.workerCount)) > p.minWorkers &&
+			now.Sub(p.idleWorkers.Front().(idleWorker).since) > p.timeout {
+			close(p.idleWorkers.PopFront().(idleWorker).taskChan)
+			atomic.AddInt32(&p.workerCount, -1)
+		}
+		if p.idleWorkers.Len() == 0 {
+			return nil
+		}
+		return p.idleWorkers.PopBack().(idleWorker).taskChan
+	}
+
+	// dispatchTask gives a task to a ready worker.  If no workers are ready,
+	// a new worker is started for the task, if not at max, otherwise the task
+	// is queued to be executed by the next available worker.
+	dispatchTask := func(t *task) {
+		if workerTaskChan = readyWorker(); workerTaskChan != nil {
+			// A worker is ready, so give task to worker.
+			p.waitingQueue.started(t)
+			workerTaskChan <- t
+		} else {
+			// No workers ready.
+			// Create a new worker, if not at max.
+			if int(atomic.LoadInt32(&p.workerCount)) < p.maxWorkers {
+				atomic.AddInt32(&p.workerCount, 1)
+				p.waitingQueue.started(t)
+				p.startWorker(t)
+				if !timerArmed {
+					timeout.Reset(p.timeout)
+					timerArmed = true
+				}
+			} else {
+				// Enqueue task to be executed by next available worker.
+				p.queueTask(t)
+			}
+		}
+	}
+
+	// acceptTask dispatches a submitted task, or each task in a submitted
+	// batch.  While there are tasks in the waiting queue, submitted tasks are
+	// queued behind them to preserve their order.  A message that a task has
+	// finished may let a held tenant's tasks run.
+	acceptTask := func(t *task) {
+		if t.finished != nil {
+			p.waitingQueue.finished(t.finished)
+			return
+		}
+		if t.dump != nil {
+			p.dumpWaiting(t.dump)
+			return
+		}
+		if t.batch == nil {
+			if p.waitingQueue.mustWait(t) {
+				p.queueTask(t)
+			} else {
+				dispatchTask(t)
+			}
+			return
+		}
+		for _, bt := range t.batch {
+			if p.waitingQueue.mustWait(bt) {
+				p.queueTask(bt)
+			} else {
+				dispatchTask(bt)
+			}
+		}
+	}
+
+	// Start workers for the tasks kept by Suspend.
+	for p.waitingQueue.runnable() != 0 && int(atomic.LoadInt32(&p.workerCount)) < p.maxWorkers {
+		atomic.AddInt32(&p.workerCount, 1)
+		p.startWorker(p.popWaiting())
+		if !timerArmed {
+			timeout.Reset(p.timeout)
+			timerArmed = true
+		}
+	}
 Loop:
 	for {
+		atomic.StoreInt32(&p.waiting, int32(p.waitingLen()))
+		if p.watermarks != nil {
+			p.checkWatermarks(p.waitingLen())
+		}
+		if p.health != nil {
+			atomic.AddUint64(&p.heartbeat, 1)
+		}
+		if p.metrics != nil {
+			p.metrics.Gauge(MetricWaitingTasks, float64(p.waitingLen()))
+			p.gaugeWorkers()
+		}
+
 		// As long as tasks are in the waiting queue, remove and execute these
 		// tasks as workers become available, and place new incoming tasks on
 		// the queue.  Once the queue is empty, then go back to submitting
-		// incoming tasks directly to available workers.
-		if p.waitingQueue.Len() != 0 {
+		// incoming tasks directly to available workers.  Tasks of tenants
+		// that are held by their quota do not keep the queue busy.
+		if p.waitingQueue.runnable() != 0 {
 			select {
-			case task, ok = <-p.taskQueue:
-				if !ok {
-					break Loop
-				}
-				if task == nil {
-					wait = true
-					break Loop
-				}
-				p.waitingQueue.PushBack(task)
+			case <-p.submitted:
+				p.receiveSubmitted(acceptTask)
+			case <-p.stopChan:
+				break Loop
 			case workerTaskChan = <-p.readyWorkers:
-				// A worker is ready, so give task to worker.
-				workerTaskChan <- p.waitingQueue.PopFront().(func())
+				// A worker is ready, so give queued tasks to worker.
+				workerTaskChan <- p.popWaiting()
+				if p.waitingQueue.runnable() == 0 {
+					// Start idle period once all queued tasks are running.
+					lastActive = p.clock.Now()
+				}
 			}
 			continue
 		}
-		timeout.Reset(p.timeout)
 		select {
-		case task, ok = <-p.taskQueue:
-			if !ok || task == nil {
-				break Loop
+		case <-p.submitted:
+			// Got tasks to do.
+			lastActive = p.clock.Now()
+			p.receiveSubmitted(acceptTask)
+		case <-p.stopChan:
+			break Loop
+		case <-timeout.C():
+			timerArmed = false
+			if idle := p.clock.Now().Sub(lastActive); idle < p.timeout {
+				// Work arrived since the timer was armed, so wait for the
+				// rest of the idle timeout.
+				timeout.Reset(p.timeout - idle)
+				timerArmed = true
+				continue
 			}
-			// Got a task to do.
-			select {
-			case workerTaskChan = <-p.readyWorkers:
-				// A worker is ready, so give task to worker.
-				workerTaskChan <- task
-			default:
-				// No workers ready.
-				// Create a new worker, if not at max.
-				if workerCount < p.maxWorkers {
-					workerCount++
-					go func(t func()) {
-						startWorker(startReady, p.readyWorkers)
-						// Submit the task when the new worker.
-						taskChan := <-startReady
-						taskChan <- t
-					}(task)
+			// Timed out waiting for work to arrive.  Kill a ready worker,
+			// unless only the minimum number of workers are running.
+			if int(atomic.LoadInt32(&p.workerCount)) > p.minWorkers {
+				if p.lifo {
+					p.collectIdleWorkers(p.clock.Now())
+					if p.idleWorkers.Len() != 0 {
+						// Kill the worker that has been idle the longest.
+						close(p.idleWorkers.PopFront().(idleWorker).taskChan)
+						atomic.AddInt32(&p.workerCount, -1)
+					}
 				} else {
-					// Enqueue task to be executed by next available worker.
-					p.waitingQueue.PushBack(task)
+					select {
+					case workerTaskChan = <-p.readyWorkers:
+						// A worker is ready, so kill.
+						close(workerTaskChan)
+						atomic.AddInt32(&p.workerCount, -1)
+					default:
+						// No work, but no ready workers.  All workers are
+						// busy.
+					}
 				}
 			}
-		case <-timeout.C:
-			// Timed out waiting for work to arrive.  Kill a ready worker.
-			if workerCount > 0 {
-				select {
-				case workerTaskChan = <-p.readyWorkers:
-					// A worker is ready, so kill.
-					close(workerTaskChan)
-					workerCount--
-				default:
-					// No work, but no ready workers.  All workers are busy.
-				}
+			if int(atomic.LoadInt32(&p.workerCount)) > p.minWorkers {
+				timeout.Reset(p.timeout)
+				timerArmed = true
 			}
 		}
 	}
 
+	// Accept the tasks submitted before stopping, the same as if they had
+	// been received before stopping.  Take any pending signal with them, so
+	// that a task submitted while the worker pool is suspended can signal the
+	// next dispatcher without blocking.
+	select {
+	case <-p.submitted:
+	default:
+	}
+	p.receiveSubmitted(acceptTask)
+	wait = p.stopWait
+
 	// If instructed to wait for all queued tasks, then remove from queue and
-	// give to workers until queue is empty.
+	// give to workers until queue is empty, unless forced to stop waiting.
+	// Otherwise, queued tasks are abandoned and no longer pending.
 	if wait {
-		for p.waitingQueue.Len() != 0 {
-			workerTaskChan = <-p.readyWorkers
-			// A worker is ready, so give task to worker.
-			workerTaskChan <- p.waitingQueue.PopFront().(func())
+	Drain:
+		for p.waitingQueue.len() != 0 {
+			if p.waitingQueue.runnable() == 0 {
+				// Wait for running tasks of held tenants to finish.
+				select {
+				case <-p.submitted:
+					p.receiveSubmitted(acceptTask)
+					continue
+				case <-rs.abandon:
+					break Drain
+				}
+			}
+			select {
+			case workerTaskChan = <-p.readyWorkers:
+				// A worker is ready, so give queued tasks to worker.
+				workerTaskChan <- p.popWaiting()
+			case <-rs.abandon:
+				break Drain
+			}
+		}
+	}
+	if p.waitingLen() != 0 && !p.suspended {
+		p.waitingQueue.each(func(t *task) {
+			if t.quota != nil {
+				t.quota.release()
+			}
+			p.abandon(t)
+		})
+		if p.spill != nil {
+			if p.checkpoint != nil || p.onDropped != nil || p.onReject != nil {
+				// Read back the spilled tasks to save or report them.
+				for p.spill.count != 0 {
+					t, err := p.spill.pop()
+					if t == nil {
+						break
+					}
+					if err != nil {
+						atomic.AddInt64(&p.spillErrors, 1)
+						t.serializable = nil
+					}
+					p.abandon(t)
+				}
+			}
+			p.spill.drop(p.drop)
 		}
 	}
+	if p.spill != nil && !p.suspended {
+		p.spill.close()
+	}
+
+	// Tasks kept by Suspend are still waiting.
+	atomic.StoreInt32(&p.waiting, int32(p.waitingLen()))
+	if p.watermarks != nil {
+		p.checkWatermarks(p.waitingLen())
+	}
 
 	// Stop all remaining workers as they become ready.
-	for workerCount > 0 {
+	for p.idleWorkers.Len() != 0 {
+		close(p.idleWorkers.PopFront().(idleWorker).taskChan)
+		atomic.AddInt32(&p.workerCount, -1)
+	}
+	for atomic.LoadInt32(&p.workerCount) > 0 {
 		workerTaskChan = <-p.readyWorkers
 		close(workerTaskChan)
-		workerCount--
+		atomic.AddInt32(&p.workerCount, -1)
+	}
+	// Wait for the stopped workers to exit, so that they do nothing, such as
+	// sending events, after the worker pool has stopped.
+	p.workerGroup.Wait()
+}
+
+// collectIdleWorkers moves all workers that are ready onto the back of the
+// idleWorkers deque, so that the most recently ready worker is at the back
+// and the worker that has been ready the longest is at the front.
+func (p *WorkerPool) collectIdleWorkers(now time.Time) {
+	for {
+		select {
+		case ch := <-p.readyWorkers:
+			p.idleWorkers.PushBack(idleWorker{ch, now})
+		default:
+			return
+		}
 	}
 }
 
-// startWorker starts a goroutine that executes tasks given by the dispatcher.
+// popWaiting removes the next task from the waiting queue.  When there are
+// many more waiting tasks than workers, and strict ordering is not used, a
+// batch of tasks is removed instead, so that a worker can execute them without
+// returning to the dispatcher for each one.  The batch size is limited to each
+// worker's share of the waiting tasks, so that one worker does not hold tasks
+// that other workers could start sooner.
 //
-// When a new worker starts, it registers its availability on the startReady
-// channel.  This ensures that the goroutine associated with starting the
-// worker gets to use the worker to execute its task.  Otherwise, the main
-// dispatcher loop could steal the new worker and not know to start up another
-// worker for the waiting goroutine.  The task would then have to wait for
-// another existing worker to become available, even though capacity is
-// available to start additional workers.
+// The waiting queue size is updated before the task is given to a worker, so
+// that it does not include tasks that have completed.
+func (p *WorkerPool) popWaiting() *task {
+	defer func() {
+		p.unspill()
+		atomic.StoreInt32(&p.waiting, int32(p.waitingLen()))
+	}()
+	n := p.waitingQueue.runnable() / p.maxWorkers
+	if n > maxBatchSize {
+		n = maxBatchSize
+	}
+	if n < 2 || p.strictOrder {
+		return p.waitingQueue.pop()
+	}
+	// Taking tasks may hold their tenants, leaving fewer runnable tasks.
+	batch := make([]*task, 0, n)
+	for len(batch) < n && p.waitingQueue.runnable() != 0 {
+		batch = append(batch, p.waitingQueue.pop())
+	}
+	return &task{batch: batch}
+}
+
+// startWorker starts a worker, which the dispatcher waits for when stopping.
+func (p *WorkerPool) startWorker(t *task) {
+	p.workerGroup.Add(1)
+	go p.worker(t)
+}
+
+// worker executes tasks given by the dispatcher, starting with the task that
+// the worker was started for.
+//
+// A new worker is started with its first task, instead of registering its
+// availability and waiting to be given a task.  This ensures that the task
+// that caused the worker to be started is executed by the new worker,
+// without needing another goroutine to wait for the worker to start.
 //
 // A worker registers that is it available to do work by putting its task
 // channel on the readyWorkers channel.  The dispatcher reads a worker's task
 // channel from the readyWorkers channel, and writes a task to the worker over
 // the worker's task channel.  To stop a worker, the dispatcher closes a
 // worker's task channel, instead of writing a task to it.
-func startWorker(startReady, readyWorkers chan chan func()) {
-	go func() {
-		taskChan := make(chan func())
-		var task func()
-		var ok bool
-		// Register availability on starReady channel.
-		startReady <- taskChan
-		for {
-			// Read task from dispatcher.
-			task, ok = <-taskChan
-			if !ok {
-				// Dispatcher has told worker to stop.
-				break
-			}
+//
+// A worker started with a nil task, to replace an abandoned worker, registers
+// its availability immediately.
+func (p *WorkerPool) worker(t *task) {
+	var abandoned bool
+	defer func() {
+		// An abandoned worker's replacement is waited for instead.
+		if !abandoned {
+			p.workerGroup.Done()
+		}
+	}()
+	taskChan := make(chan *task)
+	ws := p.addWorkerState()
+	ws.worker = true
+	defer p.removeWorkerState(ws)
+	if p.profiler != nil {
+		labelWorker(ws)
+	}
+	p.emit(WorkerStarted, nil)
+	defer p.emit(WorkerStopped, nil)
+	if p.chaos != nil {
+		p.chaos.workerStarted()
+	}
+	var ok bool
+	for {
+		if t != nil && p.execute(ws, t) {
+			// Worker was abandoned and replaced while running a task.
+			abandoned = true
+			return
+		}
 
-			// Execute the task.
-			task()
+		// Register availability on readyWorkers channel.
+		p.readyWorkers <- taskChan
 
-			// Register availability on readyWorkers channel.
-			readyWorkers <- taskChan
+		// Read task from dispatcher.
+		if t, ok = <-taskChan; !ok {
+			// Dispatcher has told worker to stop.
+			return
 		}
-	}()
+	}
+}
+
+// execute runs a task, or each task in a batch, given to a worker by the
+// dispatcher.  Afterwards, the worker helps busy workers by stealing the
+// remaining tasks from their batches.  Returns true if the worker was
+// abandoned while running a task.
+func (p *WorkerPool) execute(ws *workerState, t *task) bool {
+	p.addBusy(1)
+	p.gaugeWorkers()
+	var abandoned bool
+	if t.batch == nil {
+		abandoned = p.runTask(ws, t)
+	} else {
+		abandoned = p.runBatch(ws, t.batch)
+	}
+	for !abandoned {
+		stolen := p.steal()
+		if stolen == nil {
+			break
+		}
+		abandoned = p.runBatch(ws, stolen)
+	}
+	if abandoned {
+		// The replacement worker has taken over this worker's place.
+		return true
+	}
+	p.addBusy(-1)
+	p.gaugeWorkers()
+	return false
+}
+
+// runTask executes a task and records that it is done.  If the worker pool
+// shares a limiter, or has an adaptive concurrency limit, the task waits for
+// the limit before it starts.  If tasks are being tracked, the worker's state
+// records when the task started.
+// Returns true if the worker was abandoned while running the task.
+func (p *WorkerPool) runTask(ws *workerState, t *task) bool {
+	if (p.workerInterval != 0 && !p.paceWorker(ws)) ||
+		(p.smoothing != 0 && !p.smooth()) {
+		p.abandon(t)
+		return false
+	}
+	if t.needed != nil && !t.needed() {
+		p.skip(t)
+		p.taskFinished(t)
+		return false
+	}
+	if !t.claim() {
+		// The task was canceled while waiting.
+		return false
+	}
+	p.taskIndex.start(t)
+	limiter, adaptive := p.limiter, p.adaptive
+	if t.pause {
+		// A paused worker does no work, so it does not take a slot that a
+		// worker pausing after it would wait for.
+		limiter, adaptive = nil, nil
+	}
+	limiter.acquire()
+	started := adaptive.acquire()
+	if p.trackTasks {
+		ws.task.Store(t)
+		atomic.StoreInt64(&ws.started, time.Now().UnixNano())
+	}
+	p.emit(TaskStarted, t)
+	p.recordQueueWait(t)
+	var start time.Time
+	if p.metrics != nil {
+		start = time.Now()
+	}
+	var cpu cpuTimer
+	if p.cpuTime {
+		cpu.begin()
+	}
+	ran := true
+	switch {
+	case p.chaos != nil && p.chaos.beforeTask():
+		// The task is dropped.
+		ran = false
+		adaptive.release(started)
+		limiter.release()
+	case t.timeout != 0:
+		release := func() {
+			adaptive.release(started)
+			limiter.release()
+		}
+		if p.runWithTimeout(ws, t, release) {
+			if p.cpuTime {
+				cpu.end()
+			}
+			return true
+		}
+		release()
+	default:
+		p.execTask(t, t.fn)
+		adaptive.release(started)
+		limiter.release()
+	}
+	if ran && !t.expired {
+		atomic.AddInt64(&p.ranTasks, 1)
+	}
+	if p.cpuTime {
+		p.recordCPUTime(t, cpu.end())
+	}
+	if p.trackTasks {
+		atomic.StoreInt64(&ws.started, 0)
+		ws.task.Store(nil)
+	}
+	if p.metrics != nil {
+		p.metrics.Timing(MetricTaskDuration, time.Since(start))
+		p.metrics.Count(MetricTasksCompleted, 1)
+	}
+	if t.panicked || t.err != nil {
+		p.taskIndex.finish(t, StateFailed)
+	} else {
+		p.taskIndex.finish(t, StateDone)
+	}
+	p.taskDone(t)
+	p.emit(TaskDone, t)
+	p.taskFinished(t)
+	if p.panicBackoff != nil {
+		p.backOffPanics(ws, t)
+	}
+	return false
+}
+
+// forceStop abandons the tasks that are waiting, and the remainder of any
+// batch given to a worker.  This makes a call to StopWait that is in progress
+// return once running tasks have completed, the same as Stop.  Running tasks
+// are told to stop by canceling the worker pool's context.
+func (p *WorkerPool) forceStop() {
+	p.runState.Load().forceStop()
+}
+
+// forceStop abandons the queued tasks of this run of the worker pool.
+func (rs *runState) forceStop() {
+	rs.abandonOnce.Do(func() {
+		rs.cancel(ErrStopped)
+		close(rs.abandon)
+	})
 }
 
 // stop tells the dispatcher to exit, and whether or not to complete queued
-// tasks.
-func (p *WorkerPool) stop(wait bool) {
+// tasks.  The reason is recorded in the worker pool's StopResult.
+func (p *WorkerPool) stop(wait bool, reason StopReason) {
 	p.stopMutex.Lock()
 	defer p.stopMutex.Unlock()
 	if p.stopped {
 		return
 	}
+	p.beginStop(wait, reason)
+	if p.isWorker() {
+		// Called by a task, which cannot finish until this returns.  The
+		// worker pool finishes stopping after the task returns.
+		return
+	}
+	<-p.runState.Load().stopped
+}
+
+// beginStop tells the dispatcher to stop.  Must be called with stopMutex held
+// while the worker pool is running.
+func (p *WorkerPool) beginStop(wait bool, reason StopReason) {
 	p.stopped = true
-	if wait {
-		p.taskQueue <- nil
+	p.stopWait = wait
+	p.suspended = reason == StoppedBySuspend
+	p.beginStopResult(reason)
+	p.stopChildren(wait)
+	p.unregister()
+	if p.parent != nil {
+		p.parent.removeChild(p)
+	}
+	if !wait && !p.suspended {
+		// Tell workers to abandon the remainder of any batch they are given.
+		p.forceStop()
+	}
+	p.flushBatches(wait || p.suspended)
+	p.flushDelayed(wait || p.suspended)
+	if !p.suspended {
+		// Drop the tasks submitted from now on.  Taking the lock waits for
+		// submits in progress, so that the dispatcher receives their tasks.
+		p.submitMutex.Lock()
+		p.submitClosed = true
+		p.submitMutex.Unlock()
 	}
-	// Close task queue and wait for currently running tasks to finish.
-	close(p.taskQueue)
-	<-p.stoppedChan
+	// Tell dispatcher to stop and wait for currently running tasks to finish.
+	close(p.stopChan)
 }
@@ -1,7 +1,12 @@
 package workerpool
 
 import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -112,6 +117,134 @@ func TestWorkerTimeout(t *testing.T) {
 	}
 }
 
+func TestLIFOWorkers(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4, WithLIFOWorkers(), func(p *WorkerPool) {
+		p.timeout = 50 * time.Millisecond
+	})
+	defer wp.Stop()
+
+	// Start all workers at the same time.
+	original := map[string]bool{}
+	var mutex sync.Mutex
+	release := make(chan struct{})
+	started := make(chan struct{}, 4)
+	for i := 0; i < 4; i++ {
+		wp.Submit(func() {
+			mutex.Lock()
+			original[goroutineID()] = true
+			mutex.Unlock()
+			started <- struct{}{}
+			<-release
+		})
+	}
+	for i := 0; i < 4; i++ {
+		<-started
+	}
+	close(release)
+	wp.Wait()
+
+	// Sequential tasks all run on the most recently ready worker.
+	used := map[string]bool{}
+	for i := 0; i < 10; i++ {
+		wp.SubmitWait(func() { used[goroutineID()] = true })
+		time.Sleep(20 * time.Millisecond)
+	}
+	if len(used) != 1 {
+		t.Fatal("expected one worker to execute sequential tasks, used", len(used))
+	}
+
+	// The other workers were idle longer than the timeout and were stopped,
+	// so concurrent tasks now need new workers.
+	release = make(chan struct{})
+	var reused int
+	for i := 0; i < 4; i++ {
+		wp.Submit(func() {
+			mutex.Lock()
+			if original[goroutineID()] {
+				reused++
+			}
+			mutex.Unlock()
+			started <- struct{}{}
+			<-release
+		})
+	}
+	for i := 0; i < 4; i++ {
+		<-started
+	}
+	close(release)
+	if reused != 1 {
+		t.Fatal("expected only the busy worker to be reused, reused", reused)
+	}
+}
+
+func TestWorkerCount(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.Stop()
+
+	// Read counts concurrently with dispatcher changing them.
+	stopMonitor := make(chan struct{})
+	monitorDone := make(chan struct{})
+	go func() {
+		defer close(monitorDone)
+		for {
+			select {
+			case <-stopMonitor:
+				return
+			default:
+				if wp.WorkerCount() > 4 || wp.BusyWorkers() > 4 || wp.IdleWorkers() > 4 {
+					t.Error("count exceeds max workers")
+					return
+				}
+			}
+		}
+	}()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	for i := 0; i < 3; i++ {
+		wp.Submit(func() {
+			started <- struct{}{}
+			<-release
+		})
+	}
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	if n := wp.WorkerCount(); n != 3 {
+		t.Fatal("expected 3 workers, got", n)
+	}
+	if n := wp.BusyWorkers(); n != 3 {
+		t.Fatal("expected 3 busy workers, got", n)
+	}
+	if n := wp.IdleWorkers(); n != 0 {
+		t.Fatal("expected 0 idle workers, got", n)
+	}
+
+	close(release)
+	wp.Wait()
+	deadline := time.Now().Add(5 * time.Second)
+	for wp.IdleWorkers() != 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected 3 idle workers, got", wp.IdleWorkers())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n := wp.BusyWorkers(); n != 0 {
+		t.Fatal("expected 0 busy workers, got", n)
+	}
+	close(stopMonitor)
+	<-monitorDone
+
+	wp.Stop()
+	if n := wp.WorkerCount(); n != 0 {
+		t.Fatal("expected 0 workers after stop, got", n)
+	}
+}
+
 func TestStop(t *testing.T) {
 	t.Parallel()
 
@@ -191,6 +324,70 @@ Count:
 	wp.Stop()
 }
 
+func TestOnDropped(t *testing.T) {
+	t.Parallel()
+
+	dropped := make(chan func(), 3)
+	wp := New(1, WithOnDropped(func(task func()) { dropped <- task }))
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	var ran int32
+	for i := 0; i < 3; i++ {
+		wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	for wp.WaitingQueueSize() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wp.Stop()
+		close(stopped)
+	}()
+	for i := 0; i < 3; i++ {
+		select {
+		case task := <-dropped:
+			task()
+		case <-time.After(5 * time.Second):
+			t.Fatal("dropped task not reported")
+		}
+	}
+	close(release)
+	<-stopped
+
+	if n := wp.Stats().DroppedTasks; n != 3 {
+		t.Fatal("expected 3 dropped tasks, got", n)
+	}
+	if atomic.LoadInt32(&ran) != 3 {
+		t.Fatal("dropped tasks were not returned to the caller")
+	}
+}
+
+func TestStopFromTask(t *testing.T) {
+	t.Parallel()
+
+	for _, wait := range []bool{false, true} {
+		wp := New(2)
+		var ran int32
+		wp.Submit(func() {
+			if wait {
+				wp.StopWait()
+			} else {
+				wp.Stop()
+			}
+			atomic.AddInt32(&ran, 1)
+		})
+		select {
+		case <-wp.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("worker pool did not stop after task called stop")
+		}
+		if !wp.Stopped() || atomic.LoadInt32(&ran) != 1 {
+			t.Fatal("task did not complete after stopping worker pool")
+		}
+	}
+}
+
 func TestStopWait(t *testing.T) {
 	t.Parallel()
 
@@ -239,6 +436,37 @@ func TestStopWait(t *testing.T) {
 	wp.StopWait()
 }
 
+func TestDone(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	select {
+	case <-wp.Done():
+		t.Fatal("done should not be closed before stop")
+	default:
+	}
+
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	go wp.Stop()
+
+	select {
+	case <-wp.Done():
+		t.Fatal("done should not be closed while task running")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+
+	select {
+	case <-wp.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("done not closed after stop")
+	}
+	if !wp.Stopped() {
+		t.Fatal("pool should be stopped")
+	}
+}
+
 func TestSubmitWait(t *testing.T) {
 	wp := New(1)
 	defer wp.Stop()
@@ -270,6 +498,392 @@ func TestSubmitWait(t *testing.T) {
 	}
 }
 
+func TestSubmitWaitContext(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	defer wp.Stop()
+
+	if err := wp.SubmitWaitContext(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	var ran bool
+	if err := wp.SubmitWaitContext(context.Background(), func() { ran = true }); err != nil || !ran {
+		t.Fatal("function did not run:", err)
+	}
+
+	// A function that has not started when the context is done is canceled,
+	// and is not run when a worker is available.
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := wp.SubmitWaitContext(ctx, func() { t.Error("canceled function ran") })
+	if err != context.DeadlineExceeded {
+		t.Fatal("expected DeadlineExceeded, got", err)
+	}
+	close(release)
+	wp.Wait()
+
+	// A panic is raised again in the caller.
+	func() {
+		defer func() {
+			if recover() != "boom" {
+				t.Error("panic was not raised in the caller")
+			}
+		}()
+		wp.SubmitWaitContext(context.Background(), func() { panic("boom") })
+	}()
+
+	// Rejected and dropped functions return an error.
+	rejecting := New(1, WithAdmission(func(TaskInfo, PoolStats) Decision {
+		return Decision{Action: Degrade, Fallback: func() {}}
+	}))
+	defer rejecting.Stop()
+	if err = rejecting.SubmitWaitContext(context.Background(), func() {}); err != ErrQueueFull {
+		t.Fatal("expected ErrQueueFull, got", err)
+	}
+	wp.Stop()
+	if err = wp.SubmitWaitContext(context.Background(), func() {}); err != ErrStopped {
+		t.Fatal("expected ErrStopped, got", err)
+	}
+}
+
+func TestSubmitAllWait(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	defer wp.Stop()
+
+	errFail := errors.New("fail")
+	var ran int32
+	errs := wp.SubmitAllWait([]func() error{
+		func() error { atomic.AddInt32(&ran, 1); return nil },
+		nil,
+		func() error { atomic.AddInt32(&ran, 1); return errFail },
+		func() error { atomic.AddInt32(&ran, 1); return nil },
+	})
+	if ran != 3 {
+		t.Fatal("expected 3 tasks to run, ran", ran)
+	}
+	if len(errs) != 4 || errs[0] != nil || errs[1] != nil || errs[2] != errFail || errs[3] != nil {
+		t.Fatal("wrong errors:", errs)
+	}
+
+	// Tasks that have not started when the context is done are skipped.
+	wp1 := New(1)
+	defer wp1.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	errs = wp1.SubmitAllWaitContext(ctx, []func() error{
+		func() error { cancel(); return nil },
+		func() error { return nil },
+		func() error { return nil },
+	})
+	if errs[0] != nil || errs[1] != context.Canceled || errs[2] != context.Canceled {
+		t.Fatal("wrong errors:", errs)
+	}
+
+	// Queued tasks are canceled without waiting for a worker to reach them.
+	release := make(chan struct{})
+	defer close(release)
+	wp1.Submit(func() { <-release })
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var ranQueued int32
+	errs = wp1.SubmitAllWaitContext(ctx, []func() error{
+		func() error { atomic.AddInt32(&ranQueued, 1); return nil },
+	})
+	if errs[0] != context.DeadlineExceeded {
+		t.Fatal("wrong errors:", errs)
+	}
+	if atomic.LoadInt32(&ranQueued) != 0 {
+		t.Fatal("canceled task ran")
+	}
+}
+
+func TestSubmitAll(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.Stop()
+
+	var count int32
+	tasks := make([]func(), 100)
+	for i := range tasks {
+		tasks[i] = func() { atomic.AddInt32(&count, 1) }
+	}
+	tasks[50] = nil
+	wp.SubmitAll(tasks...)
+	wp.SubmitAll()
+	wp.Wait()
+	if count != 99 {
+		t.Fatal("expected 99 tasks executed, got", count)
+	}
+
+	// Tasks in a batch start in order when there is a single worker.
+	wp1 := New(1)
+	defer wp1.Stop()
+	var order []int
+	for i := 0; i < 10; i++ {
+		i := i
+		tasks[i] = func() { order = append(order, i) }
+	}
+	wp1.SubmitAll(tasks[:10]...)
+	wp1.Wait()
+	for i := range order {
+		if order[i] != i {
+			t.Fatal("tasks executed out of order:", order)
+		}
+	}
+}
+
+func TestBatchDispatch(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	var count int32
+	for i := 0; i < 40; i++ {
+		wp.Submit(func() {
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&count, 1)
+		})
+	}
+
+	// Worker is given a batch of queued tasks once released.  Stopping the
+	// pool abandons the rest of the batch.
+	close(release)
+	time.Sleep(20 * time.Millisecond)
+	wp.Stop()
+	if n := atomic.LoadInt32(&count); n >= maxBatchSize {
+		t.Fatal("expected remainder of batch to be abandoned, executed", n)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wp.WaitContext(ctx); err != nil {
+		t.Fatal("abandoned batch tasks still pending")
+	}
+}
+
+func TestStrictOrder(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2, WithStrictOrder())
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	var started sync.WaitGroup
+	started.Add(2)
+	for i := 0; i < 2; i++ {
+		wp.Submit(func() {
+			started.Done()
+			<-release
+		})
+	}
+	started.Wait()
+
+	var mutex sync.Mutex
+	var order []int
+	for i := 0; i < 4*maxBatchSize; i++ {
+		i := i
+		wp.Submit(func() {
+			mutex.Lock()
+			order = append(order, i)
+			mutex.Unlock()
+			time.Sleep(time.Millisecond)
+		})
+	}
+	close(release)
+	wp.StopWait()
+
+	// A task is only given to a worker after a worker finishes one of the
+	// two tasks submitted before it.
+	for i, n := range order {
+		if n > i+1 {
+			t.Fatalf("task %d started at position %d: %v", n, i, order)
+		}
+	}
+}
+
+func TestWait(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.Stop()
+
+	// Wait with nothing submitted returns immediately.
+	wp.Wait()
+
+	var count int32
+	for phase := 1; phase <= 3; phase++ {
+		for i := 0; i < 20; i++ {
+			wp.Submit(func() {
+				time.Sleep(time.Millisecond)
+				atomic.AddInt32(&count, 1)
+			})
+		}
+		wp.Wait()
+		if c := atomic.LoadInt32(&count); c != int32(phase*20) {
+			t.Fatal("expected", phase*20, "completed tasks, got", c)
+		}
+	}
+	if wp.Stopped() {
+		t.Fatal("pool should not be stopped by Wait")
+	}
+
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := wp.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Fatal("expected deadline exceeded, got", err)
+	}
+	close(release)
+	if err := wp.WaitContext(context.Background()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+}
+
+func TestOnIdle(t *testing.T) {
+	t.Parallel()
+
+	idle := make(chan struct{}, 10)
+	wp := New(2, WithOnIdle(func() { idle <- struct{}{} }))
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		wp.Submit(func() { <-release })
+	}
+	select {
+	case <-idle:
+		t.Fatal("should not be idle with tasks running")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+
+	select {
+	case <-idle:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle hook not called")
+	}
+	select {
+	case <-idle:
+		t.Fatal("idle hook called more than once")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// Becoming idle again calls the hook again.
+	wp.Submit(func() {})
+	select {
+	case <-idle:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle hook not called after second batch")
+	}
+}
+
+func TestWaitAfterStop(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	release := make(chan struct{})
+	for i := 0; i < 10; i++ {
+		wp.Submit(func() { <-release })
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	wp.Stop()
+
+	// Abandoned tasks must not block Wait.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wp.WaitContext(ctx); err != nil {
+		t.Fatal("Wait blocked on abandoned tasks")
+	}
+}
+
+func TestSubmitAfterStop(t *testing.T) {
+	t.Parallel()
+
+	var rejected int
+	wp := New(1, WithOnReject(func(_ func(), reason RejectReason) {
+		if reason == RejectedByStop {
+			rejected++
+		}
+	}))
+	wp.Stop()
+
+	// Tasks submitted after Stop are dropped and reported, and do not block
+	// Wait.
+	wp.Submit(func() { t.Error("task ran after Stop") })
+	wp.SubmitAll(func() {}, func() {})
+	if err := wp.SubmitTenant("", func() {}); err != ErrStopped {
+		t.Fatal("expected ErrStopped, got", err)
+	}
+	// Functions that wait for their tasks return once the tasks are dropped.
+	wp.SubmitWait(func() { t.Error("task ran after Stop") })
+	errs := wp.SubmitAllWait([]func() error{nil, func() error { return nil }})
+	if errs[0] != nil || errs[1] != ErrStopped {
+		t.Fatal("expected nil and ErrStopped, got", errs)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wp.WaitContext(ctx); err != nil {
+		t.Fatal("Wait blocked on tasks submitted after Stop")
+	}
+	if rejected != 6 || wp.Stats().DroppedTasks != 6 {
+		t.Fatal("expected 6 dropped tasks, got", rejected, wp.Stats().DroppedTasks)
+	}
+}
+
+func TestFlush(t *testing.T) {
+	t.Parallel()
+
+	wp := New(4)
+	defer wp.Stop()
+
+	// Flush with nothing submitted returns immediately.
+	wp.Flush()
+
+	var before int32
+	for i := 0; i < 20; i++ {
+		wp.Submit(func() {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&before, 1)
+		})
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		wp.Flush()
+		close(flushed)
+	}()
+
+	// Tasks submitted after the flush do not hold it up.
+	release := make(chan struct{})
+	defer close(release)
+	time.Sleep(10 * time.Millisecond)
+	wp.Submit(func() { <-release })
+
+	select {
+	case <-flushed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("flush waited for tasks submitted after it")
+	}
+	if n := atomic.LoadInt32(&before); n != 20 {
+		t.Fatal("flush returned before earlier tasks completed:", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := wp.FlushContext(ctx); err != context.DeadlineExceeded {
+		t.Fatal("expected deadline exceeded, got", err)
+	}
+}
+
 func TestOverflow(t *testing.T) {
 	wp := New(2)
 	releaseChan := make(chan struct{})
@@ -290,7 +904,7 @@ func TestOverflow(t *testing.T) {
 
 	// Now that the worker pool has exited, it is safe to inspect its waiting
 	// queue without causing a race.
-	qlen := wp.waitingQueue.Len()
+	qlen := wp.waitingQueue.len()
 	if qlen != 62 {
 		t.Fatal("Expected 62 tasks in waiting queue, have", qlen)
 	}
@@ -318,6 +932,14 @@ func TestStopRace(t *testing.T) {
 	close(releaseChan)
 }
 
+// goroutineID returns the ID of the calling goroutine, which identifies the
+// worker executing a task.
+func goroutineID() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	return strings.Fields(string(buf))[1]
+}
+
 func anyReady(w *WorkerPool) bool {
 	select {
 	case wkCh := <-w.readyWorkers:
@@ -331,7 +953,7 @@ func anyReady(w *WorkerPool) bool {
 func countReady(w *WorkerPool) int {
 	// Try to pull max workers off of ready queue.
 	timeout := time.After(5 * time.Second)
-	readyTmp := make(chan chan func(), max)
+	readyTmp := make(chan chan *task, max)
 	var readyCount int
 	for i := 0; i < max; i++ {
 		select {
@@ -482,3 +1104,352 @@ func BenchmarkExecute64Workers(b *testing.B) {
 	}
 	allDone.Wait()
 }
+
+func BenchmarkSubmitAll(b *testing.B) {
+	wp := New(1)
+	defer wp.Stop()
+	tasks := make([]func(), 64)
+	for i := range tasks {
+		tasks[i] = func() {}
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		wp.SubmitAll(tasks...)
+	}
+	wp.Wait()
+}
+
+func BenchmarkExecuteBacklog(b *testing.B) {
+	wp := New(4)
+	defer wp.Stop()
+	var allDone sync.WaitGroup
+	allDone.Add(b.N)
+	release := make(chan struct{})
+	// Hold workers busy so that tasks build up in the waiting queue.
+	for i := 0; i < 4; i++ {
+		wp.Submit(func() { <-release })
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		wp.Submit(func() { allDone.Done() })
+	}
+	close(release)
+	allDone.Wait()
+}
+
+func BenchmarkSubmitParallel(b *testing.B) {
+	wp := New(4)
+	defer wp.Stop()
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			wp.Submit(func() {})
+		}
+	})
+	wp.Wait()
+}
+
+func BenchmarkSubmitWaitIdle(b *testing.B) {
+	wp := New(4)
+	defer wp.Stop()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		wp.SubmitWait(func() {})
+	}
+}
+
+func BenchmarkStartWorkers(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		wp := New(16)
+		for j := 0; j < 16; j++ {
+			wp.Submit(func() {})
+		}
+		wp.StopWait()
+	}
+}
+
+func TestWaitingQueueSize(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	defer wp.Stop()
+	if wp.Size() != 1 {
+		t.Fatal("wrong size:", wp.Size())
+	}
+
+	release := make(chan struct{})
+	for i := 0; i < 5; i++ {
+		wp.Submit(func() { <-release })
+	}
+	deadline := time.Now().Add(time.Second)
+	for wp.WaitingQueueSize() != 4 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected 4 waiting tasks, have", wp.WaitingQueueSize())
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wp.Wait()
+	if wp.WaitingQueueSize() != 0 {
+		t.Fatal("expected no waiting tasks, have", wp.WaitingQueueSize())
+	}
+}
+
+func TestPause(t *testing.T) {
+	t.Parallel()
+
+	wp := New(3)
+	defer wp.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wp.Pause(ctx)
+
+	var ran int32
+	for i := 0; i < 10; i++ {
+		wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	time.Sleep(20 * time.Millisecond)
+	if atomic.LoadInt32(&ran) != 0 {
+		t.Fatal("tasks ran while paused")
+	}
+
+	cancel()
+	wp.Wait()
+	if ran != 10 {
+		t.Fatal("expected 10 tasks to run after unpausing, got", ran)
+	}
+
+	// Stopping unpauses the workers.
+	wp.Pause(context.Background())
+	wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	wp.StopWait()
+	if ran != 11 {
+		t.Fatal("queued task not run by StopWait")
+	}
+	// Pausing a stopped pool does nothing.
+	wp.Pause(context.Background())
+}
+
+func TestPauseWithLimit(t *testing.T) {
+	t.Parallel()
+
+	wp := New(3, WithLimiter(NewLimiter(1)))
+	defer wp.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	paused := make(chan struct{})
+	go func() {
+		wp.Pause(ctx)
+		close(paused)
+	}()
+	select {
+	case <-paused:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pause blocked by concurrency limit")
+	}
+
+	var ran int32
+	wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	cancel()
+	wp.Wait()
+	if ran != 1 {
+		t.Fatal("task not run after unpausing")
+	}
+}
+
+func TestReboot(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2, WithName("reboot-test"), WithMinWorkers(1))
+	var ran int32
+	for i := 0; i < 10; i++ {
+		wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	wp.Wait()
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	for wp.BusyWorkers() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	// Release does not wait for the running task.
+	wp.Release()
+	if !wp.Stopped() || Get("reboot-test") != nil {
+		t.Fatal("pool not stopped by Release")
+	}
+	select {
+	case <-wp.Done():
+		t.Fatal("done channel closed with a task running")
+	default:
+	}
+	close(release)
+	<-wp.Done()
+	if r := wp.StopReason(); r != StoppedByRelease {
+		t.Fatal("wrong stop reason:", r)
+	}
+
+	wp.Reboot()
+	if wp.Stopped() || Get("reboot-test") != wp {
+		t.Fatal("pool not restarted by Reboot")
+	}
+	select {
+	case <-wp.Done():
+		t.Fatal("done channel closed after Reboot")
+	default:
+	}
+	for i := 0; i < 10; i++ {
+		wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	wp.StopWait()
+	if ran != 20 {
+		t.Fatal("expected 20 tasks to run, got", ran)
+	}
+	if wp.WorkerCount() != 0 {
+		t.Fatal("workers running after StopWait")
+	}
+
+	// Rebooting a running pool does nothing.
+	wp.Reboot()
+	wp.Reboot()
+
+	// Reboot waits for a released pool to finish stopping.
+	var finished int32
+	release = make(chan struct{})
+	wp.Submit(func() {
+		<-release
+		atomic.StoreInt32(&finished, 1)
+	})
+	for wp.BusyWorkers() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	wp.Release()
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+	wp.Reboot()
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Fatal("Reboot did not wait for the released pool to stop")
+	}
+	wp.Stop()
+}
+
+func TestRebootConcurrentReaders(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			wp.Done()
+			wp.Context()
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		// A running task can use the worker pool's Context and Done while
+		// Stop waits for it.
+		wp.Submit(func() {
+			<-wp.Context().Done()
+			select {
+			case <-wp.Done():
+				t.Error("stopped while a task is running")
+			default:
+			}
+		})
+		wp.Stop()
+		wp.Reboot()
+	}
+	close(stop)
+	<-done
+	wp.Stop()
+}
+
+func TestRebootConcurrentSubmit(t *testing.T) {
+	t.Parallel()
+
+	var ran, dropped, submitted int64
+	wp := New(2, WithOnReject(func(_ func(), reason RejectReason) {
+		if reason == RejectedByStop {
+			atomic.AddInt64(&dropped, 1)
+		}
+	}))
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				atomic.AddInt64(&submitted, 1)
+				wp.Submit(func() { atomic.AddInt64(&ran, 1) })
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if i%2 == 0 {
+			wp.Stop()
+		} else {
+			wp.Suspend()
+		}
+		wp.Reboot()
+	}
+	close(stop)
+	wg.Wait()
+	wp.StopWait()
+
+	// Every task either ran or was dropped and reported.
+	if n := atomic.LoadInt64(&ran) + atomic.LoadInt64(&dropped); n != submitted {
+		t.Fatalf("%d tasks submitted, but %d ran and %d were dropped", submitted, ran, dropped)
+	}
+}
+
+func TestSuspend(t *testing.T) {
+	t.Parallel()
+
+	wp := New(1)
+	defer wp.Stop()
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	var ran int32
+	for i := 0; i < 3; i++ {
+		wp.Submit(func() { atomic.AddInt32(&ran, 1) })
+	}
+	for wp.WaitingQueueSize() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+	wp.Suspend()
+	if atomic.LoadInt32(&ran) != 0 || wp.WaitingQueueSize() != 3 {
+		t.Fatal("waiting tasks not kept by Suspend")
+	}
+	if r := wp.StopResult(); r.Reason != StoppedBySuspend || r.Dropped != 0 {
+		t.Fatalf("wrong stop result: %+v", r)
+	}
+
+	wp.Reboot()
+	wp.Wait()
+	if n := atomic.LoadInt32(&ran); n != 3 {
+		t.Fatal("expected kept tasks to run after Reboot, ran", n)
+	}
+}
@@ -0,0 +1,31 @@
+package workerpool
+
+import "time"
+
+// WithWorkerRate limits each worker to starting at most n tasks per second,
+// independently of the other workers.  This is useful when each worker holds
+// its own session to a backend that limits the rate of each session.  A
+// worker that must wait before starting its next task remains busy while it
+// waits.
+func WithWorkerRate(n int) Option {
+	return func(p *WorkerPool) {
+		if n > 0 {
+			p.workerInterval = time.Second / time.Duration(n)
+		}
+	}
+}
+
+// paceWorker waits until the worker may start another task under the worker
+// rate limit.  Returns false if the worker pool was stopped without waiting
+// for queued tasks while the worker was waiting.
+func (p *WorkerPool) paceWorker(ws *workerState) bool {
+	now := p.clock.Now()
+	if wait := ws.nextStart.Sub(now); wait > 0 {
+		if !p.delay(wait) {
+			return false
+		}
+		now = ws.nextStart
+	}
+	ws.nextStart = now.Add(p.workerInterval)
+	return true
+}
@@ -0,0 +1,23 @@
+package workerpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWorkerRate(t *testing.T) {
+	t.Parallel()
+
+	wp := New(2, WithWorkerRate(50))
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		wp.Submit(func() {})
+	}
+	wp.StopWait()
+
+	// Each worker starts a task at most every 20ms, so the 10 tasks take at
+	// least 4 intervals even when divided evenly between the workers.
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Fatal("tasks ran faster than worker rate allows:", elapsed)
+	}
+}
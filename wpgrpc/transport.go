@@ -0,0 +1,134 @@
+package wpgrpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+
+	"github.com/gammazero/workerpool/wpremote"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// Name of the remote worker service and of the codec used for its messages.
+// The messages are encoded by the codec, so no generated protobuf code is
+// needed.
+const (
+	serviceName = "workerpool.RemoteWorker"
+	executeName = "/" + serviceName + "/Execute"
+	codecName   = "workerpool"
+)
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// NewTransport returns a wpremote.Transport that sends tasks over the gRPC
+// connection to a worker registered with RegisterWorker.  Errors returned by
+// tasks are returned as *wpremote.RemoteError.
+func NewTransport(conn grpc.ClientConnInterface) wpremote.Transport {
+	return &transport{conn: conn}
+}
+
+type transport struct {
+	conn grpc.ClientConnInterface
+}
+
+func (t *transport) Execute(ctx context.Context, name string, payload []byte) error {
+	req := &message{name: name, payload: payload}
+	resp := &message{}
+	if err := t.conn.Invoke(ctx, executeName, req, resp, grpc.ForceCodec(codec{})); err != nil {
+		return err
+	}
+	if resp.err != "" {
+		return &wpremote.RemoteError{Message: resp.err}
+	}
+	return nil
+}
+
+// RegisterWorker registers the remote worker service with the gRPC server, so
+// that the worker executes tasks sent by a transport created with
+// NewTransport.
+func RegisterWorker(s grpc.ServiceRegistrar, w *wpremote.Worker) {
+	s.RegisterService(&serviceDesc, w)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*wpremote.Transport)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Execute",
+		Handler:    executeHandler,
+	}},
+	Metadata: "workerpool",
+}
+
+func executeHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := &message{}
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		m := req.(*message)
+		resp := &message{}
+		if err := srv.(wpremote.Transport).Execute(ctx, m.name, m.payload); err != nil {
+			resp.err = err.Error()
+		}
+		return resp, nil
+	}
+	if interceptor == nil {
+		return handler(ctx, req)
+	}
+	return interceptor(ctx, req, &grpc.UnaryServerInfo{Server: srv, FullMethod: executeName}, handler)
+}
+
+// message is a request to execute a task, or the response with the task's
+// error.
+type message struct {
+	name    string
+	payload []byte
+	err     string
+}
+
+var errBadMessage = errors.New("wpgrpc: malformed message")
+
+// codec encodes messages as a sequence of length-prefixed fields.
+type codec struct{}
+
+func (codec) Name() string {
+	return codecName
+}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(*message)
+	if !ok {
+		return nil, errBadMessage
+	}
+	var b []byte
+	for _, field := range [][]byte{[]byte(m.name), m.payload, []byte(m.err)} {
+		b = binary.AppendUvarint(b, uint64(len(field)))
+		b = append(b, field...)
+	}
+	return b, nil
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(*message)
+	if !ok {
+		return errBadMessage
+	}
+	var fields [3][]byte
+	for i := range fields {
+		n, size := binary.Uvarint(data)
+		if size <= 0 || uint64(len(data)-size) < n {
+			return errBadMessage
+		}
+		data = data[size:]
+		fields[i] = data[:n]
+		data = data[n:]
+	}
+	m.name = string(fields[0])
+	m.payload = fields[1]
+	m.err = string(fields[2])
+	return nil
+}
@@ -0,0 +1,50 @@
+package wpgrpc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCodec(t *testing.T) {
+	t.Parallel()
+
+	in := &message{name: "task", payload: []byte{0, 1, 2}, err: "failed"}
+	data, err := codec{}.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &message{}
+	if err = (codec{}).Unmarshal(data, out); err != nil {
+		t.Fatal(err)
+	}
+	if out.name != in.name || string(out.payload) != string(in.payload) || out.err != in.err {
+		t.Fatalf("decoded %+v, expected %+v", out, in)
+	}
+	if err = (codec{}).Unmarshal(data[:len(data)-1], out); err != errBadMessage {
+		t.Fatal("expected errBadMessage for truncated message, got", err)
+	}
+}
+
+type echoTransport struct {
+	name string
+}
+
+func (e *echoTransport) Execute(ctx context.Context, name string, payload []byte) error {
+	e.name = name
+	return nil
+}
+
+func TestExecuteHandler(t *testing.T) {
+	t.Parallel()
+
+	srv := &echoTransport{}
+	data, _ := codec{}.Marshal(&message{name: "hello"})
+	dec := func(v interface{}) error { return codec{}.Unmarshal(data, v) }
+	resp, err := executeHandler(srv, context.Background(), dec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.name != "hello" || resp.(*message).err != "" {
+		t.Fatal("task not executed by handler")
+	}
+}
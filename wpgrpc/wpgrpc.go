@@ -0,0 +1,149 @@
+/*
+Package wpgrpc provides gRPC server interceptors that use worker pools as
+bulkheads, limiting the number of calls that are handled concurrently.
+
+Each call's handler is executed as a task in a worker pool.  Methods can be
+assigned to separate pools, as concurrency classes, so that a slow or busy
+method does not use the capacity of others.  A call that waits too long for a
+worker, arrives when too many tasks are already waiting, or is rejected by its
+pool, such as by the pool's admission function or after the pool is stopped,
+is rejected with the RESOURCE_EXHAUSTED status code.
+*/
+package wpgrpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gammazero/workerpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Option configures the interceptors.
+type Option func(*limiter)
+
+// WithMethodPool executes calls to the given method in their own worker pool,
+// instead of in the default pool.  The method is a full method name, such as
+// "/package.Service/Method", or a service name ending with a slash, such as
+// "/package.Service/", to assign all of a service's methods.  An exact method
+// name takes precedence over a service name.
+func WithMethodPool(method string, pool *workerpool.WorkerPool) Option {
+	return func(l *limiter) {
+		if l.methods == nil {
+			l.methods = map[string]*workerpool.WorkerPool{}
+		}
+		l.methods[method] = pool
+	}
+}
+
+// WithQueueTimeout rejects a call that has waited for a worker for longer than
+// the timeout.  By default, calls wait until a worker is available or the
+// call's context is done.
+func WithQueueTimeout(timeout time.Duration) Option {
+	return func(l *limiter) {
+		l.queueTimeout = timeout
+	}
+}
+
+// WithMaxQueue rejects a call immediately when n or more tasks are already
+// waiting for a worker in the call's pool.
+func WithMaxQueue(n int) Option {
+	return func(l *limiter) {
+		l.maxQueue = n
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor that executes unary handlers
+// as tasks in the given default pool, or in the pool assigned to the method.
+func UnaryServerInterceptor(pool *workerpool.WorkerPool, options ...Option) grpc.UnaryServerInterceptor {
+	l := newLimiter(pool, options)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var resp interface{}
+		var err error
+		if rerr := l.run(ctx, info.FullMethod, func() {
+			resp, err = handler(ctx, req)
+		}); rerr != nil {
+			return nil, rerr
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor that executes stream
+// handlers as tasks in the given default pool, or in the pool assigned to the
+// method.  The task occupies a worker for the life of the stream.
+func StreamServerInterceptor(pool *workerpool.WorkerPool, options ...Option) grpc.StreamServerInterceptor {
+	l := newLimiter(pool, options)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		var err error
+		if rerr := l.run(ss.Context(), info.FullMethod, func() {
+			err = handler(srv, ss)
+		}); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+}
+
+type limiter struct {
+	pool         *workerpool.WorkerPool
+	methods      map[string]*workerpool.WorkerPool
+	queueTimeout time.Duration
+	maxQueue     int
+}
+
+func newLimiter(pool *workerpool.WorkerPool, options []Option) *limiter {
+	l := &limiter{pool: pool}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// poolFor returns the worker pool that executes calls to the method.
+func (l *limiter) poolFor(method string) *workerpool.WorkerPool {
+	if pool, ok := l.methods[method]; ok {
+		return pool
+	}
+	if i := strings.LastIndexByte(method, '/'); i > 0 {
+		if pool, ok := l.methods[method[:i+1]]; ok {
+			return pool
+		}
+	}
+	return l.pool
+}
+
+// run executes fn in the method's worker pool and waits for it to finish.
+// Returns an error if the call is rejected or its context is done before fn
+// starts.  A panic in fn is raised again in the calling goroutine.
+func (l *limiter) run(ctx context.Context, method string, fn func()) error {
+	pool := l.poolFor(method)
+	if l.maxQueue > 0 && pool.WaitingQueueSize() >= l.maxQueue {
+		return status.Errorf(codes.ResourceExhausted, "%s: too many calls waiting", method)
+	}
+
+	// The queue timeout only limits the wait for a worker.  The handler is
+	// given the call's own context.
+	waitCtx := ctx
+	if l.queueTimeout > 0 {
+		var cancel context.CancelFunc
+		waitCtx, cancel = context.WithTimeout(ctx, l.queueTimeout)
+		defer cancel()
+	}
+	err := pool.SubmitWaitContext(waitCtx, fn)
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case waitCtx.Err() != nil:
+		return status.Errorf(codes.ResourceExhausted, "%s: timed out waiting for worker", method)
+	case errors.Is(err, workerpool.ErrStopped):
+		return status.Errorf(codes.ResourceExhausted, "%s: worker pool stopped", method)
+	}
+	return status.Errorf(codes.ResourceExhausted, "%s: %v", method, err)
+}
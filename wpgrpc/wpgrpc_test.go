@@ -0,0 +1,90 @@
+package wpgrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gammazero/workerpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	t.Parallel()
+
+	wp := workerpool.New(1)
+	defer wp.Stop()
+	slow := workerpool.New(1)
+	defer slow.Stop()
+
+	intercept := UnaryServerInterceptor(wp, WithQueueTimeout(20*time.Millisecond),
+		WithMethodPool("/test.Slow/", slow))
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Slow/Wait"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			close(started)
+			<-release
+			return nil, nil
+		})
+	<-started
+	defer close(release)
+
+	// A call in the default pool is not blocked by the slow method.
+	resp, err := intercept(context.Background(), "req", &grpc.UnaryServerInfo{FullMethod: "/test.Fast/Echo"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return req, nil
+		})
+	if err != nil || resp != "req" {
+		t.Fatal("unexpected result:", resp, err)
+	}
+
+	// Another call to the slow service times out waiting.
+	_, err = intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/test.Slow/Other"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			t.Error("rejected handler was called")
+			return nil, nil
+		})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatal("expected RESOURCE_EXHAUSTED, got", err)
+	}
+
+	// A call whose context is canceled while waiting has the context's code.
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = intercept(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/test.Slow/Other"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			t.Error("canceled handler was called")
+			return nil, nil
+		})
+	if status.Code(err) != codes.Canceled {
+		t.Fatal("expected CANCELED, got", err)
+	}
+}
+
+func TestUnaryServerInterceptorRejected(t *testing.T) {
+	t.Parallel()
+
+	wp := workerpool.New(1, workerpool.WithAdmission(
+		func(workerpool.TaskInfo, workerpool.PoolStats) workerpool.Decision {
+			return workerpool.Decision{Action: workerpool.Reject}
+		}))
+	defer wp.Stop()
+	stopped := workerpool.New(1)
+	stopped.Stop()
+
+	intercept := UnaryServerInterceptor(wp, WithMethodPool("/test.Stopped/", stopped))
+	for _, method := range []string{"/test.Rejected/Call", "/test.Stopped/Call"} {
+		_, err := intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				t.Error("rejected handler was called")
+				return nil, nil
+			})
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatal("expected RESOURCE_EXHAUSTED for", method, "got", err)
+		}
+	}
+}
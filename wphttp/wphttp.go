@@ -0,0 +1,98 @@
+/*
+Package wphttp provides net/http middleware that uses a worker pool as a
+bulkhead, limiting the number of requests that are handled concurrently.
+
+Each request's handler is executed as a task in the worker pool, so at most
+the pool's maximum number of workers handle requests at once, and other
+requests wait in the pool's queue.  A request that waits too long, arrives
+when too many requests are already waiting, or is rejected by the pool, such
+as by its admission function or after it is stopped, is rejected with 503
+Service Unavailable.
+*/
+package wphttp
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gammazero/workerpool"
+)
+
+// Option configures the middleware returned by Limit.
+type Option func(*limiter)
+
+// WithQueueTimeout rejects a request that has waited for a worker for longer
+// than the timeout.  By default, requests wait until a worker is available or
+// the request's context is done.
+func WithQueueTimeout(timeout time.Duration) Option {
+	return func(l *limiter) {
+		l.queueTimeout = timeout
+	}
+}
+
+// WithMaxQueue rejects a request immediately when n or more tasks are already
+// waiting for a worker in the pool.
+func WithMaxQueue(n int) Option {
+	return func(l *limiter) {
+		l.maxQueue = n
+	}
+}
+
+// WithRejectHandler sets the handler that responds to rejected requests.  The
+// default handler responds with 503 Service Unavailable.
+func WithRejectHandler(h http.Handler) Option {
+	return func(l *limiter) {
+		l.reject = h
+	}
+}
+
+// Limit returns a handler that executes next as a task in the worker pool.
+//
+// A panic in next is recovered on the worker, so that it does not stop the
+// program, and is raised again in the goroutine serving the request, where it
+// is handled by the http server.
+func Limit(pool *workerpool.WorkerPool, next http.Handler, options ...Option) http.Handler {
+	l := &limiter{
+		pool:   pool,
+		next:   next,
+		reject: http.HandlerFunc(serviceUnavailable),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+type limiter struct {
+	pool         *workerpool.WorkerPool
+	next         http.Handler
+	reject       http.Handler
+	queueTimeout time.Duration
+	maxQueue     int
+}
+
+func (l *limiter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if l.maxQueue > 0 && l.pool.WaitingQueueSize() >= l.maxQueue {
+		l.reject.ServeHTTP(w, r)
+		return
+	}
+
+	// The queue timeout only limits the wait for a worker.  The handler is
+	// given the request's own context.
+	ctx := r.Context()
+	if l.queueTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.queueTimeout)
+		defer cancel()
+	}
+	if err := l.pool.SubmitWaitContext(ctx, func() { l.next.ServeHTTP(w, r) }); err != nil {
+		// The request was rejected by the pool, or was canceled while
+		// waiting for a worker.
+		l.reject.ServeHTTP(w, r)
+	}
+}
+
+func serviceUnavailable(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
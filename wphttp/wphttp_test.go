@@ -0,0 +1,134 @@
+package wphttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gammazero/workerpool"
+)
+
+func TestLimit(t *testing.T) {
+	t.Parallel()
+
+	wp := workerpool.New(1)
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	handler := Limit(wp, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}), WithQueueTimeout(20*time.Millisecond))
+
+	first := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(first, httptest.NewRequest("GET", "/", nil))
+		close(done)
+	}()
+	<-started
+
+	// The only worker is busy, so this request times out in the queue.
+	second := httptest.NewRecorder()
+	handler.ServeHTTP(second, httptest.NewRequest("GET", "/", nil))
+	if second.Code != http.StatusServiceUnavailable {
+		t.Fatal("expected 503, got", second.Code)
+	}
+
+	close(release)
+	<-done
+	if first.Code != http.StatusNoContent {
+		t.Fatal("expected 204, got", first.Code)
+	}
+	// The timed out request's task was canceled, and is not run.
+	wp.Wait()
+	if len(started) != 0 {
+		t.Fatal("timed out request was handled")
+	}
+}
+
+func TestLimitMaxQueue(t *testing.T) {
+	t.Parallel()
+
+	wp := workerpool.New(1)
+	defer wp.Stop()
+
+	release := make(chan struct{})
+	wp.Submit(func() { <-release })
+	wp.Submit(func() {})
+	for wp.WaitingQueueSize() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	handler := Limit(wp, http.NotFoundHandler(), WithMaxQueue(1),
+		WithRejectHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		})))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatal("expected 429, got", rec.Code)
+	}
+
+	close(release)
+	for wp.WaitingQueueSize() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatal("expected 404, got", rec.Code)
+	}
+}
+
+func TestLimitPanic(t *testing.T) {
+	t.Parallel()
+
+	wp := workerpool.New(1)
+	defer wp.Stop()
+
+	handler := Limit(wp, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler failed")
+	}))
+	defer func() {
+		if r := recover(); r != "handler failed" {
+			t.Fatal("expected panic to be raised in request goroutine, got", r)
+		}
+	}()
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+func TestLimitRejected(t *testing.T) {
+	t.Parallel()
+
+	wp := workerpool.New(1, workerpool.WithAdmission(
+		func(workerpool.TaskInfo, workerpool.PoolStats) workerpool.Decision {
+			return workerpool.Decision{Action: workerpool.Reject}
+		}))
+	defer wp.Stop()
+
+	handler := Limit(wp, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatal("expected 503 for rejected task, got", rec.Code)
+	}
+
+	// A stopped pool does not run the request.
+	wp2 := workerpool.New(1)
+	wp2.Stop()
+	handler = Limit(wp2, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatal("expected 503 for stopped pool, got", rec.Code)
+	}
+}
@@ -0,0 +1,276 @@
+/*
+Package wpredis provides a durable task queue, stored in a Redis stream, whose
+tasks are executed by a worker pool.
+
+Tasks that must survive process restarts are submitted to the queue as a
+task name and a serialized payload, instead of as a function.  A consumer
+pulls tasks from the stream, only when the worker pool has capacity, and
+decodes each task using a workerpool.TaskRegistry to execute it.  A task is
+acknowledged when it returns without error.  Tasks that are not acknowledged,
+because the task failed or the process stopped, are delivered again after a
+timeout.
+
+Any number of processes can consume from the same stream, as members of the
+same consumer group, with each task delivered to one of them at a time.
+*/
+package wpredis
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gammazero/workerpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// Fields of a task's stream entry.
+const (
+	fieldName    = "name"
+	fieldPayload = "payload"
+)
+
+// Handler executes a task from its payload.  Returning an error leaves the
+// task unacknowledged, so that it is delivered again.
+type Handler func(ctx context.Context, payload []byte) error
+
+// Option configures a Queue.
+type Option func(*Queue)
+
+// WithGroup sets the name of the consumer group.  The default is
+// "workerpool".
+func WithGroup(group string) Option {
+	return func(q *Queue) {
+		q.group = group
+	}
+}
+
+// WithConsumer sets the name of this consumer within the group.  Each process
+// consuming from the stream must have a different name.  The default is the
+// host name and process ID.
+func WithConsumer(consumer string) Option {
+	return func(q *Queue) {
+		q.consumer = consumer
+	}
+}
+
+// WithRedeliverAfter sets how long a task may remain unacknowledged before it
+// is delivered again.  This must be longer than the time needed to run a
+// task.  The default is 5 minutes.
+func WithRedeliverAfter(d time.Duration) Option {
+	return func(q *Queue) {
+		q.redeliverAfter = d
+	}
+}
+
+// WithRegistry sets the registry used to decode tasks.  By default, each
+// Queue has its own registry, which is populated using Handle.
+func WithRegistry(r *workerpool.TaskRegistry) Option {
+	return func(q *Queue) {
+		q.registry = r
+	}
+}
+
+// WithErrorHandler sets a function that is called when a task fails or
+// cannot be decoded.  Tasks that cannot be decoded, such as tasks whose name
+// is not registered, are acknowledged and dropped, so that they are not
+// delivered again forever.
+func WithErrorHandler(fn func(id, name string, err error)) Option {
+	return func(q *Queue) {
+		q.onError = fn
+	}
+}
+
+// streamClient is the part of the Redis client that a Queue uses.
+type streamClient interface {
+	XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd
+	XGroupCreateMkStream(ctx context.Context, stream, group, start string) *redis.StatusCmd
+	XAutoClaim(ctx context.Context, a *redis.XAutoClaimArgs) *redis.XAutoClaimCmd
+	XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd
+	XAck(ctx context.Context, stream, group string, ids ...string) *redis.IntCmd
+}
+
+// Queue is a durable task queue stored in a Redis stream.
+type Queue struct {
+	client         streamClient
+	pool           *workerpool.WorkerPool
+	stream         string
+	group          string
+	consumer       string
+	redeliverAfter time.Duration
+	onError        func(id, name string, err error)
+	registry       *workerpool.TaskRegistry
+
+	// pending holds messages read from the stream and not yet given to the
+	// worker pool.  Only accessed by the consuming goroutine.
+	pending []redis.XMessage
+}
+
+// New creates a Queue that stores tasks in the named stream, and executes
+// them using the worker pool.
+func New(client redis.UniversalClient, stream string, pool *workerpool.WorkerPool, options ...Option) *Queue {
+	q := &Queue{
+		client:         client,
+		pool:           pool,
+		stream:         stream,
+		group:          "workerpool",
+		redeliverAfter: 5 * time.Minute,
+	}
+	for _, option := range options {
+		option(q)
+	}
+	if q.registry == nil {
+		q.registry = new(workerpool.TaskRegistry)
+	}
+	if q.consumer == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			host = "localhost"
+		}
+		q.consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	return q
+}
+
+// Handle registers the handler for tasks with the given name, in the queue's
+// registry.  The handler is given the payload of each task.
+func (q *Queue) Handle(name string, h Handler) {
+	q.registry.Register(name, func(payload []byte) (workerpool.SerializableTask, error) {
+		return &handlerTask{name: name, payload: payload, handler: h}, nil
+	})
+}
+
+// handlerTask is a task executed by a Handler.
+type handlerTask struct {
+	name    string
+	payload []byte
+	handler Handler
+}
+
+func (t *handlerTask) TaskName() string              { return t.name }
+func (t *handlerTask) Payload() ([]byte, error)      { return t.payload, nil }
+func (t *handlerTask) Run(ctx context.Context) error { return t.handler(ctx, t.payload) }
+
+// Submit adds a task to the queue.  The task is executed by the handler
+// registered for its name, by one of the processes consuming from the queue.
+func (q *Queue) Submit(ctx context.Context, name string, payload []byte) error {
+	return q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.stream,
+		Values: map[string]interface{}{
+			fieldName:    name,
+			fieldPayload: payload,
+		},
+	}).Err()
+}
+
+// SubmitTask adds a serializable task to the queue.  The task is decoded,
+// using the registry, and executed by one of the processes consuming from the
+// queue.
+func (q *Queue) SubmitTask(ctx context.Context, t workerpool.SerializableTask) error {
+	payload, err := t.Payload()
+	if err != nil {
+		return err
+	}
+	return q.Submit(ctx, t.TaskName(), payload)
+}
+
+// Run consumes tasks from the queue and executes them in the worker pool,
+// until ctx is done or reading from Redis fails.  Run returns after the tasks
+// it started have completed.
+func (q *Queue) Run(ctx context.Context) error {
+	err := q.client.XGroupCreateMkStream(ctx, q.stream, q.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return q.pool.Consume(ctx, workerpool.SourceFunc(q.next))
+}
+
+// next returns a task for the next message from the stream.  Messages that
+// have been unacknowledged for too long are claimed before new messages are
+// read.
+func (q *Queue) next(ctx context.Context) (func(), error) {
+	for len(q.pending) == 0 {
+		if err := q.fetch(ctx); err != nil {
+			return nil, err
+		}
+	}
+	msg := q.pending[0]
+	q.pending = q.pending[1:]
+	return func() { q.execute(ctx, msg) }, nil
+}
+
+// fetch reads messages into the pending list, waiting for up to one second
+// for new messages to arrive.
+func (q *Queue) fetch(ctx context.Context) error {
+	size := int64(q.pool.Size())
+	claimed, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.stream,
+		Group:    q.group,
+		Consumer: q.consumer,
+		MinIdle:  q.redeliverAfter,
+		Start:    "0-0",
+		Count:    size,
+	}).Result()
+	if err != nil {
+		return err
+	}
+	if len(claimed) != 0 {
+		q.pending = claimed
+		return nil
+	}
+
+	streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.group,
+		Consumer: q.consumer,
+		Streams:  []string{q.stream, ">"},
+		Count:    size,
+		Block:    time.Second,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			// No new messages before blocking timed out.
+			return ctx.Err()
+		}
+		return err
+	}
+	for _, s := range streams {
+		q.pending = append(q.pending, s.Messages...)
+	}
+	return nil
+}
+
+// execute decodes and runs the task in a message, and acknowledges the
+// message if the task succeeds or the message cannot be decoded.
+func (q *Queue) execute(ctx context.Context, msg redis.XMessage) {
+	name, _ := msg.Values[fieldName].(string)
+	payload, _ := msg.Values[fieldPayload].(string)
+
+	t, err := q.registry.Decode(name, []byte(payload))
+	if err != nil {
+		// The task can never be run, so drop it.
+		q.report(msg.ID, name, err)
+		q.ack(msg.ID, name)
+		return
+	}
+	if err = t.Run(ctx); err != nil {
+		// Leave the message pending, to be delivered again.
+		q.report(msg.ID, name, err)
+		return
+	}
+	q.ack(msg.ID, name)
+}
+
+// ack acknowledges a message, so that it is not delivered again.
+func (q *Queue) ack(id, name string) {
+	if err := q.client.XAck(context.Background(), q.stream, q.group, id).Err(); err != nil {
+		q.report(id, name, err)
+	}
+}
+
+func (q *Queue) report(id, name string, err error) {
+	if q.onError != nil {
+		q.onError(id, name, err)
+	}
+}
@@ -0,0 +1,298 @@
+package wpredis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gammazero/workerpool"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestClient connects to the Redis server given by the WPREDIS_ADDR
+// environment variable, or skips the test if it is not set.
+func newTestClient(t *testing.T) redis.UniversalClient {
+	addr := os.Getenv("WPREDIS_ADDR")
+	if addr == "" {
+		t.Skip("WPREDIS_ADDR not set")
+	}
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestQueue(t *testing.T) {
+	client := newTestClient(t)
+	stream := "wpredis-test-" + t.Name()
+	ctx := context.Background()
+	client.Del(ctx, stream)
+	defer client.Del(ctx, stream)
+
+	wp := workerpool.New(2)
+	defer wp.Stop()
+
+	var mutex sync.Mutex
+	got := map[string]int{}
+	failed := false
+	done := make(chan struct{})
+	q := New(client, stream, wp, WithRedeliverAfter(100*time.Millisecond))
+	q.Handle("record", func(ctx context.Context, payload []byte) error {
+		mutex.Lock()
+		defer mutex.Unlock()
+		if string(payload) == "retry" && !failed {
+			// Fail the first time, so that the task is delivered again.
+			failed = true
+			return errors.New("try again")
+		}
+		got[string(payload)]++
+		if len(got) == 3 {
+			close(done)
+		}
+		return nil
+	})
+	for _, payload := range []string{"a", "b", "retry"} {
+		if err := q.Submit(ctx, "record", []byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	runErr := make(chan error, 1)
+	go func() { runErr <- q.Run(runCtx) }()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for tasks")
+	}
+	cancel()
+	if err := <-runErr; err != context.Canceled {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+	if got["a"] != 1 || got["b"] != 1 || got["retry"] != 1 {
+		t.Fatal("wrong deliveries:", got)
+	}
+}
+
+// fakeClient is an in-memory stream with a single consumer group, for testing
+// a Queue without a Redis server.
+type fakeClient struct {
+	mutex   sync.Mutex
+	lastID  int
+	unread  []redis.XMessage
+	pending []fakeDelivery
+	acked   []string
+	err     error
+}
+
+// fakeDelivery is a message that was delivered and not acknowledged.
+type fakeDelivery struct {
+	msg       redis.XMessage
+	delivered time.Time
+}
+
+func (c *fakeClient) XAdd(ctx context.Context, a *redis.XAddArgs) *redis.StringCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.lastID++
+	id := fmt.Sprintf("%d-0", c.lastID)
+	values := map[string]interface{}{}
+	for k, v := range a.Values.(map[string]interface{}) {
+		// Redis returns values as strings.
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		values[k] = v
+	}
+	c.unread = append(c.unread, redis.XMessage{ID: id, Values: values})
+	return redis.NewStringResult(id, nil)
+}
+
+func (c *fakeClient) XGroupCreateMkStream(ctx context.Context, stream, group, start string) *redis.StatusCmd {
+	return redis.NewStatusResult("OK", nil)
+}
+
+func (c *fakeClient) XAutoClaim(ctx context.Context, a *redis.XAutoClaimArgs) *redis.XAutoClaimCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	cmd := redis.NewXAutoClaimCmd(ctx)
+	if c.err != nil {
+		cmd.SetErr(c.err)
+		return cmd
+	}
+	var claimed []redis.XMessage
+	now := time.Now()
+	for i := range c.pending {
+		if int64(len(claimed)) == a.Count {
+			break
+		}
+		if d := &c.pending[i]; now.Sub(d.delivered) >= a.MinIdle {
+			d.delivered = now
+			claimed = append(claimed, d.msg)
+		}
+	}
+	cmd.SetVal(claimed, "0-0")
+	return cmd
+}
+
+func (c *fakeClient) XReadGroup(ctx context.Context, a *redis.XReadGroupArgs) *redis.XStreamSliceCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err != nil {
+		return redis.NewXStreamSliceCmdResult(nil, c.err)
+	}
+	if len(c.unread) == 0 {
+		return redis.NewXStreamSliceCmdResult(nil, redis.Nil)
+	}
+	n := len(c.unread)
+	if int64(n) > a.Count {
+		n = int(a.Count)
+	}
+	msgs := c.unread[:n]
+	c.unread = c.unread[n:]
+	for _, msg := range msgs {
+		c.pending = append(c.pending, fakeDelivery{msg: msg, delivered: time.Now()})
+	}
+	return redis.NewXStreamSliceCmdResult([]redis.XStream{{Stream: a.Streams[0], Messages: msgs}}, nil)
+}
+
+func (c *fakeClient) XAck(ctx context.Context, stream, group string, ids ...string) *redis.IntCmd {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, id := range ids {
+		c.acked = append(c.acked, id)
+		for i, d := range c.pending {
+			if d.msg.ID == id {
+				c.pending = append(c.pending[:i], c.pending[i+1:]...)
+				break
+			}
+		}
+	}
+	return redis.NewIntResult(int64(len(ids)), nil)
+}
+
+// newFakeQueue returns a Queue that uses a fakeClient, and the client.
+func newFakeQueue(wp *workerpool.WorkerPool, options ...Option) (*Queue, *fakeClient) {
+	client := &fakeClient{}
+	q := New(nil, "stream", wp, options...)
+	q.client = client
+	return q, client
+}
+
+func TestNext(t *testing.T) {
+	t.Parallel()
+
+	wp := workerpool.New(2)
+	defer wp.Stop()
+	q, client := newFakeQueue(wp)
+
+	var got []string
+	q.Handle("record", func(ctx context.Context, payload []byte) error {
+		got = append(got, string(payload))
+		return nil
+	})
+	ctx := context.Background()
+	for _, payload := range []string{"a", "b", "c"} {
+		if err := q.Submit(ctx, "record", []byte(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Messages are read up to the pool's size at a time, and executed in
+	// order.
+	for i := 0; i < 3; i++ {
+		task, err := q.next(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 && len(q.pending) != 1 {
+			t.Fatal("expected 1 more message read, got", len(q.pending))
+		}
+		task()
+	}
+	if strings.Join(got, "") != "abc" {
+		t.Fatal("wrong tasks executed:", got)
+	}
+	if len(client.acked) != 3 || len(client.pending) != 0 {
+		t.Fatal("expected 3 messages acknowledged, got", client.acked)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+
+	wp := workerpool.New(2)
+	defer wp.Stop()
+	q, client := newFakeQueue(wp, WithRedeliverAfter(time.Minute))
+	ctx := context.Background()
+
+	// Nothing to read returns without error, so that the caller can check
+	// its context and try again.
+	if err := q.fetch(ctx); err != nil || len(q.pending) != 0 {
+		t.Fatal("expected nothing read, got", q.pending, err)
+	}
+
+	q.Submit(ctx, "old", nil)
+	q.Submit(ctx, "new", nil)
+	if err := q.fetch(ctx); err != nil || len(q.pending) != 2 {
+		t.Fatal("expected 2 messages read, got", q.pending, err)
+	}
+
+	// A message that has been unacknowledged for longer than the redeliver
+	// time is claimed before new messages are read.
+	q.pending = nil
+	client.pending[0].delivered = time.Now().Add(-time.Hour)
+	q.Submit(ctx, "newer", nil)
+	if err := q.fetch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.pending) != 1 || q.pending[0].Values[fieldName] != "old" {
+		t.Fatal("expected old message to be claimed, got", q.pending)
+	}
+
+	client.err = errors.New("connection refused")
+	if err := q.fetch(ctx); err != client.err {
+		t.Fatal("expected read error, got", err)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	t.Parallel()
+
+	wp := workerpool.New(3)
+	defer wp.Stop()
+
+	var reported []string
+	q, client := newFakeQueue(wp, WithErrorHandler(func(id, name string, err error) {
+		reported = append(reported, name)
+	}))
+	q.Handle("ok", func(context.Context, []byte) error { return nil })
+	q.Handle("fail", func(context.Context, []byte) error { return errors.New("failed") })
+
+	ctx := context.Background()
+	for _, name := range []string{"ok", "fail", "unknown"} {
+		q.Submit(ctx, name, nil)
+	}
+	if err := q.fetch(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range q.pending {
+		q.execute(ctx, msg)
+	}
+
+	// A failed task is left pending to be delivered again, and a task that
+	// cannot be decoded is acknowledged so that it is dropped.
+	if strings.Join(reported, ",") != "fail,unknown" {
+		t.Fatal("wrong errors reported:", reported)
+	}
+	if strings.Join(client.acked, ",") != "1-0,3-0" {
+		t.Fatal("wrong messages acknowledged:", client.acked)
+	}
+	if len(client.pending) != 1 || client.pending[0].msg.ID != "2-0" {
+		t.Fatal("expected failed message to stay pending, got", client.pending)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Package wpremote provides the building blocks for distributing serializable
+tasks from a coordinating worker pool to worker processes on other machines.
+
+A Coordinator accepts tasks the same way as a local worker pool, and uses the
+pool's workers to send each task over a Transport to a remote Worker, waiting
+for the remote worker to finish.  The coordinator's maximum number of workers
+limits the number of tasks executing remotely at once.  A Worker decodes the
+tasks it receives using a workerpool.TaskRegistry, and executes them in its own
+local worker pool.
+
+A Worker is itself a Transport, which is useful for testing and for running
+the coordinator and workers in the same process.  The wpgrpc package provides a
+gRPC transport.
+*/
+package wpremote
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/gammazero/workerpool"
+)
+
+// Transport sends a task to a remote worker for execution.
+type Transport interface {
+	// Execute sends the task with the given name and payload to a worker,
+	// and waits for the worker to run it.  Returns the error returned by the
+	// task, or an error sending the task.
+	Execute(ctx context.Context, name string, payload []byte) error
+}
+
+// RemoteError is an error returned by a task that ran on a remote worker.
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// Coordinator submits serializable tasks to remote workers.
+type Coordinator struct {
+	pool      *workerpool.WorkerPool
+	transport Transport
+	onError   func(workerpool.SerializableTask, error)
+}
+
+// NewCoordinator creates a Coordinator that sends tasks over the transport,
+// using the pool's workers.  The onError function, if not nil, is called
+// with each task submitted using Submit that fails.
+func NewCoordinator(pool *workerpool.WorkerPool, transport Transport, onError func(workerpool.SerializableTask, error)) *Coordinator {
+	return &Coordinator{
+		pool:      pool,
+		transport: transport,
+		onError:   onError,
+	}
+}
+
+// Submit enqueues a task to be sent to a remote worker.  Like the worker
+// pool's Submit, it does not wait for the task to run.
+func (c *Coordinator) Submit(t workerpool.SerializableTask) {
+	c.pool.Submit(func() {
+		if err := c.execute(context.Background(), t); err != nil && c.onError != nil {
+			c.onError(t, err)
+		}
+	})
+}
+
+// SubmitWait enqueues a task to be sent to a remote worker, and waits for it
+// to complete.  Returns the task's error.  If ctx is done, the task is
+// canceled on the remote worker if it is running.
+func (c *Coordinator) SubmitWait(ctx context.Context, t workerpool.SerializableTask) error {
+	var err error
+	c.pool.SubmitWait(func() {
+		err = c.execute(ctx, t)
+	})
+	return err
+}
+
+func (c *Coordinator) execute(ctx context.Context, t workerpool.SerializableTask) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	payload, err := t.Payload()
+	if err != nil {
+		return err
+	}
+	return c.transport.Execute(ctx, t.TaskName(), payload)
+}
+
+// Worker executes tasks received from a coordinator in a local worker pool.
+type Worker struct {
+	pool     *workerpool.WorkerPool
+	registry *workerpool.TaskRegistry
+}
+
+// NewWorker creates a Worker that decodes tasks using the registry and runs
+// them in the pool.
+func NewWorker(pool *workerpool.WorkerPool, registry *workerpool.TaskRegistry) *Worker {
+	return &Worker{
+		pool:     pool,
+		registry: registry,
+	}
+}
+
+// Execute decodes a task, and runs it in the worker's pool.  Returns the
+// task's error, or ctx's error if ctx is done before the task starts.
+func (w *Worker) Execute(ctx context.Context, name string, payload []byte) error {
+	t, err := w.registry.Decode(name, payload)
+	if err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	var started int32
+	w.pool.Submit(func() {
+		if !atomic.CompareAndSwapInt32(&started, 0, 1) {
+			// The caller stopped waiting before the task started.
+			return
+		}
+		err = t.Run(ctx)
+		close(done)
+	})
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		if atomic.CompareAndSwapInt32(&started, 0, 1) {
+			return ctx.Err()
+		}
+		<-done
+		return err
+	}
+}
+
+// RoundRobin returns a Transport that sends each task to the next of the
+// given transports in turn, to spread tasks across several workers.
+func RoundRobin(transports ...Transport) Transport {
+	return &roundRobin{transports: transports}
+}
+
+type roundRobin struct {
+	transports []Transport
+	next       uint32
+}
+
+func (r *roundRobin) Execute(ctx context.Context, name string, payload []byte) error {
+	i := atomic.AddUint32(&r.next, 1) - 1
+	return r.transports[int(i%uint32(len(r.transports)))].Execute(ctx, name, payload)
+}
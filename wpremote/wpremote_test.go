@@ -0,0 +1,56 @@
+package wpremote
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gammazero/workerpool"
+)
+
+func TestCoordinator(t *testing.T) {
+	t.Parallel()
+
+	var reg workerpool.TaskRegistry
+	var sum int64
+	add := func(ctx context.Context, n int64) error {
+		if n < 0 {
+			return errors.New("negative")
+		}
+		atomic.AddInt64(&sum, n)
+		return nil
+	}
+	workerpool.RegisterJSON(&reg, "add", add)
+
+	// Two remote workers, each with its own pool.
+	var workers []Transport
+	for i := 0; i < 2; i++ {
+		wp := workerpool.New(2)
+		defer wp.Stop()
+		workers = append(workers, NewWorker(wp, &reg))
+	}
+
+	local := workerpool.New(3)
+	defer local.Stop()
+	failed := make(chan error, 1)
+	c := NewCoordinator(local, RoundRobin(workers...), func(_ workerpool.SerializableTask, err error) {
+		failed <- err
+	})
+	for i := int64(1); i <= 10; i++ {
+		c.Submit(workerpool.NewJSONTask("add", i, add))
+	}
+	c.Submit(workerpool.NewJSONTask("add", int64(-1), add))
+	local.Wait()
+	if sum != 55 {
+		t.Fatal("expected sum 55, got", sum)
+	}
+	if err := <-failed; err == nil || err.Error() != "negative" {
+		t.Fatal("expected task error, got", err)
+	}
+
+	err := c.SubmitWait(context.Background(), workerpool.NewJSONTask("unknown", 0, add))
+	if !errors.Is(err, workerpool.ErrUnknownTask) {
+		t.Fatal("expected ErrUnknownTask, got", err)
+	}
+}